@@ -0,0 +1,229 @@
+package linode
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for ACMEDNSProvider.  Linode's name servers pick up changes in
+// batches, so a record can take several minutes to appear.
+const (
+	DefaultACMEPropagationTimeout = 20 * time.Minute
+	DefaultACMEPollInterval       = 30 * time.Second
+)
+
+// ACMECheckFunc reports whether a TXT record for fqdn with the given value is
+// visible.
+type ACMECheckFunc func(ctx context.Context, fqdn string, value string) (bool, error)
+
+// ACMEDNSProvider solves ACME DNS-01 challenges using domains hosted in
+// Linode DNS.  Its Present(), CleanUp(), and Timeout() methods match the
+// provider interfaces used by common ACME clients, such as lego's
+// challenge.Provider and challenge.ProviderTimeout.
+//
+// It should be created by a call to NewACMEDNSProvider().
+type ACMEDNSProvider struct {
+	// TTL of challenge records.  Zero uses the shortest TTL the API allows.
+	TTL int
+
+	// PropagationTimeout bounds how long Present() waits for the record to
+	// become visible, checking every PollInterval.
+	PropagationTimeout time.Duration
+	PollInterval       time.Duration
+
+	// Check decides when a record is visible.  If nil, Linode's name
+	// servers are queried directly, which avoids waiting out any negative
+	// caching in recursive resolvers.
+	Check ACMECheckFunc
+
+	c       *Client
+	mu      sync.Mutex
+	records map[acmeKey]acmeRecord
+}
+
+type acmeKey struct {
+	fqdn  string
+	value string
+}
+
+type acmeRecord struct {
+	domainID   int
+	resourceID int
+}
+
+// NewACMEDNSProvider returns an ACMEDNSProvider with the default timeouts.
+func (c *Client) NewACMEDNSProvider() *ACMEDNSProvider {
+	return &ACMEDNSProvider{
+		PropagationTimeout: DefaultACMEPropagationTimeout,
+		PollInterval:       DefaultACMEPollInterval,
+		c:                  c,
+		records:            make(map[acmeKey]acmeRecord),
+	}
+}
+
+// ACMEChallenge returns the name and value of the TXT record that proves
+// control of domain, given the key authorization for the challenge.
+// Wildcard domains share a record with their base domain.
+func ACMEChallenge(domain string, keyAuth string) (fqdn string, value string) {
+	domain = strings.TrimPrefix(strings.TrimSuffix(domain, "."), "*.")
+	sum := sha256.Sum256([]byte(keyAuth))
+	return "_acme-challenge." + strings.ToLower(domain), base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Present creates the challenge TXT record for domain, and waits until it is
+// visible.  token is unused, but is part of the usual provider interface.
+func (p *ACMEDNSProvider) Present(domain string, token string, keyAuth string) error {
+	fqdn, value := ACMEChallenge(domain, keyAuth)
+
+	d, err := p.c.findDomain(fqdn)
+	if err != nil {
+		return err
+	}
+	name, _ := zoneRelative(fqdn, d.Domain)
+
+	ttl := p.TTL
+	if ttl == 0 {
+		ttl = allowedTTLs[0]
+	}
+
+	r, err := TXTRecord(name, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	resourceID, err := p.c.DomainRecordCreate(d.ID, r)
+	if err != nil {
+		return fmt.Errorf("acme: creating %s: %s", fqdn, err)
+	}
+
+	p.mu.Lock()
+	p.records[acmeKey{fqdn, value}] = acmeRecord{d.ID, resourceID}
+	p.mu.Unlock()
+
+	timeout := p.PropagationTimeout
+	if timeout <= 0 {
+		timeout = DefaultACMEPropagationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return p.wait(ctx, fqdn, value)
+}
+
+func (p *ACMEDNSProvider) wait(ctx context.Context, fqdn string, value string) error {
+	check := p.Check
+	if check == nil {
+		check = LinodeTXTCheck
+	}
+
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = DefaultACMEPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := check(ctx, fqdn, value)
+		if err == nil && ok {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("acme: %s never became visible: %s", fqdn, err)
+			}
+			return fmt.Errorf("acme: %s never became visible: %s", fqdn, ctx.Err())
+		}
+	}
+}
+
+// CleanUp deletes the challenge TXT record created by Present().  If this
+// provider did not create it, for example after a restart, the record is
+// found by name and value instead.
+func (p *ACMEDNSProvider) CleanUp(domain string, token string, keyAuth string) error {
+	fqdn, value := ACMEChallenge(domain, keyAuth)
+	key := acmeKey{fqdn, value}
+
+	p.mu.Lock()
+	rec, ok := p.records[key]
+	p.mu.Unlock()
+
+	if !ok {
+		d, err := p.c.findDomain(fqdn)
+		if err != nil {
+			return err
+		}
+		name, _ := zoneRelative(fqdn, d.Domain)
+
+		resources, err := p.c.DomainResourceList(d.ID, nil)
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			if strings.EqualFold(r.Type, "txt") && strings.EqualFold(r.Name, name) && r.Target == value {
+				rec = acmeRecord{d.ID, r.ID}
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	err := p.c.DomainResourceDelete(rec.domainID, rec.resourceID)
+	if err != nil {
+		return fmt.Errorf("acme: deleting %s: %s", fqdn, err)
+	}
+
+	p.mu.Lock()
+	delete(p.records, key)
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Timeout returns PropagationTimeout and PollInterval.
+func (p *ACMEDNSProvider) Timeout() (timeout time.Duration, interval time.Duration) {
+	return p.PropagationTimeout, p.PollInterval
+}
+
+// LinodeTXTCheck is an ACMECheckFunc that queries Linode's name servers
+// directly, returning true once any of them serves the value.
+func LinodeTXTCheck(ctx context.Context, fqdn string, value string) (bool, error) {
+	var lastErr error
+
+	for _, ns := range linodeNameServers {
+		addr := net.JoinHostPort(ns, "53")
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+
+		txts, err := r.LookupTXT(ctx, fqdn)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		for _, txt := range txts {
+			if txt == value {
+				return true, nil
+			}
+		}
+	}
+
+	return false, lastErr
+}
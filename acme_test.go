@@ -0,0 +1,122 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+const testACMEValue = "67MSe_XHxLTkK1FxD0lGwcHQWzMdI3ndFeOlQx7ZNBY"
+
+func TestACMEChallenge(t *testing.T) {
+	fqdn, value := ACMEChallenge("*.WWW.sub.example.com.", "abc.def")
+	assert.Equal(t, "_acme-challenge.www.sub.example.com", fqdn)
+	assert.Equal(t, testACMEValue, value)
+}
+
+func mockACMEDomains() mockAPIResponse {
+	return newMockAPIResponse("domain.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","AXFR_IPS":"none"},{"DOMAINID":31,"DOMAIN":"sub.example.com","TYPE":"master","AXFR_IPS":"none"},{"DOMAINID":32,"DOMAIN":"www.sub.example.com","TYPE":"slave","AXFR_IPS":"none"}],"ACTION":"domain.list"}`)
+}
+
+func TestACMEDNSProvider(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockACMEDomains())
+	params := map[string]string{
+		"DomainID": "31",
+		"Type":     "txt",
+		"Name":     "_acme-challenge.www",
+		"Target":   testACMEValue,
+		"TTL_sec":  "300",
+	}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "ResourceID": "5"}
+	responses = append(responses, newMockAPIResponse("domain.resource.delete", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.delete"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	checks := 0
+	p := c.NewACMEDNSProvider()
+	p.PollInterval = time.Millisecond
+	p.Check = func(ctx context.Context, fqdn string, value string) (bool, error) {
+		assert.Equal(t, "_acme-challenge.www.sub.example.com", fqdn)
+		assert.Equal(t, testACMEValue, value)
+		checks++
+		if checks == 1 {
+			return false, errors.New("NXDOMAIN")
+		}
+		return checks == 3, nil
+	}
+
+	err := p.Present("www.sub.example.com", "abc", "abc.def")
+	require.NoError(t, err)
+	assert.Equal(t, 3, checks)
+
+	err = p.CleanUp("www.sub.example.com", "abc", "abc.def")
+	require.NoError(t, err)
+
+	timeout, interval := p.Timeout()
+	assert.Equal(t, DefaultACMEPropagationTimeout, timeout)
+	assert.Equal(t, time.Millisecond, interval)
+}
+
+func TestACMEDNSProviderTimeout(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockACMEDomains())
+	params := map[string]string{"DomainID": "30", "Name": "_acme-challenge"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":6},"ACTION":"domain.resource.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	p := c.NewACMEDNSProvider()
+	p.PropagationTimeout = 10 * time.Millisecond
+	p.PollInterval = time.Millisecond
+	p.Check = func(ctx context.Context, fqdn string, value string) (bool, error) {
+		return false, errors.New("NXDOMAIN")
+	}
+
+	err := p.Present("example.com", "abc", "abc.def")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "NXDOMAIN")
+}
+
+func TestACMEDNSProviderCleanUpUnknown(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockACMEDomains())
+	params := map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":7,"DOMAINID":30,"TYPE":"txt","NAME":"_acme-challenge.www","TARGET":"stale"},{"RESOURCEID":8,"DOMAINID":30,"TYPE":"txt","NAME":"_acme-challenge.www","TARGET":"`+testACMEValue+`"}],"ACTION":"domain.resource.list"}`))
+	params = map[string]string{"DomainID": "30", "ResourceID": "8"}
+	responses = append(responses, newMockAPIResponse("domain.resource.delete", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":8},"ACTION":"domain.resource.delete"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	p := c.NewACMEDNSProvider()
+	err := p.CleanUp("www.example.com", "abc", "abc.def")
+	require.NoError(t, err)
+}
+
+func TestACMEDNSProviderNoDomain(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, []mockAPIResponse{mockACMEDomains()}))
+	defer ts.Close()
+
+	p := c.NewACMEDNSProvider()
+	err := p.Present("www.example.org", "abc", "abc.def")
+	require.Error(t, err)
+}
@@ -348,6 +348,7 @@ func (c *Client) LinodeConfigList(linodeID int, configID *int) ([]LinodeConfig,
 type LinodeConfigUpdateOpts struct {
 	LinodeID              *int    `args:"LinodeID"`
 	KernelID              *int    `args:"KernelID"`
+	DiskList              *string `args:"DiskList"`
 	Comments              *string `args:"Comments"`
 	RAMLimit              *int    `args:"RAMLimit"`
 	VirtMode              *string `args:"virt_mode"`
@@ -794,6 +795,7 @@ func (c *Client) WaitForJob(linodeID int, jobID int, checkInterval time.Duration
 			}
 			j := jobs[0]
 			if j.Done() {
+				c.untrackJob(j)
 				return j.Success(), nil
 			}
 		case <-deadline.C:
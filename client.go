@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 )
 
 const (
@@ -21,11 +22,19 @@ type argMarshaler func(interface{}) (map[string]interface{}, error)
 // Client is the API client.  It should be created by a call to
 // NewClient().
 type Client struct {
-	URL        string
+	URL string
+
+	// JobStore, if set, persists jobs queued through the *Job variants of
+	// the job-producing calls.  See ResumeJobs().
+	JobStore JobStore
+
 	key        string
 	post       httpPoster
 	apiCall    apiCaller
 	argMarshal argMarshaler
+
+	watcherOnce sync.Once
+	watcher     *JobWatcher
 }
 
 // NewClient returns a new client configured with the passed API key.
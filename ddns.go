@@ -0,0 +1,300 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for DynDNS.
+const (
+	DefaultDynDNSDebounce      = 30 * time.Second
+	DefaultDynDNSRetryInterval = 1 * time.Minute
+)
+
+// DynDNS keeps the A and AAAA records for a name pointed at a changing
+// address.  It should be created by a call to NewDynDNS().
+//
+// IPv4 and IPv6 addresses are tracked separately: an IPv4 address only ever
+// changes the A record, and an IPv6 address the AAAA record.  The record is
+// found by name in the domain that FQDN belongs to, and created if missing.
+type DynDNS struct {
+	FQDN string
+
+	// TTL of created records.  Zero uses the domain's default.
+	TTL int
+
+	// Debounce is how long Run() waits for an address to settle before
+	// updating the record, so a flapping source does not cause a burst of
+	// API calls.  Failed updates are retried after RetryInterval.
+	Debounce      time.Duration
+	RetryInterval time.Duration
+
+	// OnUpdate, if set, is called by Run() after each attempt to update a
+	// record.
+	OnUpdate func(ip net.IP, err error)
+
+	c         *Client
+	mu        sync.Mutex
+	domainID  int
+	name      string
+	resources map[string]int
+	current   map[string]net.IP
+}
+
+// NewDynDNS returns a DynDNS for fqdn with the default timings.
+func (c *Client) NewDynDNS(fqdn string) *DynDNS {
+	return &DynDNS{
+		FQDN:          strings.ToLower(strings.TrimSuffix(fqdn, ".")),
+		Debounce:      DefaultDynDNSDebounce,
+		RetryInterval: DefaultDynDNSRetryInterval,
+		c:             c,
+		resources:     make(map[string]int),
+		current:       make(map[string]net.IP),
+	}
+}
+
+func ipRecordType(ip net.IP) string {
+	switch {
+	case ip.To4() != nil:
+		return "A"
+	case ip.To16() != nil:
+		return "AAAA"
+	}
+	return ""
+}
+
+// Update points the A or AAAA record at ip right away.  It does nothing if
+// the record was already set to ip by this DynDNS.
+func (d *DynDNS) Update(ip net.IP) error {
+	rType := ipRecordType(ip)
+	if rType == "" {
+		return fmt.Errorf("ddns: invalid address %v", ip)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.current[rType].Equal(ip) {
+		return nil
+	}
+
+	if d.domainID == 0 {
+		dom, err := d.c.findDomain(d.FQDN)
+		if err != nil {
+			return err
+		}
+		d.domainID = dom.ID
+		d.name, _ = zoneRelative(d.FQDN, dom.Domain)
+	}
+
+	id, ok := d.resources[rType]
+	if !ok {
+		resources, err := d.c.DomainResourceList(d.domainID, nil)
+		if err != nil {
+			return err
+		}
+		for _, r := range resources {
+			if !strings.EqualFold(r.Type, rType) || !strings.EqualFold(r.Name, d.name) {
+				continue
+			}
+			id, ok = r.ID, true
+			d.resources[rType] = id
+			if net.ParseIP(r.Target).Equal(ip) {
+				d.current[rType] = ip
+				return nil
+			}
+			break
+		}
+	}
+
+	if !ok {
+		var rec DNSRecord
+		var err error
+		if rType == "A" {
+			rec, err = ARecord(d.name, ip, d.TTL)
+		} else {
+			rec, err = AAAARecord(d.name, ip, d.TTL)
+		}
+		if err != nil {
+			return err
+		}
+
+		id, err = d.c.DomainRecordCreate(d.domainID, rec)
+		if err != nil {
+			return fmt.Errorf("ddns: creating %s %s: %s", rType, d.FQDN, err)
+		}
+		d.resources[rType] = id
+	} else {
+		opts := DomainResourceUpdateOpts{
+			DomainID: Int(d.domainID),
+			Target:   String(ip.String()),
+		}
+		err := d.c.DomainResourceUpdate(id, opts)
+		if err != nil {
+			// The record may have been deleted; look it up again next
+			// time.
+			delete(d.resources, rType)
+			return fmt.Errorf("ddns: updating %s %s: %s", rType, d.FQDN, err)
+		}
+	}
+
+	d.current[rType] = ip
+	return nil
+}
+
+type dynDNSFire struct {
+	rType string
+	gen   int
+}
+
+// Run updates the records with addresses from ips until ctx is done or ips
+// is closed.  An address is applied once no different address of the same
+// family has arrived for Debounce; repeats of the pending address do not
+// delay it.  When ips is closed, any pending addresses are
+// applied immediately, and Run returns the first error from doing so.
+func (d *DynDNS) Run(ctx context.Context, ips <-chan net.IP) error {
+	pending := make(map[string]net.IP)
+	gens := make(map[string]int)
+	timers := make(map[string]*time.Timer)
+	fire := make(chan dynDNSFire)
+	done := make(chan struct{})
+
+	defer func() {
+		close(done)
+		for _, t := range timers {
+			t.Stop()
+		}
+	}()
+
+	schedule := func(rType string, after time.Duration) {
+		if t, ok := timers[rType]; ok {
+			t.Stop()
+		}
+		gens[rType]++
+		f := dynDNSFire{rType, gens[rType]}
+		timers[rType] = time.AfterFunc(after, func() {
+			select {
+			case fire <- f:
+			case <-done:
+			}
+		})
+	}
+
+	apply := func(rType string) error {
+		ip := pending[rType]
+		err := d.Update(ip)
+		if d.OnUpdate != nil {
+			d.OnUpdate(ip, err)
+		}
+		if err == nil {
+			delete(pending, rType)
+		}
+		return err
+	}
+
+	for {
+		select {
+		case ip, ok := <-ips:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				var firstErr error
+				for _, rType := range []string{"A", "AAAA"} {
+					if pending[rType] == nil {
+						continue
+					}
+					err := apply(rType)
+					if err != nil && firstErr == nil {
+						firstErr = err
+					}
+				}
+				return firstErr
+			}
+
+			rType := ipRecordType(ip)
+			if rType == "" || pending[rType].Equal(ip) {
+				// Repeats must not restart the debounce, or a source
+				// polled more often than Debounce never settles.
+				continue
+			}
+			d.mu.Lock()
+			current := d.current[rType].Equal(ip)
+			d.mu.Unlock()
+			if current {
+				// Back to the address already set; drop any pending
+				// change.
+				if pending[rType] != nil {
+					delete(pending, rType)
+					gens[rType]++
+				}
+				continue
+			}
+			pending[rType] = ip
+
+			debounce := d.Debounce
+			if debounce <= 0 {
+				debounce = DefaultDynDNSDebounce
+			}
+			schedule(rType, debounce)
+
+		case f := <-fire:
+			if f.gen != gens[f.rType] || pending[f.rType] == nil {
+				continue
+			}
+
+			err := apply(f.rType)
+			if err != nil {
+				retry := d.RetryInterval
+				if retry <= 0 {
+					retry = DefaultDynDNSRetryInterval
+				}
+				schedule(f.rType, retry)
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// PollIPSource calls source every interval and sends the addresses it
+// returns, for use with DynDNS.Run().  Errors from source are skipped.  The
+// channel is closed when ctx is done.
+func PollIPSource(ctx context.Context, interval time.Duration,
+	source func(ctx context.Context) ([]net.IP, error)) <-chan net.IP {
+
+	out := make(chan net.IP)
+
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			ips, err := source(ctx)
+			if err == nil {
+				for _, ip := range ips {
+					select {
+					case out <- ip:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
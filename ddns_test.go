@@ -0,0 +1,222 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockDynDNSLookup(records string) []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+records+`],"ACTION":"domain.resource.list"}`))
+
+	return responses
+}
+
+func TestDynDNSUpdate(t *testing.T) {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockDynDNSLookup(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"edge","TARGET":"192.0.2.1"},{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"other","TARGET":"192.0.2.9"}`)...)
+	params = map[string]string{"ResourceID": "1", "DomainID": "30", "Target": "192.0.2.2"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+
+	// The AAAA record is looked up separately, and created.
+	params = map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"edge","TARGET":"192.0.2.2"}],"ACTION":"domain.resource.list"}`))
+	params = map[string]string{"DomainID": "30", "Type": "aaaa", "Name": "edge", "Target": "2001:db8::1", "TTL_sec": "300"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":3},"ACTION":"domain.resource.create"}`))
+
+	params = map[string]string{"ResourceID": "1", "Target": "192.0.2.3"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	d := c.NewDynDNS("Edge.Example.com.")
+	d.TTL = 300
+
+	require.NoError(t, d.Update(net.ParseIP("192.0.2.2")))
+	// Unchanged, so no API calls.
+	require.NoError(t, d.Update(net.ParseIP("192.0.2.2")))
+	require.NoError(t, d.Update(net.ParseIP("2001:db8::1")))
+	require.NoError(t, d.Update(net.ParseIP("192.0.2.3")))
+
+	assert.Error(t, d.Update(nil))
+}
+
+func TestDynDNSUpdateCurrent(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockDynDNSLookup(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"","TARGET":"192.0.2.1"}`)))
+	defer ts.Close()
+
+	d := c.NewDynDNS("example.com")
+	require.NoError(t, d.Update(net.ParseIP("192.0.2.1")))
+	require.NoError(t, d.Update(net.ParseIP("192.0.2.1")))
+}
+
+func TestDynDNSRun(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockDynDNSLookup(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"edge","TARGET":"192.0.2.1"}`)...)
+	params := map[string]string{"ResourceID": "1", "Target": "192.0.2.3"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+	params = map[string]string{"ResourceID": "1", "Target": "192.0.2.4"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	updates := make(chan net.IP, 10)
+	d := c.NewDynDNS("edge.example.com")
+	d.Debounce = 50 * time.Millisecond
+	d.OnUpdate = func(ip net.IP, err error) {
+		assert.NoError(t, err)
+		updates <- ip
+	}
+
+	ips := make(chan net.IP)
+	errc := make(chan error)
+	go func() {
+		errc <- d.Run(context.Background(), ips)
+	}()
+
+	// Only the last of a burst is applied.
+	ips <- net.ParseIP("192.0.2.2")
+	ips <- net.ParseIP("192.0.2.3")
+	assert.Equal(t, "192.0.2.3", (<-updates).String())
+
+	// Pending addresses are flushed when the source closes.
+	ips <- net.ParseIP("192.0.2.4")
+	close(ips)
+	require.NoError(t, <-errc)
+	assert.Equal(t, "192.0.2.4", (<-updates).String())
+	assert.Len(t, updates, 0)
+}
+
+func TestDynDNSRunRepeats(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockDynDNSLookup(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"edge","TARGET":"192.0.2.1"}`)...)
+	params := map[string]string{"ResourceID": "1", "Target": "192.0.2.2"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	updates := make(chan net.IP, 10)
+	d := c.NewDynDNS("edge.example.com")
+	d.Debounce = 100 * time.Millisecond
+	d.OnUpdate = func(ip net.IP, err error) {
+		assert.NoError(t, err)
+		updates <- ip
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ips := make(chan net.IP)
+	go func() {
+		_ = d.Run(ctx, ips)
+	}()
+
+	// The same address, sent faster than Debounce, is still applied, and
+	// only once.
+	deadline := time.After(5 * time.Second)
+	var got net.IP
+	for got == nil {
+		select {
+		case ips <- net.ParseIP("192.0.2.2"):
+			time.Sleep(10 * time.Millisecond)
+		case got = <-updates:
+		case <-deadline:
+			t.Fatal("address was never applied")
+		}
+	}
+	assert.Equal(t, "192.0.2.2", got.String())
+
+	for i := 0; i < 5; i++ {
+		ips <- net.ParseIP("192.0.2.2")
+	}
+	time.Sleep(2 * d.Debounce)
+	assert.Len(t, updates, 0)
+}
+
+func TestDynDNSRunCancel(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	d := c.NewDynDNS("edge.example.com")
+	d.OnUpdate = func(ip net.IP, err error) {
+		t.Error("unexpected update")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ips := make(chan net.IP)
+	errc := make(chan error)
+	go func() {
+		errc <- d.Run(ctx, ips)
+	}()
+
+	ips <- net.ParseIP("192.0.2.2")
+	cancel()
+	assert.Equal(t, context.Canceled, <-errc)
+}
+
+func TestDynDNSRunFlushError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	d := c.NewDynDNS("edge.example.com")
+	d.Debounce = time.Hour
+	var failed []string
+	d.OnUpdate = func(ip net.IP, err error) {
+		assert.Error(t, err)
+		failed = append(failed, ip.String())
+	}
+
+	ips := make(chan net.IP, 2)
+	ips <- net.ParseIP("192.0.2.2")
+	ips <- net.ParseIP("2001:db8::2")
+	close(ips)
+
+	// The final flush fails, and Run says so.
+	assert.Error(t, d.Run(context.Background(), ips))
+	assert.Equal(t, []string{"192.0.2.2", "2001:db8::2"}, failed)
+}
+
+func TestPollIPSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n := 0
+	ips := PollIPSource(ctx, time.Millisecond, func(ctx context.Context) ([]net.IP, error) {
+		n++
+		return []net.IP{net.IPv4(192, 0, 2, byte(n))}, nil
+	})
+
+	assert.Equal(t, "192.0.2.1", (<-ips).String())
+	assert.Equal(t, "192.0.2.2", (<-ips).String())
+
+	cancel()
+	for range ips {
+	}
+}
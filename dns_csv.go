@@ -0,0 +1,425 @@
+package linode
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSVRowStatus is the outcome of importing one row with ImportRecordsCSV().
+type CSVRowStatus string
+
+// Row statuses.  In a dry run, created and updated mean the record would
+// have been.
+const (
+	CSVRowCreated   CSVRowStatus = "created"
+	CSVRowUpdated   CSVRowStatus = "updated"
+	CSVRowUnchanged CSVRowStatus = "unchanged"
+	CSVRowInvalid   CSVRowStatus = "invalid"
+	CSVRowFailed    CSVRowStatus = "failed"
+)
+
+// csvColumns are the columns of a record CSV, in their default order.
+var csvColumns = []string{"domain", "name", "type", "target", "ttl", "priority", "weight", "port"}
+
+// CSVImportOpts contains the optional arguments to ImportRecordsCSV().
+type CSVImportOpts struct {
+	// DryRun validates the rows and reports what would change, without
+	// changing anything.
+	DryRun bool
+}
+
+// CSVRowResult is the outcome of importing one row.
+type CSVRowResult struct {
+	// Line is the row's line number in the input.
+	Line   int
+	Domain string
+
+	// Record is the row as it was imported, relative to Domain, and
+	// ResourceID is the record it created, updated, or matched.
+	Record     RecordSpec
+	ResourceID int
+
+	Status CSVRowStatus
+
+	// Diffs lists the fields changed by an update, and Err why an invalid
+	// or failed row was not imported.
+	Diffs []FieldDiff
+	Err   error
+}
+
+func (r CSVRowResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("line %d: %s: %s", r.Line, r.Status, r.Err)
+	}
+	name := r.Record.Name
+	if name == "" {
+		name = "@"
+	}
+	return fmt.Sprintf("line %d: %s: %s %s %s %s", r.Line, r.Status, r.Domain, name, r.Record.Type, r.Record.Target)
+}
+
+// ImportRecordsCSV creates or updates DNS records from CSV with the columns
+// domain, name, type, target, ttl, priority, weight, and port.  If the first
+// row starts with "domain" it is read as a header, and the columns may be in
+// any order, with only the first four required.  Lines starting with # are
+// skipped.
+//
+// Domains are found by name, and names may be relative, "@", or fully
+// qualified.  A row is matched with the existing records of the same name
+// and type: an identical record is left alone, then one with the same target
+// is updated, and otherwise the record is created.  A CNAME row, since there
+// can only be one, also updates any other CNAME of the same name.  Existing
+// records are never deleted or overwritten with a different target, except
+// for such a CNAME.  An SRV row's name must be "_service._protocol".  An
+// empty ttl leaves the TTL of an existing record as it is.
+//
+// Every row gets a result, in input order.  Rows that fail validation or
+// whose API call fails do not stop the import; an error is only returned if
+// the CSV cannot be read or the domains cannot be listed.
+func (c *Client) ImportRecordsCSV(r io.Reader, opts CSVImportOpts) ([]CSVRowResult, error) {
+	rows, err := readRecordsCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]Domain)
+	for _, d := range domains {
+		if strings.EqualFold(d.Type, "master") {
+			byName[strings.ToLower(d.Domain)] = d
+		}
+	}
+
+	// Validate every row, and group the valid ones by domain.
+	results := make([]CSVRowResult, len(rows))
+	records := make([]DNSRecord, len(rows))
+	keepTTL := make([]bool, len(rows))
+	var importDomains []Domain
+	rowsByDomain := make(map[int][]int)
+	seen := make(map[string]int)
+
+	for i, row := range rows {
+		res := &results[i]
+		res.Line = row.line
+		keepTTL[i] = row.fields["ttl"] == ""
+		res.Domain = strings.ToLower(strings.TrimSuffix(row.fields["domain"], "."))
+
+		d, ok := byName[res.Domain]
+		if !ok {
+			res.Status = CSVRowInvalid
+			res.Err = fmt.Errorf("dns: no domain named %q", res.Domain)
+			continue
+		}
+
+		records[i], res.Record, err = csvRecord(row.fields, d.Domain)
+		if err != nil {
+			res.Status = CSVRowInvalid
+			res.Err = err
+			continue
+		}
+
+		key := fmt.Sprintf("%d %s %s %s", d.ID, res.Record.Name, res.Record.Type, res.Record.Target)
+		if line, ok := seen[key]; ok {
+			res.Status = CSVRowInvalid
+			res.Err = fmt.Errorf("dns: duplicate of line %d", line)
+			continue
+		}
+		seen[key] = row.line
+
+		if _, ok := rowsByDomain[d.ID]; !ok {
+			importDomains = append(importDomains, d)
+		}
+		rowsByDomain[d.ID] = append(rowsByDomain[d.ID], i)
+	}
+
+	for _, d := range importDomains {
+		c.importCSVDomain(d, rowsByDomain[d.ID], results, records, keepTTL, opts)
+	}
+
+	return results, nil
+}
+
+type csvRow struct {
+	line   int
+	fields map[string]string
+}
+
+// readRecordsCSV reads the rows of a record CSV.  Each row is split off by
+// hand and parsed on its own, so that its line number is known.
+func readRecordsCSV(r io.Reader) ([]csvRow, error) {
+	sc := bufio.NewScanner(r)
+	n := 0
+
+	columns := csvColumns
+	var rows []csvRow
+	first := true
+
+	for sc.Scan() {
+		n++
+		text := sc.Text()
+		if strings.HasPrefix(text, "#") || strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// A quoted field may span lines.
+		line := n
+		for strings.Count(text, `"`)%2 == 1 && sc.Scan() {
+			n++
+			text += "\n" + sc.Text()
+		}
+
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.FieldsPerRecord = -1
+		cr.TrimLeadingSpace = true
+		fields, err := cr.Read()
+		if err != nil {
+			return nil, fmt.Errorf("dns: reading CSV: line %d: %s", line, err)
+		}
+
+		if first {
+			first = false
+			if strings.EqualFold(strings.TrimSpace(fields[0]), "domain") {
+				columns, err = csvHeader(fields)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		if len(fields) > len(columns) {
+			return nil, fmt.Errorf("dns: line %d: %d fields, expected at most %d", line, len(fields), len(columns))
+		}
+
+		row := csvRow{line: line, fields: make(map[string]string)}
+		for i, f := range fields {
+			row.fields[columns[i]] = strings.TrimSpace(f)
+		}
+		rows = append(rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("dns: reading CSV: %s", err)
+	}
+
+	return rows, nil
+}
+
+func csvHeader(fields []string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, col := range csvColumns {
+		known[col] = true
+	}
+
+	var columns []string
+	have := make(map[string]bool)
+	for _, f := range fields {
+		col := strings.ToLower(strings.TrimSpace(f))
+		if !known[col] {
+			return nil, fmt.Errorf("dns: unknown CSV column %q", f)
+		}
+		if have[col] {
+			return nil, fmt.Errorf("dns: duplicate CSV column %q", f)
+		}
+		have[col] = true
+		columns = append(columns, col)
+	}
+
+	for _, col := range csvColumns[:4] {
+		if !have[col] {
+			return nil, fmt.Errorf("dns: CSV is missing the %q column", col)
+		}
+	}
+
+	return columns, nil
+}
+
+// csvRecord validates a row with the record constructors, returning the
+// record to create and its normalized form for matching.
+func csvRecord(fields map[string]string, domain string) (DNSRecord, RecordSpec, error) {
+	name := fields["name"]
+	if strings.HasSuffix(name, ".") {
+		rel, ok := zoneRelative(name, domain)
+		if !ok {
+			return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: %s is not in %s", name, domain)
+		}
+		name = rel
+	} else {
+		name = relativeName(name, domain)
+	}
+
+	ints := make(map[string]int)
+	for _, col := range []string{"ttl", "priority", "weight", "port"} {
+		if fields[col] == "" {
+			continue
+		}
+		v, err := strconv.Atoi(fields[col])
+		if err != nil {
+			return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: invalid %s %q", col, fields[col])
+		}
+		ints[col] = v
+	}
+	ttl := ints["ttl"]
+	target := fields["target"]
+
+	var r DNSRecord
+	var err error
+	switch rType := strings.ToUpper(fields["type"]); rType {
+	case "A", "AAAA":
+		ip := net.ParseIP(target)
+		if ip == nil {
+			return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: invalid address %q", target)
+		}
+		if rType == "A" {
+			r, err = ARecord(name, ip, ttl)
+		} else {
+			r, err = AAAARecord(name, ip, ttl)
+		}
+	case "CNAME":
+		r, err = CNAMERecord(name, target, ttl)
+	case "MX":
+		r, err = MXRecord(name, target, ints["priority"], ttl)
+	case "TXT":
+		r, err = TXTRecord(name, target, ttl)
+	case "NS":
+		r, err = NSRecord(name, target, ttl)
+	case "SRV":
+		labels := strings.Split(name, ".")
+		if len(labels) != 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+			return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: SRV name %q must be _service._protocol", name)
+		}
+		r, err = SRVRecord(labels[0], labels[1], target, ints["priority"], ints["weight"], ints["port"], ttl)
+	case "":
+		return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: missing record type")
+	default:
+		return DNSRecord{}, RecordSpec{}, fmt.Errorf("dns: unsupported record type %q", fields["type"])
+	}
+	if err != nil {
+		return DNSRecord{}, RecordSpec{}, err
+	}
+
+	return r, normalizeRecord(dnsRecordSpec(r), domain), nil
+}
+
+// dnsRecordSpec returns r as a RecordSpec.
+func dnsRecordSpec(r DNSRecord) RecordSpec {
+	rs := RecordSpec{Type: strings.ToUpper(r.Type)}
+	if r.Opts.Name != nil {
+		rs.Name = *r.Opts.Name
+	}
+	if r.Opts.Target != nil {
+		rs.Target = *r.Opts.Target
+	}
+	if r.Opts.TTLSec != nil {
+		rs.TTLSec = *r.Opts.TTLSec
+	}
+	if r.Opts.Priority != nil {
+		rs.Priority = *r.Opts.Priority
+	}
+	if r.Opts.Weight != nil {
+		rs.Weight = *r.Opts.Weight
+	}
+	if r.Opts.Port != nil {
+		rs.Port = *r.Opts.Port
+	}
+	if r.Opts.Protocol != nil {
+		rs.Protocol = *r.Opts.Protocol
+	}
+	return rs
+}
+
+// importCSVDomain matches and imports the rows for one domain, recording the
+// outcome in results.
+func (c *Client) importCSVDomain(d Domain, rows []int, results []CSVRowResult,
+	records []DNSRecord, keepTTL []bool, opts CSVImportOpts) {
+
+	resources, err := c.DomainResourceList(d.ID, nil)
+	if err != nil {
+		for _, i := range rows {
+			results[i].Status = CSVRowFailed
+			results[i].Err = err
+		}
+		return
+	}
+
+	have := make(map[zoneKey][]zoneRecord)
+	for _, r := range resources {
+		zr := zoneRecord{r.ID, normalizeRecord(recordSpecFor(r), d.Domain)}
+		k := zoneKey{zr.spec.Name, zr.spec.Type}
+		have[k] = append(have[k], zr)
+	}
+	for k := range have {
+		recs := have[k]
+		sort.Slice(recs, func(i, j int) bool { return recs[i].id < recs[j].id })
+	}
+	used := make(map[int]bool)
+
+	// match pairs each row with an unused record of the same name and type,
+	// in the passes described in ImportRecordsCSV().
+	matched := make(map[int]zoneRecord)
+	match := func(same func(zoneRecord, RecordSpec) bool) {
+		for _, i := range rows {
+			if _, ok := matched[i]; ok {
+				continue
+			}
+			for _, zr := range have[zoneKey{results[i].Record.Name, results[i].Record.Type}] {
+				rs := results[i].Record
+				if keepTTL[i] {
+					rs.TTLSec = zr.spec.TTLSec
+				}
+				if !used[zr.id] && same(zr, rs) {
+					used[zr.id] = true
+					matched[i] = zr
+					break
+				}
+			}
+		}
+	}
+	match(func(zr zoneRecord, rs RecordSpec) bool { return zr.spec == rs })
+	match(func(zr zoneRecord, rs RecordSpec) bool { return zr.spec.Target == rs.Target })
+	// Other types may hold several values for a name, so an unmatched row is
+	// a new value rather than a replacement.
+	match(func(zr zoneRecord, rs RecordSpec) bool { return rs.Type == "CNAME" })
+
+	a := &zoneApplier{c: c, domainID: d.ID}
+	for _, i := range rows {
+		res := &results[i]
+
+		zr, ok := matched[i]
+		if ok && keepTTL[i] {
+			res.Record.TTLSec = zr.spec.TTLSec
+		}
+		switch {
+		case !ok:
+			res.Status = CSVRowCreated
+			if !opts.DryRun {
+				res.ResourceID, err = c.DomainRecordCreate(d.ID, records[i])
+			}
+		case zr.spec == res.Record:
+			res.Status = CSVRowUnchanged
+			res.ResourceID = zr.id
+		default:
+			res.Status = CSVRowUpdated
+			res.ResourceID = zr.id
+			zc := planRecordUpdate(zr, res.Record)
+			res.Diffs = zc.Diffs
+			if !opts.DryRun {
+				err = zc.apply(a)
+			}
+		}
+
+		if err != nil {
+			res.Status = CSVRowFailed
+			res.Err = err
+			err = nil
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// +build !integration
+
+package linode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+const testRecordsCSV = `Domain,Name,Type,Target,TTL,Priority
+# launch records
+example.com,www,A,192.0.2.10,300
+example.com,api.example.com.,A,192.0.2.20,
+example.com,@,MX,mail.example.com.,,10
+example.com,promo,CNAME,www.example.com,
+example.com,promo2,cname,www.example.com,
+example.com,bad,A,not-an-ip,
+example.com,www,A,192.0.2.10,300
+missing.com,www,A,192.0.2.1,
+example.org,blog,TXT,"hello, world",
+`
+
+func mockCSVDomains() mockAPIResponse {
+	return newMockAPIResponse("domain.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","AXFR_IPS":"none"},`+
+			`{"DOMAINID":31,"DOMAIN":"example.org","TYPE":"master","AXFR_IPS":"none"}`+
+			`],"ACTION":"domain.list"}`)
+}
+
+func TestImportRecordsCSV(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockCSVDomains())
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.10","TTL_SEC":300},`+
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"api","TARGET":"192.0.2.2","TTL_SEC":0},`+
+			`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"mx","NAME":"","TARGET":"mail.example.com","PRIORITY":20}`+
+			`],"ACTION":"domain.resource.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create",
+		map[string]string{"DomainID": "30", "Type": "a", "Name": "api", "Target": "192.0.2.20"},
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":6},"ACTION":"domain.resource.create"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.update",
+		map[string]string{"ResourceID": "3", "DomainID": "30", "Priority": "10"},
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":3},"ACTION":"domain.resource.update"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create",
+		map[string]string{"DomainID": "30", "Type": "cname", "Name": "promo", "Target": "www.example.com"},
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":4},"ACTION":"domain.resource.create"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create",
+		map[string]string{"Name": "promo2"},
+		`{"ERRORARRAY":[{"ERRORCODE":8,"ERRORMESSAGE":"Limit exceeded"}],"DATA":{},"ACTION":"domain.resource.create"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "31"},
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"domain.resource.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create",
+		map[string]string{"DomainID": "31", "Type": "txt", "Name": "blog", "Target": "hello, world"},
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	results, err := c.ImportRecordsCSV(strings.NewReader(testRecordsCSV), CSVImportOpts{})
+	require.NoError(t, err)
+	require.Len(t, results, 9)
+
+	var statuses []CSVRowStatus
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	assert.Equal(t, []CSVRowStatus{
+		CSVRowUnchanged,
+		CSVRowCreated,
+		CSVRowUpdated,
+		CSVRowCreated,
+		CSVRowFailed,
+		CSVRowInvalid,
+		CSVRowInvalid,
+		CSVRowInvalid,
+		CSVRowCreated,
+	}, statuses)
+
+	assert.Equal(t, 3, results[0].Line)
+	assert.Equal(t, 1, results[0].ResourceID)
+	assert.Equal(t, "api", results[1].Record.Name)
+	assert.Equal(t, 6, results[1].ResourceID)
+	assert.Equal(t, []FieldDiff{{"priority", "20", "10"}}, results[2].Diffs)
+	assert.Equal(t, 4, results[3].ResourceID)
+	assert.Equal(t, "line 9: invalid: dns: duplicate of line 3", results[6].String())
+	assert.Equal(t, "line 11: created: example.org blog TXT hello, world", results[8].String())
+}
+
+func TestImportRecordsCSVDryRun(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockCSVDomains())
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.1"}],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// Without a header, the default column order is used.
+	csv := "example.com,www,A,192.0.2.10\nexample.com,_sip._tcp,SRV,sip.example.com,,10,5,5060\n"
+	results, err := c.ImportRecordsCSV(strings.NewReader(csv), CSVImportOpts{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, CSVRowCreated, results[0].Status)
+	assert.Equal(t, CSVRowCreated, results[1].Status)
+	assert.Equal(t, RecordSpec{Type: "SRV", Name: "_sip._tcp", Target: "sip.example.com",
+		Priority: 10, Weight: 5, Port: 5060, Protocol: "tcp"}, results[1].Record)
+}
+
+func TestImportRecordsCSVKeepsValues(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockCSVDomains())
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"txt","NAME":"","TARGET":"v=spf1 mx -all"},`+
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.1"},`+
+			`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"cname","NAME":"alias","TARGET":"old.example.com"}`+
+			`],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// A second TXT or A value is added alongside the existing one, but a
+	// CNAME can only have one target.
+	csv := "example.com,@,TXT,site-verification=abc\n" +
+		"example.com,www,A,192.0.2.2\n" +
+		"example.com,alias,CNAME,new.example.com\n"
+	results, err := c.ImportRecordsCSV(strings.NewReader(csv), CSVImportOpts{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, CSVRowCreated, results[0].Status)
+	assert.Nil(t, results[0].Diffs)
+	assert.Equal(t, CSVRowCreated, results[1].Status)
+	assert.Equal(t, CSVRowUpdated, results[2].Status)
+	assert.Equal(t, 3, results[2].ResourceID)
+}
+
+func TestImportRecordsCSVKeepsTTL(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockCSVDomains())
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.1","TTL_SEC":3600},`+
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"api","TARGET":"192.0.2.2","TTL_SEC":3600}`+
+			`],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// An empty ttl keeps the existing one; a given ttl replaces it.  The
+	// quoted field spans two lines, so the last row is on line 4.
+	csv := "example.com,www,A,192.0.2.1\n" +
+		"example.com,\"txt\n\",TXT,x\n" +
+		"example.com,api,A,192.0.2.2,300\n"
+	results, err := c.ImportRecordsCSV(strings.NewReader(csv), CSVImportOpts{DryRun: true})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	assert.Equal(t, CSVRowUnchanged, results[0].Status)
+	assert.Equal(t, 3600, results[0].Record.TTLSec)
+	assert.Equal(t, CSVRowUpdated, results[2].Status)
+	assert.Equal(t, []FieldDiff{{"ttl_sec", "3600", "300"}}, results[2].Diffs)
+	assert.Equal(t, 4, results[2].Line)
+}
+
+func TestImportRecordsCSVErrors(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	for _, csv := range []string{
+		"domain,name,kind,target\n",
+		"domain,name,type\n",
+		"domain,name,name,type,target\n",
+		"example.com,www,A,192.0.2.1,300,0,0,0,extra\n",
+		"example.com,\"www\n",
+		"example.com,www,A,192.0.2.1\n",
+	} {
+		_, err := c.ImportRecordsCSV(strings.NewReader(csv), CSVImportOpts{})
+		assert.Error(t, err, csv)
+	}
+}
+
+func TestCSVRecord(t *testing.T) {
+	for _, fields := range []map[string]string{
+		{"name": "www.other.com.", "type": "A", "target": "192.0.2.1"},
+		{"name": "www", "type": "A", "target": "192.0.2.1", "ttl": "soon"},
+		{"name": "www", "type": "AAAA", "target": "192.0.2.1"},
+		{"name": "", "type": "CNAME", "target": "www.example.com"},
+		{"name": "sip", "type": "SRV", "target": "sip.example.com"},
+		{"name": "www", "type": "", "target": "192.0.2.1"},
+		{"name": "www", "type": "PTR", "target": "192.0.2.1"},
+	} {
+		_, _, err := csvRecord(fields, "example.com")
+		assert.Error(t, err, "%v", fields)
+	}
+}
@@ -0,0 +1,227 @@
+package linode
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNSRecord is a validated DNS record, ready to be passed to
+// DomainRecordCreate().  It should be created by one of the record
+// constructors, such as ARecord() or MXRecord().
+//
+// Names passed to the constructors are relative to the domain, as the API
+// expects: "www" rather than "www.example.com.".  The apex is "" or "@".
+// TTLs are rounded up to the next value the API accepts, and zero uses the
+// domain's default.
+type DNSRecord struct {
+	Type string
+	Opts DomainResourceCreateOpts
+}
+
+// ARecord returns an A record pointing name at an IPv4 address.
+func ARecord(name string, ip net.IP, ttl int) (DNSRecord, error) {
+	if ip.To4() == nil {
+		return DNSRecord{}, fmt.Errorf("dns: %v is not an IPv4 address", ip)
+	}
+	return newDNSRecord("a", name, ip.To4().String(), ttl)
+}
+
+// AAAARecord returns an AAAA record pointing name at an IPv6 address.
+func AAAARecord(name string, ip net.IP, ttl int) (DNSRecord, error) {
+	if ip.To16() == nil || ip.To4() != nil {
+		return DNSRecord{}, fmt.Errorf("dns: %v is not an IPv6 address", ip)
+	}
+	return newDNSRecord("aaaa", name, ip.String(), ttl)
+}
+
+// CNAMERecord returns a CNAME record aliasing name to target.  A CNAME cannot
+// be created at the apex.
+func CNAMERecord(name string, target string, ttl int) (DNSRecord, error) {
+	name, err := recordName(name)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+	if name == "" {
+		return DNSRecord{}, errors.New("dns: a CNAME cannot be created at the apex")
+	}
+
+	return newHostRecord("cname", name, target, ttl)
+}
+
+// MXRecord returns an MX record routing mail for name to target.  Lower
+// priorities are preferred.
+func MXRecord(name string, target string, priority int, ttl int) (DNSRecord, error) {
+	err := checkUint16("priority", priority)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+
+	r, err := newHostRecord("mx", name, target, ttl)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+	r.Opts.Priority = Int(priority)
+
+	return r, nil
+}
+
+// TXTRecord returns a TXT record for name.  The value is stored as given,
+// without zone file quoting.
+func TXTRecord(name string, value string, ttl int) (DNSRecord, error) {
+	if value == "" {
+		return DNSRecord{}, errors.New("dns: TXT value must not be empty")
+	}
+	return newDNSRecord("txt", name, value, ttl)
+}
+
+// SRVRecord returns an SRV record for service over protocol, such as "sip"
+// and "tcp", at the apex.  The record is named "_service._protocol".
+func SRVRecord(service string, protocol string, target string,
+	priority int, weight int, port int, ttl int) (DNSRecord, error) {
+
+	service = strings.TrimPrefix(strings.ToLower(service), "_")
+	protocol = strings.TrimPrefix(strings.ToLower(protocol), "_")
+	if service == "" || protocol == "" {
+		return DNSRecord{}, errors.New("dns: SRV records need a service and protocol")
+	}
+
+	for _, f := range []struct {
+		name  string
+		value int
+	}{{"priority", priority}, {"weight", weight}, {"port", port}} {
+		err := checkUint16(f.name, f.value)
+		if err != nil {
+			return DNSRecord{}, err
+		}
+	}
+
+	r, err := newHostRecord("srv", "_"+service+"._"+protocol, target, ttl)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+	r.Opts.Priority = Int(priority)
+	r.Opts.Weight = Int(weight)
+	r.Opts.Port = Int(port)
+	r.Opts.Protocol = String(protocol)
+
+	return r, nil
+}
+
+// NSRecord returns an NS record delegating name to the name server target.
+// NS records at the apex are managed by Linode and cannot be created.
+func NSRecord(name string, target string, ttl int) (DNSRecord, error) {
+	name, err := recordName(name)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+	if name == "" {
+		return DNSRecord{}, errors.New("dns: NS records at the apex are managed by Linode")
+	}
+
+	return newHostRecord("ns", name, target, ttl)
+}
+
+// DomainRecordCreate creates a record made by one of the record constructors,
+// returning its resource ID.
+func (c *Client) DomainRecordCreate(domainID int, r DNSRecord) (int, error) {
+	if r.Type == "" {
+		return 0, errors.New("dns: record has no type")
+	}
+	return c.DomainResourceCreate(domainID, r.Type, r.Opts)
+}
+
+func newDNSRecord(rType string, name string, target string, ttl int) (DNSRecord, error) {
+	name, err := recordName(name)
+	if err != nil {
+		return DNSRecord{}, err
+	}
+	if ttl < 0 {
+		return DNSRecord{}, fmt.Errorf("dns: invalid TTL %d", ttl)
+	}
+
+	r := DNSRecord{
+		Type: rType,
+		Opts: DomainResourceCreateOpts{
+			Name:   String(name),
+			Target: String(target),
+		},
+	}
+	if ttl := snapTTL(ttl); ttl != 0 {
+		r.Opts.TTLSec = Int(ttl)
+	}
+
+	return r, nil
+}
+
+func newHostRecord(rType string, name string, target string, ttl int) (DNSRecord, error) {
+	target = strings.TrimSuffix(target, ".")
+	err := checkHostname(target, false)
+	if err != nil {
+		return DNSRecord{}, fmt.Errorf("dns: invalid target %q: %s", target, err)
+	}
+	return newDNSRecord(rType, name, target, ttl)
+}
+
+// recordName validates a relative record name and returns it in the form the
+// API expects.
+func recordName(name string) (string, error) {
+	if name == "@" {
+		return "", nil
+	}
+	if strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("dns: record name %q must be relative to the domain", name)
+	}
+	if name == "" {
+		return "", nil
+	}
+
+	err := checkHostname(name, true)
+	if err != nil {
+		return "", fmt.Errorf("dns: invalid record name %q: %s", name, err)
+	}
+
+	return strings.ToLower(name), nil
+}
+
+// checkHostname checks the length and characters of each label in name.
+// Underscores are allowed, since they are common in service records, and if
+// wildcard is set the first label may be "*".
+func checkHostname(name string, wildcard bool) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	if len(name) > 253 {
+		return errors.New("name is longer than 253 characters")
+	}
+
+	for i, label := range strings.Split(name, ".") {
+		if label == "*" && i == 0 && wildcard {
+			continue
+		}
+		if label == "" || len(label) > 63 {
+			return errors.New("labels must be 1 to 63 characters")
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.New("labels must not start or end with a hyphen")
+		}
+		for _, ch := range label {
+			switch {
+			case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+			case ch == '-' || ch == '_':
+			default:
+				return fmt.Errorf("invalid character %q", ch)
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkUint16(field string, v int) error {
+	if v < 0 || v > 65535 {
+		return fmt.Errorf("dns: %s must be between 0 and 65535", field)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// +build !integration
+
+package linode
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestARecord(t *testing.T) {
+	r, err := ARecord("WWW", net.ParseIP("192.0.2.1"), 299)
+	require.NoError(t, err)
+	assert.Equal(t, DNSRecord{
+		Type: "a",
+		Opts: DomainResourceCreateOpts{
+			Name:   String("www"),
+			Target: String("192.0.2.1"),
+			TTLSec: Int(300),
+		},
+	}, r)
+
+	r, err = ARecord("@", net.ParseIP("192.0.2.1"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "", *r.Opts.Name)
+	assert.Nil(t, r.Opts.TTLSec)
+
+	_, err = ARecord("www", net.ParseIP("2001:db8::1"), 0)
+	assert.Error(t, err)
+	_, err = ARecord("www", nil, 0)
+	assert.Error(t, err)
+}
+
+func TestAAAARecord(t *testing.T) {
+	r, err := AAAARecord("", net.ParseIP("2001:DB8::1"), 3600)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa", r.Type)
+	assert.Equal(t, "2001:db8::1", *r.Opts.Target)
+
+	_, err = AAAARecord("www", net.ParseIP("192.0.2.1"), 0)
+	assert.Error(t, err)
+}
+
+func TestCNAMERecord(t *testing.T) {
+	r, err := CNAMERecord("blog", "www.example.com.", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "www.example.com", *r.Opts.Target)
+
+	_, err = CNAMERecord("@", "www.example.com", 0)
+	assert.Error(t, err)
+}
+
+func TestMXRecord(t *testing.T) {
+	r, err := MXRecord("", "mail.example.com", 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DNSRecord{
+		Type: "mx",
+		Opts: DomainResourceCreateOpts{
+			Name:     String(""),
+			Target:   String("mail.example.com"),
+			Priority: Int(10),
+		},
+	}, r)
+
+	_, err = MXRecord("", "mail.example.com", 70000, 0)
+	assert.Error(t, err)
+	_, err = MXRecord("", "", 10, 0)
+	assert.Error(t, err)
+}
+
+func TestTXTRecord(t *testing.T) {
+	r, err := TXTRecord("_acme-challenge", `"quoted" value`, 0)
+	require.NoError(t, err)
+	assert.Equal(t, `"quoted" value`, *r.Opts.Target)
+
+	_, err = TXTRecord("foo", "", 0)
+	assert.Error(t, err)
+}
+
+func TestSRVRecord(t *testing.T) {
+	r, err := SRVRecord("_SIP", "tcp", "sip.example.com", 5, 10, 5060, 300)
+	require.NoError(t, err)
+	assert.Equal(t, DNSRecord{
+		Type: "srv",
+		Opts: DomainResourceCreateOpts{
+			Name:     String("_sip._tcp"),
+			Target:   String("sip.example.com"),
+			Priority: Int(5),
+			Weight:   Int(10),
+			Port:     Int(5060),
+			Protocol: String("tcp"),
+			TTLSec:   Int(300),
+		},
+	}, r)
+
+	_, err = SRVRecord("", "tcp", "sip.example.com", 5, 10, 5060, 0)
+	assert.Error(t, err)
+	_, err = SRVRecord("sip", "tcp", "sip.example.com", 5, 10, -1, 0)
+	assert.Error(t, err)
+}
+
+func TestNSRecord(t *testing.T) {
+	r, err := NSRecord("sub", "ns1.other.net", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ns", r.Type)
+
+	_, err = NSRecord("", "ns1.other.net", 0)
+	assert.Error(t, err)
+}
+
+func TestRecordNames(t *testing.T) {
+	good := []string{"", "@", "www", "*.dev", "a-b.c_d", strings.Repeat("a", 63)}
+	for _, name := range good {
+		_, err := TXTRecord(name, "x", 0)
+		assert.NoError(t, err, name)
+	}
+
+	bad := []string{"www.example.com.", "a..b", "-www", "www-", "w w", "dev.*",
+		strings.Repeat("a", 64), strings.Repeat("a.", 127) + "a"}
+	for _, name := range bad {
+		_, err := TXTRecord(name, "x", 0)
+		assert.Error(t, err, name)
+	}
+
+	_, err := TXTRecord("www", "x", -1)
+	assert.Error(t, err)
+}
+
+func TestDomainRecordCreate(t *testing.T) {
+	params := map[string]string{
+		"DomainID": "30",
+		"Type":     "mx",
+		"Name":     "",
+		"Target":   "mail.example.com",
+		"Priority": "10",
+	}
+	responses := []mockAPIResponse{newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.create"}`)}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	r, err := MXRecord("@", "mail.example.com", 10, 0)
+	require.NoError(t, err)
+
+	id, err := c.DomainRecordCreate(30, r)
+	require.NoError(t, err)
+	assert.Equal(t, 5, id)
+
+	_, err = c.DomainRecordCreate(30, DNSRecord{})
+	assert.Error(t, err)
+}
@@ -154,6 +154,19 @@ func testErrors(t *testing.T, c *Client, msg string, badReturnData bool) {
 	_, _, errMap["LinodeDiskImagize"] = c.LinodeDiskImagize(0, 0, nil, nil)
 	_, _, errMap["LinodeIPAddPrivate"] = c.LinodeIPAddPrivate(0)
 
+	_, errMap["LinodeBootJob"] = c.LinodeBootJob(0, nil)
+	_, errMap["LinodeRebootJob"] = c.LinodeRebootJob(0, nil)
+	_, errMap["LinodeShutdownJob"] = c.LinodeShutdownJob(0)
+	_, errMap["LinodeDiskDeleteJob"] = c.LinodeDiskDeleteJob(0, 0)
+	_, errMap["LinodeDiskResizeJob"] = c.LinodeDiskResizeJob(0, 0, 0)
+	_, _, errMap["LinodeDiskCreateJob"] = c.LinodeDiskCreateJob(0, "", "", 0)
+	_, _, errMap["LinodeDiskCreateFromDistributionJob"] = c.LinodeDiskCreateFromDistributionJob(0, 0, "", 0, "", nil)
+	_, _, errMap["LinodeDiskCreateFromImageJob"] = c.LinodeDiskCreateFromImageJob(0, 0, "", nil, nil, nil)
+	_, _, errMap["LinodeDiskCreateFromStackScriptJob"] = c.LinodeDiskCreateFromStackScriptJob(0, 0, "", 0, "", 0, "", nil)
+	_, _, errMap["LinodeDiskDuplicateJob"] = c.LinodeDiskDuplicateJob(0, 0)
+	_, _, errMap["LinodeDiskImagizeJob"] = c.LinodeDiskImagizeJob(0, 0, nil, nil)
+	_, errMap["JobPoll"] = c.NewJob(0, 0).Poll()
+
 	var ok int
 
 	for fn, err := range errMap {
@@ -0,0 +1,367 @@
+package linode
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Linode status values, as returned in Linode.Status.
+const (
+	LinodeStatusBeingCreated = -1
+	LinodeStatusBrandNew     = 0
+	LinodeStatusRunning      = 1
+	LinodeStatusPoweredOff   = 2
+)
+
+// EventType identifies the kind of change an Event describes.
+type EventType string
+
+// Event types sent by WatchEvents().
+const (
+	EventJobQueued        EventType = "job_queued"
+	EventJobStarted       EventType = "job_started"
+	EventJobSucceeded     EventType = "job_succeeded"
+	EventJobFailed        EventType = "job_failed"
+	EventLinodeCreated    EventType = "linode_created"
+	EventLinodeDeleted    EventType = "linode_deleted"
+	EventLinodeBooted     EventType = "linode_booted"
+	EventLinodePoweredOff EventType = "linode_powered_off"
+	EventError            EventType = "error"
+)
+
+// Event is a single change observed by WatchEvents().
+type Event struct {
+	Type     EventType
+	LinodeID int
+
+	// Linode is the current state of the Linode for Linode events, or its
+	// last known state for EventLinodeDeleted.  If the Linode was deleted
+	// before it was seen since resuming from a cursor, only its ID, Label,
+	// DisplayGroup, and Status are set.
+	Linode Linode
+
+	// Job is set for job events.  For EventJobFailed, Job.HostMessage
+	// describes the failure.
+	Job LinodeJob
+
+	// Err is set for EventError.  Polling continues after an error.
+	Err error
+
+	// Cursor can be passed to WatchEvents() to resume immediately after
+	// this event.
+	Cursor string
+}
+
+type jobPhase int
+
+const (
+	jobPhaseQueued jobPhase = iota
+	jobPhaseStarted
+	jobPhaseDone
+)
+
+func phaseOf(j LinodeJob) jobPhase {
+	switch {
+	case j.Done():
+		return jobPhaseDone
+	case j.HostStartDT != "":
+		return jobPhaseStarted
+	default:
+		return jobPhaseQueued
+	}
+}
+
+// linodeEventState is what the cursor records about a Linode.  The label
+// and display group are kept so that a deletion seen after resuming can
+// still say which Linode it was.
+type linodeEventState struct {
+	Status       int              `json:"s"`
+	Jobs         map[int]jobPhase `json:"j"`
+	Label        string           `json:"n,omitempty"`
+	DisplayGroup string           `json:"g,omitempty"`
+	linode       Linode
+}
+
+// lastKnown returns the last state seen of the Linode.  After resuming from
+// a cursor, only the fields kept in the cursor are set.
+func (ls *linodeEventState) lastKnown(id int) Linode {
+	if ls.linode.ID != 0 {
+		return ls.linode
+	}
+	return Linode{ID: id, Label: ls.Label, DisplayGroup: ls.DisplayGroup, Status: ls.Status}
+}
+
+func (ls *linodeEventState) see(l Linode) {
+	ls.linode = l
+	ls.Label = l.Label
+	ls.DisplayGroup = l.DisplayGroup
+}
+
+// eventState is encoded in each Event's Cursor.  Filter is the sorted list
+// of watched Linode IDs, or empty if the whole account is watched.
+type eventState struct {
+	Linodes map[int]*linodeEventState `json:"l"`
+	Filter  []int                     `json:"f,omitempty"`
+}
+
+func (s *eventState) cursor() string {
+	// Marshaling a struct of ints and strings cannot fail.
+	b, _ := json.Marshal(s)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseEventCursor(cursor string) (*eventState, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &eventState{}
+	err = json.Unmarshal(b, s)
+	if err != nil {
+		return nil, err
+	}
+	if s.Linodes == nil {
+		s.Linodes = make(map[int]*linodeEventState)
+	}
+	for _, ls := range s.Linodes {
+		if ls.Jobs == nil {
+			ls.Jobs = make(map[int]jobPhase)
+		}
+	}
+
+	return s, nil
+}
+
+// WatchEvents polls the account every interval and sends an Event on the
+// returned channel for each job or Linode state change.  If linodeIDs are
+// passed, only those Linodes are watched; otherwise the whole account is.
+//
+// If cursor is empty, the first poll records the current state without
+// sending any events.  Otherwise it should be the Cursor of the last event
+// the caller handled, and only changes since that event are sent.  The
+// cursor must come from a watch of the same linodeIDs, as Linodes outside of
+// them would otherwise look created or deleted.
+//
+// The channel is closed once the context is done.
+func (c *Client) WatchEvents(ctx context.Context, interval time.Duration, cursor string,
+	linodeIDs ...int) (<-chan Event, error) {
+
+	var s *eventState
+	if cursor != "" {
+		var err error
+		s, err = parseEventCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var filter map[int]bool
+	if len(linodeIDs) != 0 {
+		filter = make(map[int]bool)
+		for _, id := range linodeIDs {
+			filter[id] = true
+		}
+	}
+
+	if s != nil && !equalInts(s.Filter, sortedFilterIDs(filter)) {
+		return nil, errors.New("events: cursor is from a watch of different Linodes")
+	}
+
+	ch := make(chan Event)
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			var events []Event
+			s, events = c.pollEvents(s, filter)
+
+			for _, e := range events {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// pollEvents fetches the current state of the watched Linodes and returns
+// the updated state along with the events leading to it.  A nil state is
+// initialized without generating events.
+func (c *Client) pollEvents(s *eventState, filter map[int]bool) (*eventState, []Event) {
+	var events []Event
+
+	fail := func(err error) (*eventState, []Event) {
+		e := Event{Type: EventError, Err: err}
+		if s != nil {
+			e.Cursor = s.cursor()
+		}
+		return s, append(events, e)
+	}
+
+	linodes, err := c.LinodeList(nil)
+	if err != nil {
+		return fail(err)
+	}
+
+	current := make(map[int]Linode)
+	for _, l := range linodes {
+		if filter == nil || filter[l.ID] {
+			current[l.ID] = l
+		}
+	}
+
+	jobs := make(map[int][]LinodeJob)
+	for id := range current {
+		jobs[id], err = c.LinodeJobList(id, nil, nil)
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if s == nil {
+		s = &eventState{
+			Linodes: make(map[int]*linodeEventState),
+			Filter:  sortedFilterIDs(filter),
+		}
+		for id, l := range current {
+			ls := &linodeEventState{Status: l.Status, Jobs: make(map[int]jobPhase)}
+			ls.see(l)
+			for _, j := range jobs[id] {
+				ls.Jobs[j.ID] = phaseOf(j)
+			}
+			s.Linodes[id] = ls
+		}
+		return s, nil
+	}
+
+	emit := func(t EventType, l Linode, j LinodeJob) {
+		events = append(events, Event{
+			Type:     t,
+			LinodeID: l.ID,
+			Linode:   l,
+			Job:      j,
+			Cursor:   s.cursor(),
+		})
+	}
+
+	for _, id := range sortedEventIDs(current) {
+		l := current[id]
+
+		ls, ok := s.Linodes[id]
+		if !ok {
+			ls = &linodeEventState{Status: l.Status, Jobs: make(map[int]jobPhase)}
+			s.Linodes[id] = ls
+		}
+		ls.see(l)
+		if !ok {
+			emit(EventLinodeCreated, l, LinodeJob{})
+		}
+
+		seen := make(map[int]bool)
+		linodeJobs := jobs[id]
+		sort.Slice(linodeJobs, func(i, j int) bool { return linodeJobs[i].ID < linodeJobs[j].ID })
+
+		for _, j := range linodeJobs {
+			seen[j.ID] = true
+			phase := phaseOf(j)
+			prev, ok := ls.Jobs[j.ID]
+			if ok && prev >= phase {
+				continue
+			}
+			ls.Jobs[j.ID] = phase
+
+			switch {
+			case phase == jobPhaseQueued:
+				emit(EventJobQueued, l, j)
+			case phase == jobPhaseStarted:
+				emit(EventJobStarted, l, j)
+			case j.Success():
+				emit(EventJobSucceeded, l, j)
+			default:
+				emit(EventJobFailed, l, j)
+			}
+		}
+
+		// Forget jobs that have aged out of the Linode's history.
+		for jobID := range ls.Jobs {
+			if !seen[jobID] {
+				delete(ls.Jobs, jobID)
+			}
+		}
+
+		if l.Status != ls.Status {
+			ls.Status = l.Status
+			switch l.Status {
+			case LinodeStatusRunning:
+				emit(EventLinodeBooted, l, LinodeJob{})
+			case LinodeStatusPoweredOff:
+				emit(EventLinodePoweredOff, l, LinodeJob{})
+			}
+		}
+	}
+
+	var gone []int
+	for id := range s.Linodes {
+		if _, ok := current[id]; !ok {
+			gone = append(gone, id)
+		}
+	}
+	sort.Ints(gone)
+
+	for _, id := range gone {
+		l := s.Linodes[id].lastKnown(id)
+		delete(s.Linodes, id)
+		emit(EventLinodeDeleted, l, LinodeJob{})
+	}
+
+	return s, events
+}
+
+func sortedFilterIDs(filter map[int]bool) []int {
+	var ids []int
+	for id := range filter {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func equalInts(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedEventIDs(m map[int]Linode) []int {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,244 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+type fakeAccount struct {
+	mu      sync.Mutex
+	linodes map[int]map[string]interface{}
+	jobs    map[int][]map[string]interface{}
+}
+
+func newFakeAccount() *fakeAccount {
+	return &fakeAccount{
+		linodes: make(map[int]map[string]interface{}),
+		jobs:    make(map[int][]map[string]interface{}),
+	}
+}
+
+func (f *fakeAccount) setLinode(id int, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.linodes[id] = map[string]interface{}{"LINODEID": id, "STATUS": status, "LABEL": fmt.Sprintf("l%d", id)}
+}
+
+func (f *fakeAccount) setJob(linodeID int, jobID int, started bool, finished bool, success bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	j := map[string]interface{}{"JOBID": jobID, "LINODEID": linodeID, "HOST_MESSAGE": "msg"}
+	if started {
+		j["HOST_START_DT"] = "2015-07-08 20:16:40.0"
+	}
+	if finished {
+		j["HOST_FINISH_DT"] = "2015-07-08 20:16:52.0"
+		j["HOST_SUCCESS"] = success
+	}
+
+	for i, old := range f.jobs[linodeID] {
+		if old["JOBID"] == jobID {
+			f.jobs[linodeID][i] = j
+			return
+		}
+	}
+	f.jobs[linodeID] = append(f.jobs[linodeID], j)
+}
+
+func (f *fakeAccount) call(action string, args map[string]interface{}) (json.RawMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch action {
+	case "linode.list":
+		out := []map[string]interface{}{}
+		for _, l := range f.linodes {
+			out = append(out, l)
+		}
+		return json.Marshal(out)
+	case "linode.job.list":
+		out := f.jobs[args["LinodeID"].(int)]
+		if out == nil {
+			out = []map[string]interface{}{}
+		}
+		return json.Marshal(out)
+	}
+
+	return nil, fmt.Errorf("unexpected action %s", action)
+}
+
+func eventTypes(events []Event) []EventType {
+	var out []EventType
+	for _, e := range events {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+func TestPollEvents(t *testing.T) {
+	f := newFakeAccount()
+	f.setLinode(1, LinodeStatusPoweredOff)
+	f.setJob(1, 100, true, true, true)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+
+	s, events := c.pollEvents(nil, nil)
+	require.NotNil(t, s)
+	assert.Len(t, events, 0)
+
+	f.setJob(1, 101, false, false, false)
+	f.setLinode(2, LinodeStatusBeingCreated)
+	f.setJob(2, 200, true, false, false)
+
+	s, events = c.pollEvents(s, nil)
+	assert.Equal(t, []EventType{EventJobQueued, EventLinodeCreated, EventJobStarted}, eventTypes(events))
+	assert.Equal(t, 101, events[0].Job.ID)
+	assert.Equal(t, 2, events[1].LinodeID)
+	assert.Equal(t, 200, events[2].Job.ID)
+
+	f.setJob(1, 101, true, true, true)
+	f.setLinode(1, LinodeStatusRunning)
+	f.setJob(2, 200, true, true, false)
+
+	s, events = c.pollEvents(s, nil)
+	assert.Equal(t, []EventType{EventJobSucceeded, EventLinodeBooted, EventJobFailed}, eventTypes(events))
+	assert.Equal(t, "msg", events[2].Job.HostMessage)
+
+	f.mu.Lock()
+	delete(f.linodes, 2)
+	f.mu.Unlock()
+	f.setLinode(1, LinodeStatusPoweredOff)
+
+	_, events = c.pollEvents(s, nil)
+	assert.Equal(t, []EventType{EventLinodePoweredOff, EventLinodeDeleted}, eventTypes(events))
+	assert.Equal(t, 2, events[1].LinodeID)
+	assert.Equal(t, "l2", events[1].Linode.Label)
+}
+
+func TestPollEventsFilter(t *testing.T) {
+	f := newFakeAccount()
+	f.setLinode(1, LinodeStatusPoweredOff)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+
+	filter := map[int]bool{2: true}
+	s, _ := c.pollEvents(nil, filter)
+
+	f.setJob(1, 100, false, false, false)
+	f.setLinode(2, LinodeStatusBrandNew)
+
+	_, events := c.pollEvents(s, filter)
+	assert.Equal(t, []EventType{EventLinodeCreated}, eventTypes(events))
+}
+
+func TestPollEventsError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	s, events := c.pollEvents(nil, nil)
+	assert.Nil(t, s)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventError, events[0].Type)
+	assert.Equal(t, "bar", events[0].Err.Error())
+}
+
+func TestWatchEventsResume(t *testing.T) {
+	f := newFakeAccount()
+	f.setLinode(1, LinodeStatusPoweredOff)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+
+	s, _ := c.pollEvents(nil, nil)
+	f.setJob(1, 100, false, false, false)
+	f.setJob(1, 101, false, false, false)
+
+	_, events := c.pollEvents(s, nil)
+	require.Len(t, events, 2)
+
+	// Resume after the first event; only the second should be replayed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := c.WatchEvents(ctx, 1*time.Hour, events[0].Cursor)
+	require.NoError(t, err)
+
+	e := <-ch
+	assert.Equal(t, EventJobQueued, e.Type)
+	assert.Equal(t, 101, e.Job.ID)
+	assert.Equal(t, events[1].Cursor, e.Cursor)
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestPollEventsResumeDelete(t *testing.T) {
+	f := newFakeAccount()
+	f.setLinode(1, LinodeStatusPoweredOff)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+
+	s, _ := c.pollEvents(nil, nil)
+	f.setJob(1, 100, false, false, false)
+	_, events := c.pollEvents(s, nil)
+	require.Len(t, events, 1)
+
+	s, err := parseEventCursor(events[0].Cursor)
+	require.NoError(t, err)
+
+	f.mu.Lock()
+	delete(f.linodes, 1)
+	f.mu.Unlock()
+
+	_, events = c.pollEvents(s, nil)
+	require.Equal(t, []EventType{EventLinodeDeleted}, eventTypes(events))
+	assert.Equal(t, 1, events[0].Linode.ID)
+	assert.Equal(t, "l1", events[0].Linode.Label)
+	assert.Equal(t, LinodeStatusPoweredOff, events[0].Linode.Status)
+}
+
+func TestWatchEventsBadCursor(t *testing.T) {
+	c := NewClient("foo")
+
+	_, err := c.WatchEvents(context.Background(), 1*time.Hour, "!!")
+	require.Error(t, err)
+}
+
+func TestWatchEventsCursorFilter(t *testing.T) {
+	f := newFakeAccount()
+	f.setLinode(1, LinodeStatusPoweredOff)
+	f.setLinode(2, LinodeStatusPoweredOff)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+
+	s, _ := c.pollEvents(nil, map[int]bool{1: true, 2: true})
+	cursor := s.cursor()
+
+	// A cursor only resumes a watch of the same Linodes.
+	_, err := c.WatchEvents(context.Background(), 1*time.Hour, cursor, 1)
+	assert.Error(t, err)
+	_, err = c.WatchEvents(context.Background(), 1*time.Hour, cursor)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.WatchEvents(ctx, 1*time.Hour, cursor, 2, 1, 2)
+	require.NoError(t, err)
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,381 @@
+package linode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for DNSFailover.
+const (
+	DefaultFailoverInterval      = 10 * time.Second
+	DefaultFailoverCheckTimeout  = 5 * time.Second
+	DefaultFailoverFailThreshold = 3
+	DefaultFailoverRiseThreshold = 2
+	DefaultFailoverHealthPort    = 80
+)
+
+// FailoverSwitch describes a record being pointed at a different target by
+// DNSFailover.  From is nil if the record pointed outside of the targets.
+type FailoverSwitch struct {
+	Type   string
+	From   net.IP
+	To     net.IP
+	DryRun bool
+	Err    error
+}
+
+// DNSFailover points the A and AAAA records for a name at the first healthy
+// address in a list of targets.  It should be created by a call to
+// NewDNSFailover().
+//
+// Targets are in order of preference, and IPv4 and IPv6 targets are handled
+// separately: the A record is pointed at the first healthy IPv4 target, and
+// the AAAA record at the first healthy IPv6 target.  Each record must already
+// exist, and there must be only one of each type for the name.  When a
+// preferred target recovers, its record is switched back to it.
+//
+// Targets start out healthy.  A target is marked down after FailThreshold
+// failed checks in a row, and back up after RiseThreshold successful ones, so
+// a flapping target does not cause a flapping record.  If every target of a
+// family is down, its record is left alone.
+type DNSFailover struct {
+	FQDN    string
+	Targets []net.IP
+
+	// Check defaults to a TCP connection to port 80.
+	Check         HealthCheck
+	Interval      time.Duration
+	CheckTimeout  time.Duration
+	FailThreshold int
+	RiseThreshold int
+
+	// TTL is kept on the managed records, so that switches take effect
+	// quickly.  It is rounded up to a TTL the API allows.  Zero uses the
+	// shortest.
+	TTL int
+
+	// DryRun reports switches without making them.
+	DryRun bool
+
+	// OnHealthChange, OnSwitch, and OnError, if set, are called when a
+	// target is marked up or down, when a record is switched, and when a
+	// round of checks fails, respectively.
+	OnHealthChange func(ip net.IP, healthy bool)
+	OnSwitch       func(s FailoverSwitch)
+	OnError        func(err error)
+
+	c *Client
+
+	// round serializes calls to CheckOnce(), and guards records and domain.
+	// mu only guards health, so that Healthy() does not wait on the checks.
+	round   sync.Mutex
+	records map[string]*failoverRecord
+	domain  Domain
+	mu      sync.Mutex
+	health  map[string]*failoverHealth
+}
+
+type failoverHealth struct {
+	healthy bool
+	fails   int
+	passes  int
+}
+
+type failoverRecord struct {
+	id     int
+	target net.IP
+	ttl    int
+}
+
+// NewDNSFailover returns a DNSFailover for fqdn with the default timings.
+func (c *Client) NewDNSFailover(fqdn string, targets []net.IP, check HealthCheck) *DNSFailover {
+	if check == nil {
+		check = TCPHealthCheck(DefaultFailoverHealthPort)
+	}
+	return &DNSFailover{
+		FQDN:          strings.ToLower(strings.TrimSuffix(fqdn, ".")),
+		Targets:       targets,
+		Check:         check,
+		Interval:      DefaultFailoverInterval,
+		CheckTimeout:  DefaultFailoverCheckTimeout,
+		FailThreshold: DefaultFailoverFailThreshold,
+		RiseThreshold: DefaultFailoverRiseThreshold,
+		c:             c,
+		health:        make(map[string]*failoverHealth),
+	}
+}
+
+// Run checks the targets every Interval until ctx is done.  Errors are
+// passed to OnError.
+func (f *DNSFailover) Run(ctx context.Context) error {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = DefaultFailoverInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := f.CheckOnce(ctx)
+		if err != nil && ctx.Err() == nil && f.OnError != nil {
+			f.OnError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// CheckOnce checks every target once, and switches the records if needed.
+func (f *DNSFailover) CheckOnce(ctx context.Context) error {
+	f.round.Lock()
+	defer f.round.Unlock()
+
+	if len(f.Targets) == 0 {
+		return errors.New("failover: no targets")
+	}
+	for _, ip := range f.Targets {
+		if ipRecordType(ip) == "" {
+			return fmt.Errorf("failover: invalid target %v", ip)
+		}
+	}
+
+	healthy := f.checkTargets(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if f.records == nil {
+		err := f.lookup()
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []string
+	for _, rType := range []string{"A", "AAAA"} {
+		rec, ok := f.records[rType]
+		if !ok {
+			continue
+		}
+		err := f.reconcile(rType, rec, healthy)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// checkTargets runs the health checks concurrently, without holding f.mu,
+// then updates each target's state.  It returns whether each target is
+// healthy, by address.
+func (f *DNSFailover) checkTargets(ctx context.Context) map[string]bool {
+	timeout := f.CheckTimeout
+	if timeout <= 0 {
+		timeout = DefaultFailoverCheckTimeout
+	}
+
+	results := make([]error, len(f.Targets))
+	var wg sync.WaitGroup
+	for i, ip := range f.Targets {
+		wg.Add(1)
+		go func(i int, ip net.IP) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results[i] = f.Check(cctx, ip)
+		}(i, ip)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// The failures are ours, not the targets'.
+		return nil
+	}
+
+	fail := f.FailThreshold
+	if fail <= 0 {
+		fail = DefaultFailoverFailThreshold
+	}
+	rise := f.RiseThreshold
+	if rise <= 0 {
+		rise = DefaultFailoverRiseThreshold
+	}
+
+	healthy := make(map[string]bool)
+	var changed []net.IP
+
+	f.mu.Lock()
+	for i, ip := range f.Targets {
+		h, ok := f.health[ip.String()]
+		if !ok {
+			h = &failoverHealth{healthy: true}
+			f.health[ip.String()] = h
+		}
+
+		was := h.healthy
+		if results[i] == nil {
+			h.fails = 0
+			h.passes++
+			if h.passes >= rise {
+				h.healthy = true
+			}
+		} else {
+			h.passes = 0
+			h.fails++
+			if h.fails >= fail {
+				h.healthy = false
+			}
+		}
+
+		healthy[ip.String()] = h.healthy
+		if h.healthy != was {
+			changed = append(changed, ip)
+		}
+	}
+	f.mu.Unlock()
+
+	if f.OnHealthChange != nil {
+		for _, ip := range changed {
+			f.OnHealthChange(ip, healthy[ip.String()])
+		}
+	}
+	return healthy
+}
+
+// Healthy reports whether ip is currently considered healthy.  Targets that
+// have not been checked yet are.
+func (f *DNSFailover) Healthy(ip net.IP) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, ok := f.health[ip.String()]
+	return !ok || h.healthy
+}
+
+// lookup finds the records for each family that has targets.
+func (f *DNSFailover) lookup() error {
+	if f.domain.ID == 0 {
+		d, err := f.c.findDomain(f.FQDN)
+		if err != nil {
+			return err
+		}
+		f.domain = d
+	}
+	name, _ := zoneRelative(f.FQDN, f.domain.Domain)
+
+	resources, err := f.c.DomainResourceList(f.domain.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	records := make(map[string]*failoverRecord)
+	for _, ip := range f.Targets {
+		rType := ipRecordType(ip)
+		if _, ok := records[rType]; ok {
+			continue
+		}
+
+		var found []DomainResource
+		for _, r := range resources {
+			if strings.EqualFold(r.Type, rType) && strings.EqualFold(r.Name, name) {
+				found = append(found, r)
+			}
+		}
+		switch len(found) {
+		case 0:
+			return fmt.Errorf("failover: no %s record for %s", rType, f.FQDN)
+		case 1:
+		default:
+			return fmt.Errorf("failover: %d %s records for %s, expected one", len(found), rType, f.FQDN)
+		}
+
+		ttl := found[0].TTLSec
+		if ttl == 0 {
+			ttl = f.domain.TTLSec
+		}
+		records[rType] = &failoverRecord{
+			id:     found[0].ID,
+			target: net.ParseIP(found[0].Target),
+			ttl:    ttl,
+		}
+	}
+
+	f.records = records
+	return nil
+}
+
+// reconcile points the record at the first healthy target of its family.
+func (f *DNSFailover) reconcile(rType string, rec *failoverRecord, healthy map[string]bool) error {
+	var want net.IP
+	for _, ip := range f.Targets {
+		if ipRecordType(ip) != rType {
+			continue
+		}
+		if healthy[ip.String()] {
+			want = ip
+			break
+		}
+	}
+	if want == nil {
+		return fmt.Errorf("failover: no healthy %s targets for %s", rType, f.FQDN)
+	}
+
+	ttl := snapTTL(f.TTL)
+	if ttl == 0 {
+		ttl = allowedTTLs[0]
+	}
+
+	switched := !rec.target.Equal(want)
+	if !switched && rec.ttl == ttl {
+		return nil
+	}
+
+	s := FailoverSwitch{Type: rType, To: want, DryRun: f.DryRun}
+	for _, ip := range f.Targets {
+		if ip.Equal(rec.target) {
+			s.From = rec.target
+		}
+	}
+
+	if !f.DryRun {
+		opts := DomainResourceUpdateOpts{
+			DomainID: Int(f.domain.ID),
+			TTLSec:   Int(ttl),
+		}
+		if switched {
+			opts.Target = String(want.String())
+		}
+		err := f.c.DomainResourceUpdate(rec.id, opts)
+		if err != nil {
+			// The record may have been changed or deleted; look it up again
+			// next time.
+			f.records = nil
+			err = fmt.Errorf("failover: updating %s %s: %s", rType, f.FQDN, err)
+			if switched && f.OnSwitch != nil {
+				s.Err = err
+				f.OnSwitch(s)
+			}
+			return err
+		}
+	}
+
+	rec.target = want
+	rec.ttl = ttl
+	if switched && f.OnSwitch != nil {
+		f.OnSwitch(s)
+	}
+	return nil
+}
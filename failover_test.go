@@ -0,0 +1,176 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockFailoverLookup() []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","TTL_SEC":0,"AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"app","TARGET":"192.0.2.1","TTL_SEC":3600},`+
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.9","TTL_SEC":3600}`+
+			`],"ACTION":"domain.resource.list"}`))
+
+	return responses
+}
+
+type fakeHealth map[string]bool
+
+func (h fakeHealth) check(ctx context.Context, ip net.IP) error {
+	if h[ip.String()] {
+		return nil
+	}
+	return errors.New("down")
+}
+
+func TestDNSFailover(t *testing.T) {
+	responses := mockFailoverLookup()
+	params := map[string]string{"ResourceID": "1", "DomainID": "30", "TTL_sec": "300"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+	params = map[string]string{"ResourceID": "1", "Target": "192.0.2.2", "TTL_sec": "300"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+	params = map[string]string{"ResourceID": "1", "Target": "192.0.2.1"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	primary, backup := net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")
+	health := fakeHealth{"192.0.2.1": true, "192.0.2.2": true}
+
+	f := c.NewDNSFailover("App.example.com.", []net.IP{primary, backup}, health.check)
+	var switches []FailoverSwitch
+	f.OnSwitch = func(s FailoverSwitch) {
+		switches = append(switches, s)
+	}
+	var changes []string
+	f.OnHealthChange = func(ip net.IP, healthy bool) {
+		changes = append(changes, ip.String()+" "+strconv.FormatBool(healthy))
+	}
+
+	ctx := context.Background()
+
+	// Only the TTL is lowered.
+	require.NoError(t, f.CheckOnce(ctx))
+	assert.Len(t, switches, 0)
+
+	// The primary is marked down on the third failure.
+	health["192.0.2.1"] = false
+	require.NoError(t, f.CheckOnce(ctx))
+	require.NoError(t, f.CheckOnce(ctx))
+	assert.True(t, f.Healthy(primary))
+	require.NoError(t, f.CheckOnce(ctx))
+	assert.False(t, f.Healthy(primary))
+	require.Len(t, switches, 1)
+	assert.Equal(t, FailoverSwitch{Type: "A", From: primary, To: backup}, switches[0])
+
+	// And back up on the second success.
+	health["192.0.2.1"] = true
+	require.NoError(t, f.CheckOnce(ctx))
+	assert.Len(t, switches, 1)
+	require.NoError(t, f.CheckOnce(ctx))
+	require.Len(t, switches, 2)
+	assert.Equal(t, FailoverSwitch{Type: "A", From: backup, To: primary}, switches[1])
+
+	// Everything down leaves the record alone.
+	health["192.0.2.1"] = false
+	health["192.0.2.2"] = false
+	f.FailThreshold = 1
+	assert.Error(t, f.CheckOnce(ctx))
+	assert.Len(t, switches, 2)
+
+	assert.Equal(t, []string{
+		"192.0.2.1 false",
+		"192.0.2.1 true",
+		"192.0.2.1 false",
+		"192.0.2.2 false",
+	}, changes)
+}
+
+func TestDNSFailoverDryRun(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFailoverLookup()))
+	defer ts.Close()
+
+	health := fakeHealth{"192.0.2.2": true}
+	f := c.NewDNSFailover("app.example.com", []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, health.check)
+	f.DryRun = true
+	f.FailThreshold = 1
+
+	var switches []FailoverSwitch
+	f.OnSwitch = func(s FailoverSwitch) {
+		switches = append(switches, s)
+	}
+
+	require.NoError(t, f.CheckOnce(context.Background()))
+	require.NoError(t, f.CheckOnce(context.Background()))
+	require.Len(t, switches, 1)
+	assert.True(t, switches[0].DryRun)
+	assert.Equal(t, "192.0.2.2", switches[0].To.String())
+}
+
+func TestDNSFailoverErrors(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFailoverLookup()))
+	defer ts.Close()
+
+	health := fakeHealth{}
+	f := c.NewDNSFailover("app.example.com", []net.IP{net.ParseIP("2001:db8::1")}, health.check)
+	assert.Error(t, f.CheckOnce(context.Background()))
+
+	f.Targets = nil
+	assert.Error(t, f.CheckOnce(context.Background()))
+
+	f.Targets = []net.IP{nil}
+	assert.Error(t, f.CheckOnce(context.Background()))
+}
+
+func TestDNSFailoverHealthyDuringCheck(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	target := net.ParseIP("192.0.2.1")
+	f := c.NewDNSFailover("app.example.com", []net.IP{target}, func(ctx context.Context, ip net.IP) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	errc := make(chan error)
+	go func() {
+		errc <- f.CheckOnce(context.Background())
+	}()
+
+	// Healthy() answers while the check is still running.
+	<-started
+	done := make(chan bool)
+	go func() {
+		done <- f.Healthy(target)
+	}()
+	select {
+	case healthy := <-done:
+		assert.True(t, healthy)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Healthy() blocked on a running check")
+	}
+
+	close(release)
+	assert.Error(t, <-errc)
+}
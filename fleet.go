@@ -0,0 +1,1070 @@
+package linode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FleetSpec is the desired state of an account.  It is usually read from a
+// JSON file with ReadFleetSpec(), and can be generated from an existing
+// account with ExportFleet().  Only JSON is supported; YAML specs must be
+// converted first.
+//
+// PlanFleet() only manages Linodes.
+type FleetSpec struct {
+	Linodes       []LinodeSpec       `json:"linodes,omitempty"`
+	Domains       []DomainSpec       `json:"domains,omitempty"`
+	NodeBalancers []NodeBalancerSpec `json:"nodebalancers,omitempty"`
+}
+
+// LinodeSpec is the desired state of a single Linode.  Linodes are matched to
+// existing ones by Label, which must be unique.
+//
+// Optional fields left nil are not managed.  In particular, existing disks
+// and configs are only deleted when Disks or Configs, respectively, is set;
+// an empty list deletes them all.
+type LinodeSpec struct {
+	Label           string        `json:"label"`
+	DatacenterID    int           `json:"datacenter_id"`
+	PlanID          int           `json:"plan_id"`
+	DisplayGroup    string        `json:"display_group,omitempty"`
+	Watchdog        *bool         `json:"watchdog,omitempty"`
+	BackupWindow    *int          `json:"backup_window,omitempty"`
+	BackupWeeklyDay *int          `json:"backup_weekly_day,omitempty"`
+	Alerts          *LinodeAlerts `json:"alerts,omitempty"`
+	PrivateIP       bool          `json:"private_ip,omitempty"`
+	Disks           []DiskSpec    `json:"disks,omitempty"`
+	Configs         []ConfigSpec  `json:"configs,omitempty"`
+}
+
+// LinodeAlerts are the alert settings of a LinodeSpec.
+type LinodeAlerts struct {
+	CPU     *AlertSpec `json:"cpu,omitempty"`
+	DiskIO  *AlertSpec `json:"disk_io,omitempty"`
+	BWIn    *AlertSpec `json:"bw_in,omitempty"`
+	BWOut   *AlertSpec `json:"bw_out,omitempty"`
+	BWQuota *AlertSpec `json:"bw_quota,omitempty"`
+}
+
+// AlertSpec is the setting of a single alert.
+type AlertSpec struct {
+	Enabled   bool `json:"enabled"`
+	Threshold int  `json:"threshold"`
+}
+
+// DiskSpec is the desired state of a disk.  Disks are matched to existing
+// ones by Label.  Existing disks without a label are known as "disk-<ID>",
+// and all but the first of several with the same label as "<label>-<ID>",
+// as in ExportFleet().
+//
+// New disks are created from DistributionID or ImageID if set, and are
+// otherwise blank disks of Type.
+type DiskSpec struct {
+	Label          string `json:"label"`
+	Type           string `json:"type"`
+	Size           int    `json:"size"`
+	ReadOnly       bool   `json:"read_only,omitempty"`
+	DistributionID int    `json:"distribution_id,omitempty"`
+	ImageID        int    `json:"image_id,omitempty"`
+}
+
+// ConfigSpec is the desired state of a boot configuration.  Configurations
+// are matched to existing ones by Label, which is made unique the same way
+// as a DiskSpec's.  Disks lists disk labels in device order.
+type ConfigSpec struct {
+	Label         string   `json:"label"`
+	KernelID      int      `json:"kernel_id"`
+	Disks         []string `json:"disks"`
+	Comments      string   `json:"comments,omitempty"`
+	RAMLimit      int      `json:"ram_limit,omitempty"`
+	RunLevel      string   `json:"run_level,omitempty"`
+	VirtMode      string   `json:"virt_mode,omitempty"`
+	RootDeviceNum int      `json:"root_device_num,omitempty"`
+	RootDeviceRO  *bool    `json:"root_device_ro,omitempty"`
+	HelperDistro  *bool    `json:"helper_distro,omitempty"`
+	HelperNetwork *bool    `json:"helper_network,omitempty"`
+}
+
+// DomainSpec is the desired state of a DNS zone.  Domains are matched to
+// existing ones by Domain.
+type DomainSpec struct {
+	Domain       string       `json:"domain"`
+	Type         string       `json:"type"`
+	Description  string       `json:"description,omitempty"`
+	SOAEmail     string       `json:"soa_email,omitempty"`
+	RefreshSec   int          `json:"refresh_sec,omitempty"`
+	RetrySec     int          `json:"retry_sec,omitempty"`
+	ExpireSec    int          `json:"expire_sec,omitempty"`
+	TTLSec       int          `json:"ttl_sec,omitempty"`
+	DisplayGroup string       `json:"display_group,omitempty"`
+	Status       int          `json:"status,omitempty"`
+	MasterIPs    []string     `json:"master_ips,omitempty"`
+	AXFRIPs      []string     `json:"axfr_ips,omitempty"`
+	Records      []RecordSpec `json:"records,omitempty"`
+}
+
+// RecordSpec is the desired state of a single DNS record.  Name is relative
+// to the domain, and empty for the apex.
+type RecordSpec struct {
+	Type     string `json:"type"`
+	Name     string `json:"name,omitempty"`
+	Target   string `json:"target"`
+	TTLSec   int    `json:"ttl_sec,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+	Weight   int    `json:"weight,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// NodeBalancerSpec is the desired state of a NodeBalancer.  NodeBalancers are
+// matched to existing ones by Label.
+type NodeBalancerSpec struct {
+	Label        string                   `json:"label"`
+	DatacenterID int                      `json:"datacenter_id"`
+	Throttle     int                      `json:"throttle,omitempty"`
+	Configs      []NodeBalancerConfigSpec `json:"configs,omitempty"`
+}
+
+// NodeBalancerConfigSpec is the desired state of a NodeBalancer config.
+// Configs are matched to existing ones by Port.
+//
+// SSLCert and SSLKey are never exported, since the API does not return them.
+type NodeBalancerConfigSpec struct {
+	Port          int                    `json:"port"`
+	Protocol      string                 `json:"protocol"`
+	Algorithm     string                 `json:"algorithm,omitempty"`
+	Stickiness    string                 `json:"stickiness,omitempty"`
+	Check         string                 `json:"check,omitempty"`
+	CheckInterval int                    `json:"check_interval,omitempty"`
+	CheckTimeout  int                    `json:"check_timeout,omitempty"`
+	CheckAttempts int                    `json:"check_attempts,omitempty"`
+	CheckPath     string                 `json:"check_path,omitempty"`
+	CheckBody     string                 `json:"check_body,omitempty"`
+	CheckPassive  bool                   `json:"check_passive,omitempty"`
+	SSLCert       string                 `json:"ssl_cert,omitempty"`
+	SSLKey        string                 `json:"ssl_key,omitempty"`
+	Nodes         []NodeBalancerNodeSpec `json:"nodes,omitempty"`
+}
+
+// NodeBalancerNodeSpec is the desired state of a NodeBalancer node.  Nodes are
+// matched to existing ones by Label.
+//
+// A node's backend is either Address ("ip:port"), or the private IP of the
+// Linode labeled Linode on Port.
+type NodeBalancerNodeSpec struct {
+	Label   string `json:"label"`
+	Address string `json:"address,omitempty"`
+	Linode  string `json:"linode,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Weight  int    `json:"weight,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+}
+
+// ReadFleetSpec decodes a JSON fleet spec.  Unknown fields are an error.
+func ReadFleetSpec(r io.Reader) (FleetSpec, error) {
+	var spec FleetSpec
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&spec)
+	if err != nil {
+		return FleetSpec{}, err
+	}
+
+	return spec, nil
+}
+
+// ChangeKind is the kind of a planned change.
+type ChangeKind string
+
+// Kinds of planned changes.
+const (
+	ChangeCreate ChangeKind = "create"
+	ChangeUpdate ChangeKind = "update"
+	ChangeResize ChangeKind = "resize"
+	ChangeDelete ChangeKind = "delete"
+)
+
+var changeSymbols = map[ChangeKind]string{
+	ChangeCreate: "+",
+	ChangeUpdate: "~",
+	ChangeResize: "~",
+	ChangeDelete: "-",
+}
+
+// FieldDiff is a single field changed by a planned change.
+type FieldDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// FleetChange is a single change in a FleetPlan.
+type FleetChange struct {
+	Kind ChangeKind
+
+	// Resource is "linode", "ip", "disk", or "config".
+	Resource string
+
+	// Linode is the label of the Linode being changed, and Name is the label
+	// of the disk or config, if any.
+	Linode string
+	Name   string
+
+	Diffs []FieldDiff
+
+	// Destructive is true for changes that lose data or cause downtime:
+	// deletions, plan changes, disk shrinks, and disk replacements.
+	Destructive bool
+
+	apply func(*fleetApplier) error
+}
+
+func (fc FleetChange) String() string {
+	name := fc.Linode
+	if fc.Name != "" {
+		name += "/" + fc.Name
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s %s", changeSymbols[fc.Kind], fc.Resource, name)
+	if fc.Destructive {
+		buf.WriteString(" (destructive)")
+	}
+	for _, d := range fc.Diffs {
+		fmt.Fprintf(&buf, "\n    %s: %q -> %q", d.Field, d.Old, d.New)
+	}
+
+	return buf.String()
+}
+
+// FleetPlan is the set of changes needed to bring an account in line with a
+// FleetSpec.  It should be created by a call to PlanFleet().
+type FleetPlan struct {
+	Changes []FleetChange
+}
+
+// Empty returns true if the plan has no changes.
+func (p *FleetPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Destructive returns true if any change in the plan is destructive.
+func (p *FleetPlan) Destructive() bool {
+	for _, c := range p.Changes {
+		if c.Destructive {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FleetPlan) String() string {
+	if p.Empty() {
+		return "No changes.\n"
+	}
+
+	var buf bytes.Buffer
+	for _, c := range p.Changes {
+		buf.WriteString(c.String())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// FleetPlanOpts contains the optional arguments to PlanFleet().
+type FleetPlanOpts struct {
+	// Prune deletes Linodes not in the spec.
+	Prune bool
+}
+
+type existingLinode struct {
+	linode     Linode
+	disks      []LinodeDisk
+	configs    []LinodeConfig
+	hasPrivate bool
+}
+
+// PlanFleet compares the spec with the account and returns the changes needed
+// to make them match.  Nothing is changed until the plan is passed to
+// ApplyFleet().
+func (c *Client) PlanFleet(spec FleetSpec, opts FleetPlanOpts) (*FleetPlan, error) {
+	err := validateFleetSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	linodes, err := c.LinodeList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*existingLinode)
+	for _, l := range linodes {
+		if _, dup := existing[l.Label]; dup {
+			return nil, fmt.Errorf("fleet: more than one Linode is labeled %q", l.Label)
+		}
+		existing[l.Label] = &existingLinode{linode: l}
+	}
+
+	plan := &FleetPlan{}
+	var deletes []FleetChange
+
+	for _, ls := range spec.Linodes {
+		e, ok := existing[ls.Label]
+		if !ok {
+			plan.Changes = append(plan.Changes, planLinodeCreate(ls)...)
+			continue
+		}
+
+		e.disks, err = c.LinodeDiskList(e.linode.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		uniqueDiskLabels(e.disks)
+		e.configs, err = c.LinodeConfigList(e.linode.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		uniqueConfigLabels(e.configs)
+		if ls.PrivateIP {
+			ips, err := c.LinodeIPList(Int(e.linode.ID), nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if !ip.IsPublic {
+					e.hasPrivate = true
+				}
+			}
+		}
+
+		changes, dels, err := planLinodeUpdate(ls, e)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, changes...)
+		deletes = append(deletes, dels...)
+	}
+
+	if opts.Prune {
+		wanted := make(map[string]bool)
+		for _, ls := range spec.Linodes {
+			wanted[ls.Label] = true
+		}
+
+		var labels []string
+		for label := range existing {
+			if !wanted[label] {
+				labels = append(labels, label)
+			}
+		}
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			id := existing[label].linode.ID
+			deletes = append(deletes, FleetChange{
+				Kind:        ChangeDelete,
+				Resource:    "linode",
+				Linode:      label,
+				Destructive: true,
+				apply: func(a *fleetApplier) error {
+					return a.c.LinodeDelete(id, Bool(true))
+				},
+			})
+		}
+	}
+
+	// Deletions go last so configs stop referencing disks before the disks
+	// are removed.
+	plan.Changes = append(plan.Changes, deletes...)
+
+	return plan, nil
+}
+
+func validateFleetSpec(spec FleetSpec) error {
+	labels := make(map[string]bool)
+
+	for _, ls := range spec.Linodes {
+		if ls.Label == "" {
+			return errors.New("fleet: every Linode must have a label")
+		}
+		if labels[ls.Label] {
+			return fmt.Errorf("fleet: duplicate Linode label %q", ls.Label)
+		}
+		labels[ls.Label] = true
+
+		if ls.DatacenterID <= 0 || ls.PlanID <= 0 {
+			return fmt.Errorf("fleet: %s: datacenter_id and plan_id are required", ls.Label)
+		}
+
+		disks := make(map[string]bool)
+		for _, d := range ls.Disks {
+			if d.Label == "" || disks[d.Label] {
+				return fmt.Errorf("fleet: %s: disk labels must be unique and non-empty", ls.Label)
+			}
+			disks[d.Label] = true
+		}
+
+		configs := make(map[string]bool)
+		for _, cs := range ls.Configs {
+			if cs.Label == "" || configs[cs.Label] {
+				return fmt.Errorf("fleet: %s: config labels must be unique and non-empty", ls.Label)
+			}
+			configs[cs.Label] = true
+
+			for _, d := range cs.Disks {
+				if d != "" && !disks[d] {
+					return fmt.Errorf("fleet: %s: config %s references unknown disk %q",
+						ls.Label, cs.Label, d)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func planLinodeCreate(ls LinodeSpec) []FleetChange {
+	var changes []FleetChange
+
+	opts, diffs := linodeOptsFor(ls, Linode{})
+	opts.Label = String(ls.Label)
+
+	changes = append(changes, FleetChange{
+		Kind:     ChangeCreate,
+		Resource: "linode",
+		Linode:   ls.Label,
+		Diffs: append([]FieldDiff{
+			{"datacenter_id", "", strconv.Itoa(ls.DatacenterID)},
+			{"plan_id", "", strconv.Itoa(ls.PlanID)},
+		}, diffs...),
+		apply: func(a *fleetApplier) error {
+			id, err := a.c.LinodeCreate(ls.DatacenterID, ls.PlanID, nil)
+			if err != nil {
+				return err
+			}
+			a.linodeIDs[ls.Label] = id
+			return a.c.LinodeUpdate(id, opts)
+		},
+	})
+
+	if ls.PrivateIP {
+		changes = append(changes, planPrivateIP(ls.Label))
+	}
+	for _, d := range ls.Disks {
+		changes = append(changes, planDiskCreate(ls.Label, d))
+	}
+	for _, cs := range ls.Configs {
+		changes = append(changes, planConfigCreate(ls.Label, cs))
+	}
+
+	return changes
+}
+
+// planPrivateIP adds a private IP.  Private IPs are never removed.
+func planPrivateIP(linode string) FleetChange {
+	return FleetChange{
+		Kind:     ChangeCreate,
+		Resource: "ip",
+		Linode:   linode,
+		Name:     "private",
+		apply: func(a *fleetApplier) error {
+			_, _, err := a.c.LinodeIPAddPrivate(a.linodeIDs[linode])
+			return err
+		},
+	}
+}
+
+func planLinodeUpdate(ls LinodeSpec, e *existingLinode) (changes []FleetChange,
+	deletes []FleetChange, err error) {
+
+	l := e.linode
+
+	if ls.DatacenterID != l.DatacenterID {
+		return nil, nil, fmt.Errorf("fleet: %s: the datacenter of an existing Linode cannot be changed",
+			ls.Label)
+	}
+
+	opts, diffs := linodeOptsFor(ls, l)
+	if len(diffs) != 0 {
+		changes = append(changes, FleetChange{
+			Kind:     ChangeUpdate,
+			Resource: "linode",
+			Linode:   ls.Label,
+			Diffs:    diffs,
+			apply: func(a *fleetApplier) error {
+				return a.c.LinodeUpdate(l.ID, opts)
+			},
+		})
+	}
+
+	if ls.PlanID != l.PlanID {
+		changes = append(changes, FleetChange{
+			Kind:        ChangeResize,
+			Resource:    "linode",
+			Linode:      ls.Label,
+			Diffs:       []FieldDiff{{"plan_id", strconv.Itoa(l.PlanID), strconv.Itoa(ls.PlanID)}},
+			Destructive: true,
+			apply: func(a *fleetApplier) error {
+				return a.c.LinodeResize(l.ID, ls.PlanID)
+			},
+		})
+	}
+
+	if ls.PrivateIP && !e.hasPrivate {
+		changes = append(changes, planPrivateIP(ls.Label))
+	}
+
+	diskLabels := make(map[int]string)
+	disks := make(map[string]LinodeDisk)
+	for _, d := range e.disks {
+		diskLabels[d.ID] = d.Label
+		disks[d.Label] = d
+	}
+
+	wantDisks := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for _, ds := range ls.Disks {
+		wantDisks[ds.Label] = true
+
+		d, ok := disks[ds.Label]
+		if !ok {
+			changes = append(changes, planDiskCreate(ls.Label, ds))
+			continue
+		}
+		replaced[ds.Label] = ds.Type != d.Type
+		updates, dels := planDiskUpdate(ls.Label, ds, d)
+		changes = append(changes, updates...)
+		deletes = append(deletes, dels...)
+	}
+
+	configs := make(map[string]LinodeConfig)
+	for _, lc := range e.configs {
+		configs[lc.Label] = lc
+	}
+
+	wantConfigs := make(map[string]bool)
+	for _, cs := range ls.Configs {
+		wantConfigs[cs.Label] = true
+
+		lc, ok := configs[cs.Label]
+		if !ok {
+			changes = append(changes, planConfigCreate(ls.Label, cs))
+			continue
+		}
+		if fc, ok := planConfigUpdate(ls.Label, cs, lc, diskLabels, replaced); ok {
+			changes = append(changes, fc)
+		}
+	}
+
+	for _, lc := range e.configs {
+		if ls.Configs == nil || wantConfigs[lc.Label] {
+			continue
+		}
+		lc := lc
+		deletes = append(deletes, FleetChange{
+			Kind:        ChangeDelete,
+			Resource:    "config",
+			Linode:      ls.Label,
+			Name:        lc.Label,
+			Destructive: true,
+			apply: func(a *fleetApplier) error {
+				return a.c.LinodeConfigDelete(l.ID, lc.ID)
+			},
+		})
+	}
+
+	for _, d := range e.disks {
+		if ls.Disks == nil || wantDisks[d.Label] {
+			continue
+		}
+		deletes = append(deletes, planDiskDelete(ls.Label, d))
+	}
+
+	return changes, deletes, nil
+}
+
+func linodeOptsFor(ls LinodeSpec, l Linode) (LinodeOpts, []FieldDiff) {
+	var opts LinodeOpts
+	var diffs []FieldDiff
+
+	if ls.DisplayGroup != l.DisplayGroup {
+		opts.DisplayGroup = String(ls.DisplayGroup)
+		diffs = append(diffs, FieldDiff{"display_group", l.DisplayGroup, ls.DisplayGroup})
+	}
+	if ls.Watchdog != nil && *ls.Watchdog != l.Watchdog {
+		opts.Watchdog = ls.Watchdog
+		diffs = append(diffs, boolDiff("watchdog", l.Watchdog, *ls.Watchdog))
+	}
+	if ls.BackupWindow != nil && *ls.BackupWindow != l.BackupWindow {
+		opts.BackupWindow = ls.BackupWindow
+		diffs = append(diffs, intDiff("backup_window", l.BackupWindow, *ls.BackupWindow))
+	}
+	if ls.BackupWeeklyDay != nil && *ls.BackupWeeklyDay != l.BackupWeeklyDay {
+		opts.BackupWeeklyDay = ls.BackupWeeklyDay
+		diffs = append(diffs, intDiff("backup_weekly_day", l.BackupWeeklyDay, *ls.BackupWeeklyDay))
+	}
+
+	if ls.Alerts == nil {
+		return opts, diffs
+	}
+
+	alert := func(name string, spec *AlertSpec, enabled bool, threshold int,
+		optEnabled **bool, optThreshold **int) {
+
+		if spec == nil {
+			return
+		}
+		if spec.Enabled != enabled {
+			*optEnabled = Bool(spec.Enabled)
+			diffs = append(diffs, boolDiff("alerts."+name+".enabled", enabled, spec.Enabled))
+		}
+		if spec.Threshold != threshold {
+			*optThreshold = Int(spec.Threshold)
+			diffs = append(diffs, intDiff("alerts."+name+".threshold", threshold, spec.Threshold))
+		}
+	}
+
+	a := ls.Alerts
+	alert("cpu", a.CPU, l.AlertCPUEnabled, l.AlertCPUThreshold,
+		&opts.AlertCPUEnabled, &opts.AlertCPUThreshold)
+	alert("disk_io", a.DiskIO, l.AlertDiskIOEnabled, l.AlertDiskIOThreshold,
+		&opts.AlertDiskIOEnabled, &opts.AlertDiskIOThreshold)
+	alert("bw_in", a.BWIn, l.AlertBWInEnabled, l.AlertBWInThreshold,
+		&opts.AlertBWInEnabled, &opts.AlertBWInThreshold)
+	alert("bw_out", a.BWOut, l.AlertBWOutEnabled, l.AlertBWOutThreshold,
+		&opts.AlertBWOutEnabled, &opts.AlertBWOutThreshold)
+	alert("bw_quota", a.BWQuota, l.AlertBWQuotaEnabled, l.AlertBWQuotaThreshold,
+		&opts.AlertBWQuotaEnabled, &opts.AlertBWQuotaThreshold)
+
+	return opts, diffs
+}
+
+func planDiskCreate(linode string, ds DiskSpec) FleetChange {
+	diffs := []FieldDiff{
+		{"type", "", ds.Type},
+		{"size", "", strconv.Itoa(ds.Size)},
+	}
+	if ds.DistributionID != 0 {
+		diffs = append(diffs, FieldDiff{"distribution_id", "", strconv.Itoa(ds.DistributionID)})
+	}
+	if ds.ImageID != 0 {
+		diffs = append(diffs, FieldDiff{"image_id", "", strconv.Itoa(ds.ImageID)})
+	}
+
+	return FleetChange{
+		Kind:     ChangeCreate,
+		Resource: "disk",
+		Linode:   linode,
+		Name:     ds.Label,
+		Diffs:    diffs,
+		apply: func(a *fleetApplier) error {
+			return a.createDisk(linode, ds)
+		},
+	}
+}
+
+// planDiskUpdate returns the changes to bring d in line with ds, and any
+// deletion, which must wait until configs stop referencing d.
+func planDiskUpdate(linode string, ds DiskSpec, d LinodeDisk) (changes []FleetChange,
+	deletes []FleetChange) {
+
+	if ds.Type != d.Type {
+		// The type of a disk can't be changed, so replace it.  The new disk
+		// is created alongside the old one, which is deleted last.
+		create := planDiskCreate(linode, ds)
+		create.Destructive = true
+		create.Diffs[0].Old = d.Type
+		return []FleetChange{create}, []FleetChange{planDiskDelete(linode, d)}
+	}
+
+	if ds.Size != d.Size {
+		changes = append(changes, FleetChange{
+			Kind:        ChangeResize,
+			Resource:    "disk",
+			Linode:      linode,
+			Name:        ds.Label,
+			Diffs:       []FieldDiff{intDiff("size", d.Size, ds.Size)},
+			Destructive: ds.Size < d.Size,
+			apply: func(a *fleetApplier) error {
+				job, err := a.c.LinodeDiskResizeJob(d.LinodeID, d.ID, ds.Size)
+				err = a.queued(job, err)
+				if err != nil {
+					return err
+				}
+				return a.wait(job)
+			},
+		})
+	}
+
+	if ds.ReadOnly != d.IsReadOnly {
+		changes = append(changes, FleetChange{
+			Kind:     ChangeUpdate,
+			Resource: "disk",
+			Linode:   linode,
+			Name:     ds.Label,
+			Diffs:    []FieldDiff{boolDiff("read_only", d.IsReadOnly, ds.ReadOnly)},
+			apply: func(a *fleetApplier) error {
+				return a.c.LinodeDiskUpdate(d.LinodeID, d.ID, nil, Bool(ds.ReadOnly))
+			},
+		})
+	}
+
+	return changes, nil
+}
+
+func planDiskDelete(linode string, d LinodeDisk) FleetChange {
+	return FleetChange{
+		Kind:        ChangeDelete,
+		Resource:    "disk",
+		Linode:      linode,
+		Name:        d.Label,
+		Destructive: true,
+		apply: func(a *fleetApplier) error {
+			job, err := a.c.LinodeDiskDeleteJob(d.LinodeID, d.ID)
+			err = a.queued(job, err)
+			if err != nil {
+				return err
+			}
+			// A replacement may already have taken the label.
+			if ids := a.diskIDs[linode]; ids[d.Label] == d.ID {
+				delete(ids, d.Label)
+			}
+			return a.wait(job)
+		},
+	}
+}
+
+func configCreateOpts(cs ConfigSpec) LinodeConfigCreateOpts {
+	opts := LinodeConfigCreateOpts{
+		RootDeviceRO:  cs.RootDeviceRO,
+		HelperDistro:  cs.HelperDistro,
+		HelperNetwork: cs.HelperNetwork,
+	}
+	if cs.Comments != "" {
+		opts.Comments = String(cs.Comments)
+	}
+	if cs.RAMLimit != 0 {
+		opts.RAMLimit = Int(cs.RAMLimit)
+	}
+	if cs.RunLevel != "" {
+		opts.RunLevel = String(cs.RunLevel)
+	}
+	if cs.VirtMode != "" {
+		opts.VirtMode = String(cs.VirtMode)
+	}
+	if cs.RootDeviceNum != 0 {
+		opts.RootDeviceNum = Int(cs.RootDeviceNum)
+	}
+	return opts
+}
+
+func planConfigCreate(linode string, cs ConfigSpec) FleetChange {
+	return FleetChange{
+		Kind:     ChangeCreate,
+		Resource: "config",
+		Linode:   linode,
+		Name:     cs.Label,
+		Diffs: []FieldDiff{
+			{"kernel_id", "", strconv.Itoa(cs.KernelID)},
+			{"disks", "", strings.Join(cs.Disks, ",")},
+		},
+		apply: func(a *fleetApplier) error {
+			diskList, err := a.diskList(linode, cs.Disks)
+			if err != nil {
+				return err
+			}
+			_, err = a.c.LinodeConfigCreate(a.linodeIDs[linode], cs.KernelID, cs.Label, diskList,
+				configCreateOpts(cs))
+			return err
+		},
+	}
+}
+
+func planConfigUpdate(linode string, cs ConfigSpec, lc LinodeConfig,
+	diskLabels map[int]string, replaced map[string]bool) (FleetChange, bool) {
+
+	var opts LinodeConfigUpdateOpts
+	var diffs []FieldDiff
+
+	if cs.KernelID != lc.KernelID {
+		opts.KernelID = Int(cs.KernelID)
+		diffs = append(diffs, intDiff("kernel_id", lc.KernelID, cs.KernelID))
+	}
+
+	// Compare slot by slot, so moving a disk to another device counts.
+	current := strings.Join(diskListLabels(lc.DiskList, diskLabels), ",")
+	wanted := strings.Join(trimDiskLabels(cs.Disks), ",")
+	disksChanged := current != wanted
+	for _, label := range cs.Disks {
+		if replaced[label] {
+			// Same label, but the disk ID will change.
+			disksChanged = true
+		}
+	}
+	if disksChanged {
+		diffs = append(diffs, FieldDiff{"disks", current, wanted})
+	}
+
+	if cs.Comments != lc.Comments {
+		opts.Comments = String(cs.Comments)
+		diffs = append(diffs, FieldDiff{"comments", lc.Comments, cs.Comments})
+	}
+	if cs.RAMLimit != lc.RAMLimit {
+		opts.RAMLimit = Int(cs.RAMLimit)
+		diffs = append(diffs, intDiff("ram_limit", lc.RAMLimit, cs.RAMLimit))
+	}
+	if cs.RunLevel != "" && cs.RunLevel != lc.RunLevel {
+		opts.RunLevel = String(cs.RunLevel)
+		diffs = append(diffs, FieldDiff{"run_level", lc.RunLevel, cs.RunLevel})
+	}
+	if cs.VirtMode != "" && cs.VirtMode != lc.VirtMode {
+		opts.VirtMode = String(cs.VirtMode)
+		diffs = append(diffs, FieldDiff{"virt_mode", lc.VirtMode, cs.VirtMode})
+	}
+	if cs.RootDeviceNum != 0 && cs.RootDeviceNum != lc.RootDeviceNum {
+		opts.RootDeviceNum = Int(cs.RootDeviceNum)
+		diffs = append(diffs, intDiff("root_device_num", lc.RootDeviceNum, cs.RootDeviceNum))
+	}
+	if cs.RootDeviceRO != nil && *cs.RootDeviceRO != lc.RootDeviceRO {
+		opts.RootDeviceRO = cs.RootDeviceRO
+		diffs = append(diffs, boolDiff("root_device_ro", lc.RootDeviceRO, *cs.RootDeviceRO))
+	}
+	if cs.HelperDistro != nil && *cs.HelperDistro != lc.HelperDistro {
+		opts.HelperDistro = cs.HelperDistro
+		diffs = append(diffs, boolDiff("helper_distro", lc.HelperDistro, *cs.HelperDistro))
+	}
+	if cs.HelperNetwork != nil && *cs.HelperNetwork != lc.HelperNetwork {
+		opts.HelperNetwork = cs.HelperNetwork
+		diffs = append(diffs, boolDiff("helper_network", lc.HelperNetwork, *cs.HelperNetwork))
+	}
+
+	if len(diffs) == 0 {
+		return FleetChange{}, false
+	}
+
+	return FleetChange{
+		Kind:     ChangeUpdate,
+		Resource: "config",
+		Linode:   linode,
+		Name:     cs.Label,
+		Diffs:    diffs,
+		apply: func(a *fleetApplier) error {
+			opts := opts
+			opts.LinodeID = Int(lc.LinodeID)
+			if disksChanged {
+				diskList, err := a.diskList(linode, cs.Disks)
+				if err != nil {
+					return err
+				}
+				opts.DiskList = String(diskList)
+			}
+			return a.c.LinodeConfigUpdate(lc.ID, opts)
+		},
+	}, true
+}
+
+// trimDiskLabels drops the unused device slots after the last disk.
+func trimDiskLabels(labels []string) []string {
+	for len(labels) > 0 && labels[len(labels)-1] == "" {
+		labels = labels[:len(labels)-1]
+	}
+	return labels
+}
+
+func intDiff(field string, old int, new int) FieldDiff {
+	return FieldDiff{field, strconv.Itoa(old), strconv.Itoa(new)}
+}
+
+func boolDiff(field string, old bool, new bool) FieldDiff {
+	return FieldDiff{field, strconv.FormatBool(old), strconv.FormatBool(new)}
+}
+
+// FleetApplyOpts contains the optional arguments to ApplyFleet().
+type FleetApplyOpts struct {
+	// AllowDestructive must be set to apply a plan with destructive changes.
+	AllowDestructive bool
+
+	// RootPass and RootSSHKey are used for disks created from a
+	// distribution or image.
+	RootPass   string
+	RootSSHKey *string
+
+	// OnJobStoreError, if set, is called when a job was queued but could not
+	// be saved to the client's JobStore.  Applying continues regardless,
+	// since the job runs either way.
+	OnJobStoreError func(err error)
+}
+
+type fleetApplier struct {
+	c         *Client
+	ctx       context.Context
+	opts      FleetApplyOpts
+	linodeIDs map[string]int
+	diskIDs   map[string]map[string]int
+}
+
+// ApplyFleet makes the changes in the plan, in order, waiting for each job to
+// finish.  It stops at the first error, except that failing to save a job to
+// the client's JobStore only calls opts.OnJobStoreError.
+//
+// If the plan is destructive and opts.AllowDestructive is not set, nothing is
+// changed and an error is returned.
+func (c *Client) ApplyFleet(ctx context.Context, plan *FleetPlan, opts FleetApplyOpts) error {
+	if plan.Destructive() && !opts.AllowDestructive {
+		return errors.New("fleet: plan contains destructive changes and AllowDestructive is not set")
+	}
+
+	a := &fleetApplier{
+		c:         c,
+		ctx:       ctx,
+		opts:      opts,
+		linodeIDs: make(map[string]int),
+		diskIDs:   make(map[string]map[string]int),
+	}
+
+	err := a.load()
+	if err != nil {
+		return err
+	}
+
+	for _, fc := range plan.Changes {
+		err = fc.apply(a)
+		if err != nil {
+			return fmt.Errorf("fleet: %s %s %s: %s", fc.Kind, fc.Resource,
+				strings.TrimSuffix(fc.Linode+"/"+fc.Name, "/"), err)
+		}
+	}
+
+	return nil
+}
+
+// load records the IDs of existing Linodes and disks so changes can refer to
+// them by label.
+func (a *fleetApplier) load() error {
+	linodes, err := a.c.LinodeList(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range linodes {
+		a.linodeIDs[l.Label] = l.ID
+	}
+
+	return nil
+}
+
+func (a *fleetApplier) disks(linode string) (map[string]int, error) {
+	if ids, ok := a.diskIDs[linode]; ok {
+		return ids, nil
+	}
+
+	ids := make(map[string]int)
+	a.diskIDs[linode] = ids
+
+	linodeID, ok := a.linodeIDs[linode]
+	if !ok {
+		return nil, fmt.Errorf("no Linode labeled %q", linode)
+	}
+
+	disks, err := a.c.LinodeDiskList(linodeID, nil)
+	if err != nil {
+		return nil, err
+	}
+	uniqueDiskLabels(disks)
+	for _, d := range disks {
+		ids[d.Label] = d.ID
+	}
+
+	return ids, nil
+}
+
+func (a *fleetApplier) diskList(linode string, labels []string) (string, error) {
+	ids, err := a.disks(linode)
+	if err != nil {
+		return "", err
+	}
+
+	var list []string
+	for _, label := range labels {
+		if label == "" {
+			list = append(list, "")
+			continue
+		}
+		id, ok := ids[label]
+		if !ok {
+			return "", fmt.Errorf("no disk labeled %q", label)
+		}
+		list = append(list, strconv.Itoa(id))
+	}
+
+	return strings.Join(list, ","), nil
+}
+
+func (a *fleetApplier) createDisk(linode string, ds DiskSpec) error {
+	ids, err := a.disks(linode)
+	if err != nil {
+		return err
+	}
+	linodeID := a.linodeIDs[linode]
+
+	var job *Job
+	var diskID int
+
+	switch {
+	case ds.DistributionID != 0:
+		job, diskID, err = a.c.LinodeDiskCreateFromDistributionJob(linodeID, ds.DistributionID,
+			ds.Label, ds.Size, a.opts.RootPass, a.opts.RootSSHKey)
+	case ds.ImageID != 0:
+		var rootPass *string
+		if a.opts.RootPass != "" {
+			rootPass = String(a.opts.RootPass)
+		}
+		job, diskID, err = a.c.LinodeDiskCreateFromImageJob(ds.ImageID, linodeID, ds.Label,
+			Int(ds.Size), rootPass, a.opts.RootSSHKey)
+	default:
+		job, diskID, err = a.c.LinodeDiskCreateJob(linodeID, ds.Label, ds.Type, ds.Size)
+	}
+	err = a.queued(job, err)
+	if err != nil {
+		return err
+	}
+
+	ids[ds.Label] = diskID
+
+	err = a.wait(job)
+	if err != nil {
+		return err
+	}
+
+	if ds.ReadOnly {
+		return a.c.LinodeDiskUpdate(linodeID, diskID, nil, Bool(true))
+	}
+
+	return nil
+}
+
+// queued drops the error of a *Job call that queued its job but could not
+// save it to the JobStore, passing it to OnJobStoreError instead.
+func (a *fleetApplier) queued(job *Job, err error) error {
+	if job == nil || err == nil {
+		return err
+	}
+	if a.opts.OnJobStoreError != nil {
+		a.opts.OnJobStoreError(err)
+	}
+	return nil
+}
+
+func (a *fleetApplier) wait(job *Job) error {
+	_, err := a.c.JobWatcher().WaitJob(a.ctx, job)
+	return err
+}
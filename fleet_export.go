@@ -0,0 +1,400 @@
+package linode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExportFleet builds a FleetSpec describing every Linode, domain, and
+// NodeBalancer on the account.  The result is normalized so that exports of
+// the same account are identical: everything is sorted, and Linodes, disks,
+// and NodeBalancer backends are referred to by label rather than ID.
+//
+// Linode labels must be unique for the result to be unambiguous.  Disk and
+// configuration labels are made unique as described for DiskSpec.
+func (c *Client) ExportFleet() (FleetSpec, error) {
+	var spec FleetSpec
+
+	linodes, err := c.LinodeList(nil)
+	if err != nil {
+		return FleetSpec{}, err
+	}
+
+	ips, err := c.LinodeIPList(nil, nil)
+	if err != nil {
+		return FleetSpec{}, err
+	}
+
+	labels := make(map[int]string)
+	seen := make(map[string]bool)
+	for _, l := range linodes {
+		if seen[l.Label] {
+			return FleetSpec{}, fmt.Errorf("fleet: more than one Linode is labeled %q", l.Label)
+		}
+		seen[l.Label] = true
+		labels[l.ID] = l.Label
+	}
+
+	hasPrivate := make(map[int]bool)
+	privateIPs := make(map[string]string)
+	for _, ip := range ips {
+		if !ip.IsPublic {
+			hasPrivate[ip.LinodeID] = true
+			privateIPs[ip.Address] = labels[ip.LinodeID]
+		}
+	}
+
+	for _, l := range linodes {
+		ls, err := c.exportLinode(l)
+		if err != nil {
+			return FleetSpec{}, err
+		}
+		ls.PrivateIP = hasPrivate[l.ID]
+		spec.Linodes = append(spec.Linodes, ls)
+	}
+	sort.Slice(spec.Linodes, func(i, j int) bool {
+		return spec.Linodes[i].Label < spec.Linodes[j].Label
+	})
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return FleetSpec{}, err
+	}
+
+	for _, d := range domains {
+		ds, err := c.exportDomain(d)
+		if err != nil {
+			return FleetSpec{}, err
+		}
+		spec.Domains = append(spec.Domains, ds)
+	}
+	sort.Slice(spec.Domains, func(i, j int) bool {
+		return spec.Domains[i].Domain < spec.Domains[j].Domain
+	})
+
+	nbs, err := c.NodeBalancerList(nil)
+	if err != nil {
+		return FleetSpec{}, err
+	}
+
+	for _, nb := range nbs {
+		nbSpec, err := c.exportNodeBalancer(nb, privateIPs)
+		if err != nil {
+			return FleetSpec{}, err
+		}
+		spec.NodeBalancers = append(spec.NodeBalancers, nbSpec)
+	}
+	sort.Slice(spec.NodeBalancers, func(i, j int) bool {
+		return spec.NodeBalancers[i].Label < spec.NodeBalancers[j].Label
+	})
+
+	return spec, nil
+}
+
+// WriteFleetSpec encodes a FleetSpec as indented JSON, suitable for reading
+// with ReadFleetSpec().
+func WriteFleetSpec(w io.Writer, spec FleetSpec) error {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (c *Client) exportLinode(l Linode) (LinodeSpec, error) {
+	ls := LinodeSpec{
+		Label:           l.Label,
+		DatacenterID:    l.DatacenterID,
+		PlanID:          l.PlanID,
+		DisplayGroup:    l.DisplayGroup,
+		Watchdog:        Bool(l.Watchdog),
+		BackupWindow:    Int(l.BackupWindow),
+		BackupWeeklyDay: Int(l.BackupWeeklyDay),
+		Alerts: &LinodeAlerts{
+			CPU:     &AlertSpec{l.AlertCPUEnabled, l.AlertCPUThreshold},
+			DiskIO:  &AlertSpec{l.AlertDiskIOEnabled, l.AlertDiskIOThreshold},
+			BWIn:    &AlertSpec{l.AlertBWInEnabled, l.AlertBWInThreshold},
+			BWOut:   &AlertSpec{l.AlertBWOutEnabled, l.AlertBWOutThreshold},
+			BWQuota: &AlertSpec{l.AlertBWQuotaEnabled, l.AlertBWQuotaThreshold},
+		},
+	}
+
+	disks, err := c.LinodeDiskList(l.ID, nil)
+	if err != nil {
+		return LinodeSpec{}, err
+	}
+	uniqueDiskLabels(disks)
+
+	diskLabels := make(map[int]string)
+	for _, d := range disks {
+		diskLabels[d.ID] = d.Label
+		ls.Disks = append(ls.Disks, DiskSpec{
+			Label:    d.Label,
+			Type:     d.Type,
+			Size:     d.Size,
+			ReadOnly: d.IsReadOnly,
+		})
+	}
+	sort.Slice(ls.Disks, func(i, j int) bool { return ls.Disks[i].Label < ls.Disks[j].Label })
+
+	configs, err := c.LinodeConfigList(l.ID, nil)
+	if err != nil {
+		return LinodeSpec{}, err
+	}
+	uniqueConfigLabels(configs)
+
+	for _, lc := range configs {
+		ls.Configs = append(ls.Configs, ConfigSpec{
+			Label:         lc.Label,
+			KernelID:      lc.KernelID,
+			Disks:         diskListLabels(lc.DiskList, diskLabels),
+			Comments:      lc.Comments,
+			RAMLimit:      lc.RAMLimit,
+			RunLevel:      lc.RunLevel,
+			VirtMode:      lc.VirtMode,
+			RootDeviceNum: lc.RootDeviceNum,
+			RootDeviceRO:  Bool(lc.RootDeviceRO),
+			HelperDistro:  Bool(lc.HelperDistro),
+			HelperNetwork: Bool(lc.HelperNetwork),
+		})
+	}
+	sort.Slice(ls.Configs, func(i, j int) bool { return ls.Configs[i].Label < ls.Configs[j].Label })
+
+	return ls, nil
+}
+
+// uniqueDiskLabels renames disks so that each has a unique, non-empty label,
+// the way a FleetSpec refers to them.
+func uniqueDiskLabels(disks []LinodeDisk) {
+	ids := make([]int, len(disks))
+	labels := make([]string, len(disks))
+	for i, d := range disks {
+		ids[i], labels[i] = d.ID, d.Label
+	}
+	uniqueLabels(ids, labels, "disk")
+	for i := range disks {
+		disks[i].Label = labels[i]
+	}
+}
+
+// uniqueConfigLabels is uniqueDiskLabels() for configurations.
+func uniqueConfigLabels(configs []LinodeConfig) {
+	ids := make([]int, len(configs))
+	labels := make([]string, len(configs))
+	for i, lc := range configs {
+		ids[i], labels[i] = lc.ID, lc.Label
+	}
+	uniqueLabels(ids, labels, "config")
+	for i := range configs {
+		configs[i].Label = labels[i]
+	}
+}
+
+// uniqueLabels gives empty labels the name "<kind>-<ID>", and every repeat of
+// a label, after the one with the lowest ID, the name "<label>-<ID>".  The
+// result does not depend on the order of the lists.
+func uniqueLabels(ids []int, labels []string, kind string) {
+	order := make([]int, len(ids))
+	used := make(map[string]bool)
+	for i := range ids {
+		order[i] = i
+		used[labels[i]] = true
+	}
+	sort.Slice(order, func(a, b int) bool { return ids[order[a]] < ids[order[b]] })
+
+	claimed := make(map[string]bool)
+	for _, i := range order {
+		if labels[i] != "" && !claimed[labels[i]] {
+			claimed[labels[i]] = true
+			continue
+		}
+
+		base := fmt.Sprintf("%s-%d", labels[i], ids[i])
+		if labels[i] == "" {
+			base = fmt.Sprintf("%s-%d", kind, ids[i])
+		}
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		claimed[name] = true
+		labels[i] = name
+	}
+}
+
+// diskListLabels converts a config's DiskList to disk labels, keeping empty
+// device slots between disks.
+func diskListLabels(diskList string, diskLabels map[int]string) []string {
+	var out []string
+	for _, s := range strings.Split(diskList, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || id == 0 {
+			out = append(out, "")
+			continue
+		}
+		label, ok := diskLabels[id]
+		if !ok {
+			label = strconv.Itoa(id)
+		}
+		out = append(out, label)
+	}
+
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+
+	return out
+}
+
+func (c *Client) exportDomain(d Domain) (DomainSpec, error) {
+	ds := DomainSpec{
+		Domain:       d.Domain,
+		Type:         d.Type,
+		Description:  d.Description,
+		SOAEmail:     d.SOAEmail,
+		RefreshSec:   d.RefreshSec,
+		RetrySec:     d.RetrySec,
+		ExpireSec:    d.ExpireSec,
+		TTLSec:       d.TTLSec,
+		DisplayGroup: d.DisplayGroup,
+		Status:       d.Status,
+		MasterIPs:    splitIPList(d.MasterIPs),
+		AXFRIPs:      splitIPList(d.AXFRIPs),
+	}
+
+	resources, err := c.DomainResourceList(d.ID, nil)
+	if err != nil {
+		return DomainSpec{}, err
+	}
+
+	for _, r := range resources {
+		ds.Records = append(ds.Records, recordSpecFor(r))
+	}
+	sortRecordSpecs(ds.Records)
+
+	return ds, nil
+}
+
+func recordSpecFor(r DomainResource) RecordSpec {
+	return RecordSpec{
+		Type:     strings.ToUpper(r.Type),
+		Name:     r.Name,
+		Target:   r.Target,
+		TTLSec:   r.TTLSec,
+		Priority: r.Priority,
+		Weight:   r.Weight,
+		Port:     r.Port,
+		Protocol: r.Protocol,
+	}
+}
+
+func sortRecordSpecs(records []RecordSpec) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.Port < b.Port
+	})
+}
+
+// splitIPList splits the IP lists used by domains, which may be separated by
+// semicolons, commas, or whitespace.
+func splitIPList(s string) []string {
+	ips := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	if len(ips) == 0 {
+		return nil
+	}
+	return ips
+}
+
+func (c *Client) exportNodeBalancer(nb NodeBalancer, privateIPs map[string]string) (NodeBalancerSpec, error) {
+	nbs := NodeBalancerSpec{
+		Label:        nb.Label,
+		DatacenterID: nb.DatacenterID,
+		Throttle:     nb.Throttle,
+	}
+
+	configs, err := c.NodeBalancerConfigList(nb.ID, nil)
+	if err != nil {
+		return NodeBalancerSpec{}, err
+	}
+
+	for _, conf := range configs {
+		cs := NodeBalancerConfigSpec{
+			Port:          conf.Port,
+			Protocol:      conf.Protocol,
+			Algorithm:     conf.Algorithm,
+			Stickiness:    conf.Stickiness,
+			Check:         conf.Check,
+			CheckInterval: conf.CheckInterval,
+			CheckTimeout:  conf.CheckTimeout,
+			CheckAttempts: conf.CheckAttempts,
+			CheckPath:     conf.CheckPath,
+			CheckBody:     conf.CheckBody,
+			CheckPassive:  conf.CheckPassive,
+		}
+
+		nodes, err := c.NodeBalancerNodeList(conf.ID, nil)
+		if err != nil {
+			return NodeBalancerSpec{}, err
+		}
+
+		for _, n := range nodes {
+			cs.Nodes = append(cs.Nodes, nodeSpecFor(n, privateIPs))
+		}
+		sort.Slice(cs.Nodes, func(i, j int) bool { return cs.Nodes[i].Label < cs.Nodes[j].Label })
+
+		nbs.Configs = append(nbs.Configs, cs)
+	}
+	sort.Slice(nbs.Configs, func(i, j int) bool { return nbs.Configs[i].Port < nbs.Configs[j].Port })
+
+	return nbs, nil
+}
+
+func nodeSpecFor(n NodeBalancerNode, privateIPs map[string]string) NodeBalancerNodeSpec {
+	ns := NodeBalancerNodeSpec{
+		Label:   n.Label,
+		Address: n.Address,
+		Weight:  n.Weight,
+		Mode:    n.Mode,
+	}
+
+	host, port, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return ns
+	}
+	label, ok := privateIPs[host]
+	if !ok {
+		return ns
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return ns
+	}
+
+	ns.Address = ""
+	ns.Linode = label
+	ns.Port = p
+
+	return ns
+}
@@ -0,0 +1,295 @@
+// +build !integration
+
+package linode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockExportFleet() []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("linode.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":2,"LABEL":"web2","DATACENTERID":2,"PLANID":1,"WATCHDOG":1,"BACKUPWINDOW":1,"BACKUPWEEKLYDAY":0,"ALERT_CPU_ENABLED":1,"ALERT_CPU_THRESHOLD":90},{"LINODEID":1,"LABEL":"web1","DATACENTERID":2,"PLANID":1,"LPM_DISPLAYGROUP":"web"}],"ACTION":"linode.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.ip.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1,"ISPUBLIC":1,"IPADDRESS":"45.33.5.10","IPADDRESSID":5},{"LINODEID":1,"ISPUBLIC":0,"IPADDRESS":"192.168.133.10","IPADDRESSID":6}],"ACTION":"linode.ip.list"}`))
+
+	params = map[string]string{"LinodeID": "2"}
+	responses = append(responses, newMockAPIResponse("linode.disk.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"linode.disk.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.config.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"linode.config.list"}`))
+
+	params = map[string]string{"LinodeID": "1"}
+	responses = append(responses, newMockAPIResponse("linode.disk.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DISKID":11,"LINODEID":1,"LABEL":"swap","TYPE":"swap","SIZE":256},{"DISKID":10,"LINODEID":1,"LABEL":"root","TYPE":"ext4","SIZE":24320}],"ACTION":"linode.disk.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.config.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"ConfigID":20,"LinodeID":1,"label":"default","KernelID":138,"DiskList":"10,,11,,,,,,","RunLevel":"default","virt_mode":"paravirt","helper_distro":1,"helper_network":1}],"ACTION":"linode.config.list"}`))
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","SOA_EMAIL":"admin@example.com","STATUS":1,"TTL_SEC":0,"MASTER_IPS":"","AXFR_IPS":"1.2.3.4;5.6.7.8"}],"ACTION":"domain.list"}`))
+
+	params = map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":41,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"45.33.5.10","TTL_SEC":300},{"RESOURCEID":40,"DOMAINID":30,"TYPE":"mx","NAME":"","TARGET":"mail.example.com","PRIORITY":10}],"ACTION":"domain.resource.list"}`))
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":50,"LABEL":"lb","DATACENTERID":2,"CLIENTCONNTHROTTLE":5}],"ACTION":"nodebalancer.list"}`))
+
+	params = map[string]string{"NodeBalancerID": "50"}
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":60,"NODEBALANCERID":50,"PORT":80,"PROTOCOL":"http","ALGORITHM":"roundrobin","STICKINESS":"none","CHECK":"connection"}],"ACTION":"nodebalancer.config.list"}`))
+
+	params = map[string]string{"ConfigID": "60"}
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"NODEID":70,"CONFIGID":60,"LABEL":"web1","ADDRESS":"192.168.133.10:80","WEIGHT":100,"MODE":"accept"},{"NODEID":71,"CONFIGID":60,"LABEL":"ext","ADDRESS":"192.168.200.1:80","WEIGHT":50,"MODE":"drain"}],"ACTION":"nodebalancer.node.list"}`))
+
+	return responses
+}
+
+const expectedFleetExport = `{
+  "linodes": [
+    {
+      "label": "web1",
+      "datacenter_id": 2,
+      "plan_id": 1,
+      "display_group": "web",
+      "watchdog": false,
+      "backup_window": 0,
+      "backup_weekly_day": 0,
+      "alerts": {
+        "cpu": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "disk_io": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_in": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_out": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_quota": {
+          "enabled": false,
+          "threshold": 0
+        }
+      },
+      "private_ip": true,
+      "disks": [
+        {
+          "label": "root",
+          "type": "ext4",
+          "size": 24320
+        },
+        {
+          "label": "swap",
+          "type": "swap",
+          "size": 256
+        }
+      ],
+      "configs": [
+        {
+          "label": "default",
+          "kernel_id": 138,
+          "disks": [
+            "root",
+            "",
+            "swap"
+          ],
+          "run_level": "default",
+          "virt_mode": "paravirt",
+          "root_device_ro": false,
+          "helper_distro": true,
+          "helper_network": true
+        }
+      ]
+    },
+    {
+      "label": "web2",
+      "datacenter_id": 2,
+      "plan_id": 1,
+      "watchdog": true,
+      "backup_window": 1,
+      "backup_weekly_day": 0,
+      "alerts": {
+        "cpu": {
+          "enabled": true,
+          "threshold": 90
+        },
+        "disk_io": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_in": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_out": {
+          "enabled": false,
+          "threshold": 0
+        },
+        "bw_quota": {
+          "enabled": false,
+          "threshold": 0
+        }
+      }
+    }
+  ],
+  "domains": [
+    {
+      "domain": "example.com",
+      "type": "master",
+      "soa_email": "admin@example.com",
+      "status": 1,
+      "axfr_ips": [
+        "1.2.3.4",
+        "5.6.7.8"
+      ],
+      "records": [
+        {
+          "type": "MX",
+          "target": "mail.example.com",
+          "priority": 10
+        },
+        {
+          "type": "A",
+          "name": "www",
+          "target": "45.33.5.10",
+          "ttl_sec": 300
+        }
+      ]
+    }
+  ],
+  "nodebalancers": [
+    {
+      "label": "lb",
+      "datacenter_id": 2,
+      "throttle": 5,
+      "configs": [
+        {
+          "port": 80,
+          "protocol": "http",
+          "algorithm": "roundrobin",
+          "stickiness": "none",
+          "check": "connection",
+          "nodes": [
+            {
+              "label": "ext",
+              "address": "192.168.200.1:80",
+              "weight": 50,
+              "mode": "drain"
+            },
+            {
+              "label": "web1",
+              "linode": "web1",
+              "port": 80,
+              "weight": 100,
+              "mode": "accept"
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+func TestExportFleet(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockExportFleet()))
+	defer ts.Close()
+
+	spec, err := c.ExportFleet()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFleetSpec(&buf, spec))
+	assert.Equal(t, expectedFleetExport, buf.String())
+	assert.NoError(t, validateFleetSpec(spec))
+
+	// Round trip.
+	read, err := ReadFleetSpec(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, spec, read)
+}
+
+func TestExportFleetLabels(t *testing.T) {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("linode.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1,"LABEL":"web1","DATACENTERID":2,"PLANID":1}],"ACTION":"linode.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.ip.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"linode.ip.list"}`))
+
+	params = map[string]string{"LinodeID": "1"}
+	responses = append(responses, newMockAPIResponse("linode.disk.list", params,
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"DISKID":12,"LINODEID":1,"LABEL":"data","TYPE":"ext4","SIZE":100},`+
+			`{"DISKID":11,"LINODEID":1,"LABEL":"","TYPE":"swap","SIZE":256},`+
+			`{"DISKID":10,"LINODEID":1,"LABEL":"data","TYPE":"ext4","SIZE":200},`+
+			`{"DISKID":13,"LINODEID":1,"LABEL":"disk-11","TYPE":"ext4","SIZE":300}`+
+			`],"ACTION":"linode.disk.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.config.list", params,
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"ConfigID":21,"LinodeID":1,"label":"boot","KernelID":138,"DiskList":"12,11"},`+
+			`{"ConfigID":20,"LinodeID":1,"label":"boot","KernelID":138,"DiskList":"10,13"},`+
+			`{"ConfigID":22,"LinodeID":1,"label":"","KernelID":138,"DiskList":"10"}`+
+			`],"ACTION":"linode.config.list"}`))
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"nodebalancer.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	spec, err := c.ExportFleet()
+	require.NoError(t, err)
+	require.NoError(t, validateFleetSpec(spec))
+
+	ls := spec.Linodes[0]
+	var disks []string
+	for _, d := range ls.Disks {
+		disks = append(disks, d.Label)
+	}
+	assert.Equal(t, []string{"data", "data-12", "disk-11", "disk-11-2"}, disks)
+
+	var configs [][]string
+	for _, cs := range ls.Configs {
+		configs = append(configs, append([]string{cs.Label}, cs.Disks...))
+	}
+	assert.Equal(t, [][]string{
+		{"boot", "data", "disk-11"},
+		{"boot-21", "data-12", "disk-11-2"},
+		{"config-22", "data"},
+	}, configs)
+}
+
+func TestExportFleetError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.ExportFleet()
+	require.Error(t, err)
+}
+
+func TestSplitIPList(t *testing.T) {
+	assert.Equal(t, []string{"1.2.3.4", "5.6.7.8", "::1"}, splitIPList("1.2.3.4; 5.6.7.8,::1"))
+	assert.Len(t, splitIPList(""), 0)
+}
@@ -0,0 +1,278 @@
+// +build !integration
+
+package linode
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+const testFleetSpec = `{
+  "linodes": [
+    {
+      "label": "web1",
+      "datacenter_id": 2,
+      "plan_id": 2,
+      "display_group": "web",
+      "alerts": {"cpu": {"enabled": true, "threshold": 90}},
+      "disks": [
+        {"label": "root", "type": "ext4", "size": 24000},
+        {"label": "swap", "type": "swap", "size": 512}
+      ],
+      "configs": [
+        {"label": "default", "kernel_id": 138, "disks": ["root", "swap"]}
+      ]
+    },
+    {
+      "label": "web2",
+      "datacenter_id": 2,
+      "plan_id": 1,
+      "disks": [{"label": "root", "type": "ext4", "size": 24576, "distribution_id": 130}],
+      "configs": [{"label": "default", "kernel_id": 138, "disks": ["root"]}]
+    }
+  ]
+}`
+
+func mockFleetExisting() []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("linode.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1,"LABEL":"web1","DATACENTERID":2,"PLANID":1,"LPM_DISPLAYGROUP":"","ALERT_CPU_ENABLED":1,"ALERT_CPU_THRESHOLD":90},{"LINODEID":3,"LABEL":"old1","DATACENTERID":2,"PLANID":1}],"ACTION":"linode.list"}`))
+
+	params = map[string]string{"LinodeID": "1"}
+	responses = append(responses, newMockAPIResponse("linode.disk.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DISKID":10,"LINODEID":1,"LABEL":"root","TYPE":"ext4","SIZE":24000,"ISREADONLY":0},{"DISKID":11,"LINODEID":1,"LABEL":"tmp","TYPE":"ext4","SIZE":256,"ISREADONLY":0}],"ACTION":"linode.disk.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.config.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"ConfigID":20,"LinodeID":1,"label":"default","KernelID":138,"DiskList":"10,11,,,,,,,","RAMLimit":0,"RootDeviceRO":true,"helper_distro":1}],"ACTION":"linode.config.list"}`))
+
+	return responses
+}
+
+func TestPlanFleet(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts.Close()
+
+	spec, err := ReadFleetSpec(bytes.NewBufferString(testFleetSpec))
+	require.NoError(t, err)
+
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{Prune: true})
+	require.NoError(t, err)
+	assert.True(t, plan.Destructive())
+
+	expected := `~ linode web1
+    display_group: "" -> "web"
+~ linode web1 (destructive)
+    plan_id: "1" -> "2"
++ disk web1/swap
+    type: "" -> "swap"
+    size: "" -> "512"
+~ config web1/default
+    disks: "root,tmp" -> "root,swap"
++ linode web2
+    datacenter_id: "" -> "2"
+    plan_id: "" -> "1"
++ disk web2/root
+    type: "" -> "ext4"
+    size: "" -> "24576"
+    distribution_id: "" -> "130"
++ config web2/default
+    kernel_id: "" -> "138"
+    disks: "" -> "root"
+- disk web1/tmp (destructive)
+- linode old1 (destructive)
+`
+	assert.Equal(t, expected, plan.String())
+
+	err = c.ApplyFleet(context.Background(), plan, FleetApplyOpts{})
+	require.Error(t, err)
+}
+
+func mockFleetApply() []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockFleetExisting()...)
+
+	// ApplyFleet
+	responses = append(responses, mockFleetExisting()[0])
+
+	params = map[string]string{"LinodeID": "1", "lpm_displayGroup": "web"}
+	responses = append(responses, newMockAPIResponse("linode.update", params,
+		`{"ERRORARRAY":[],"DATA":{"LinodeID":1},"ACTION":"linode.update"}`))
+
+	responses = append(responses, mockFleetExisting()[1])
+
+	params = map[string]string{"LinodeID": "1", "Label": "swap", "Type": "swap", "Size": "512"}
+	responses = append(responses, newMockAPIResponse("linode.disk.create", params,
+		`{"ERRORARRAY":[],"DATA":{"JobID":100,"DiskID":12},"ACTION":"linode.disk.create"}`))
+
+	params = map[string]string{"LinodeID": "1", "pendingOnly": "1"}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"linode.job.list"}`))
+	params = map[string]string{"LinodeID": "1"}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"JOBID":100,"LINODEID":1,"HOST_FINISH_DT":"2015-07-08 20:16:52.0","HOST_SUCCESS":1}],"ACTION":"linode.job.list"}`))
+
+	params = map[string]string{"ConfigID": "20", "LinodeID": "1", "DiskList": "10,12"}
+	responses = append(responses, newMockAPIResponse("linode.config.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":20},"ACTION":"linode.config.update"}`))
+
+	return responses
+}
+
+func TestApplyFleet(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetApply()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+
+	spec, err := ReadFleetSpec(bytes.NewBufferString(testFleetSpec))
+	require.NoError(t, err)
+
+	// Only manage web1, and keep its plan and the tmp disk.
+	spec.Linodes = spec.Linodes[:1]
+	spec.Linodes[0].PlanID = 1
+	spec.Linodes[0].Disks = append(spec.Linodes[0].Disks, DiskSpec{Label: "tmp", Type: "ext4", Size: 256})
+
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 3)
+	assert.False(t, plan.Destructive())
+
+	err = c.ApplyFleet(context.Background(), plan, FleetApplyOpts{})
+	require.NoError(t, err)
+}
+
+func TestApplyFleetJobStoreError(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetApply()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+	c.JobStore = failingJobStore{}
+
+	spec, err := ReadFleetSpec(bytes.NewBufferString(testFleetSpec))
+	require.NoError(t, err)
+	spec.Linodes = spec.Linodes[:1]
+	spec.Linodes[0].PlanID = 1
+	spec.Linodes[0].Disks = append(spec.Linodes[0].Disks, DiskSpec{Label: "tmp", Type: "ext4", Size: 256})
+
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+
+	// The swap disk is still created and used by the config.
+	var storeErrs []error
+	err = c.ApplyFleet(context.Background(), plan, FleetApplyOpts{
+		OnJobStoreError: func(err error) { storeErrs = append(storeErrs, err) },
+	})
+	require.NoError(t, err)
+	require.Len(t, storeErrs, 1)
+	assert.Equal(t, "disk full", storeErrs[0].Error())
+}
+
+func TestPlanFleetInvalid(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	bad := []FleetSpec{
+		{Linodes: []LinodeSpec{{}}},
+		{Linodes: []LinodeSpec{{Label: "a", DatacenterID: 2, PlanID: 1}, {Label: "a", DatacenterID: 2, PlanID: 1}}},
+		{Linodes: []LinodeSpec{{Label: "a", DatacenterID: 2}}},
+		{Linodes: []LinodeSpec{{Label: "a", PlanID: 1}}},
+		{Linodes: []LinodeSpec{{Label: "a", DatacenterID: 2, PlanID: 1, Disks: []DiskSpec{{Label: "d"}, {Label: "d"}}}}},
+		{Linodes: []LinodeSpec{{Label: "a", DatacenterID: 2, PlanID: 1, Configs: []ConfigSpec{{Label: "c", Disks: []string{"x"}}}}}},
+	}
+	for i, spec := range bad {
+		_, err := c.PlanFleet(spec, FleetPlanOpts{})
+		require.Error(t, err, "%d", i)
+		assert.NotEqual(t, "bar", err.Error(), "%d", i)
+	}
+
+	_, err := ReadFleetSpec(bytes.NewBufferString(`{"linodes": [{"lable": "typo"}]}`))
+	assert.Error(t, err)
+}
+
+func TestPlanFleetDatacenterChange(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts.Close()
+
+	spec := FleetSpec{Linodes: []LinodeSpec{{Label: "web1", DatacenterID: 3, PlanID: 1}}}
+	_, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.Error(t, err)
+}
+
+func TestPlanFleetUnmanagedDisks(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts.Close()
+
+	// Neither disks nor configs are given, so the existing ones are kept.
+	spec := FleetSpec{Linodes: []LinodeSpec{{Label: "web1", DatacenterID: 2, PlanID: 1}}}
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+	assert.False(t, plan.Destructive(), plan.String())
+	assert.NotContains(t, plan.String(), "- ")
+}
+
+func TestPlanFleetReplaceDisk(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts.Close()
+
+	spec := FleetSpec{Linodes: []LinodeSpec{{
+		Label:        "web1",
+		DatacenterID: 2,
+		PlanID:       1,
+		Disks: []DiskSpec{
+			{Label: "root", Type: "ext3", Size: 24000},
+			{Label: "tmp", Type: "ext4", Size: 256},
+		},
+		Configs: []ConfigSpec{{Label: "default", KernelID: 138, Disks: []string{"root", "tmp"}}},
+	}}}
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+
+	// The old disk is only deleted after the config points at the new one.
+	expected := `+ disk web1/root (destructive)
+    type: "ext4" -> "ext3"
+    size: "" -> "24000"
+~ config web1/default
+    disks: "root,tmp" -> "root,tmp"
+- disk web1/root (destructive)
+`
+	assert.Equal(t, expected, plan.String())
+}
+
+func TestPlanFleetMoveDisk(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts.Close()
+
+	// tmp moves from sdb to sdc.
+	spec := FleetSpec{Linodes: []LinodeSpec{{
+		Label:        "web1",
+		DatacenterID: 2,
+		PlanID:       1,
+		Disks: []DiskSpec{
+			{Label: "root", Type: "ext4", Size: 24000},
+			{Label: "tmp", Type: "ext4", Size: 256},
+		},
+		Configs: []ConfigSpec{{Label: "default", KernelID: 138, Disks: []string{"root", "", "tmp", ""}}},
+	}}}
+	plan, err := c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+
+	expected := `~ config web1/default
+    disks: "root,tmp" -> "root,,tmp"
+`
+	assert.Equal(t, expected, plan.String())
+
+	// The same slots, written with trailing empty ones, are unchanged.
+	c, ts2 := clientFor(newMockAPIServer(t, mockFleetExisting()))
+	defer ts2.Close()
+	spec.Linodes[0].Configs[0].Disks = []string{"root", "tmp", ""}
+	plan, err = c.PlanFleet(spec, FleetPlanOpts{})
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
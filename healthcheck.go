@@ -0,0 +1,62 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// HealthCheck checks whether the service at ip is healthy, returning nil if
+// it is.  It should give up when ctx is done.
+type HealthCheck func(ctx context.Context, ip net.IP) error
+
+// TCPHealthCheck returns a HealthCheck that succeeds if a TCP connection to
+// port can be opened.
+func TCPHealthCheck(port int) HealthCheck {
+	return func(ctx context.Context, ip net.IP) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPHealthCheck returns a HealthCheck that sends a GET for path to port,
+// and succeeds on a 2xx or 3xx response.  Redirects are not followed.  If
+// host is set, it is sent as the Host header.
+func HTTPHealthCheck(port int, path string, host string) HealthCheck {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return func(ctx context.Context, ip net.IP) error {
+		url := "http://" + net.JoinHostPort(ip.String(), strconv.Itoa(port)) + path
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return err
+		}
+		if host != "" {
+			req.Host = host
+		}
+
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return fmt.Errorf("health check: %s returned %s", url, resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,61 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func listenerPort(t *testing.T, addr net.Addr) int {
+	_, port, err := net.SplitHostPort(addr.String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return p
+}
+
+func TestTCPHealthCheck(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listenerPort(t, l.Addr())
+
+	ip := net.ParseIP("127.0.0.1")
+	assert.NoError(t, TCPHealthCheck(port)(context.Background(), ip))
+
+	l.Close()
+	assert.Error(t, TCPHealthCheck(port)(context.Background(), ip))
+}
+
+func TestHTTPHealthCheck(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "app.example.com" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/health":
+		case "/moved":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	port := listenerPort(t, ts.Listener.Addr())
+	ip := net.ParseIP("127.0.0.1")
+	ctx := context.Background()
+
+	assert.NoError(t, HTTPHealthCheck(port, "/health", "app.example.com")(ctx, ip))
+	assert.NoError(t, HTTPHealthCheck(port, "/moved", "app.example.com")(ctx, ip))
+	assert.Error(t, HTTPHealthCheck(port, "/down", "app.example.com")(ctx, ip))
+	assert.Error(t, HTTPHealthCheck(port, "/health", "")(ctx, ip))
+}
@@ -0,0 +1,269 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultJobInterval is how often Job.Wait() checks on a job when the Job's
+// Interval is not set.
+const DefaultJobInterval = 5 * time.Second
+
+// JobError is returned when a job finishes unsuccessfully.  The failed job,
+// including HostMessage, is available in Job.
+type JobError struct {
+	Job LinodeJob
+}
+
+func (e *JobError) Error() string {
+	if e.Job.HostMessage == "" {
+		return fmt.Sprintf("job %d (%s) failed", e.Job.ID, e.Job.Action)
+	}
+	return fmt.Sprintf("job %d (%s) failed: %s", e.Job.ID, e.Job.Action, e.Job.HostMessage)
+}
+
+// Job is a handle to a job queued against a Linode.  It should be created by
+// a call to NewJob() or one of the *Job variants of the job-producing calls,
+// such as LinodeBootJob().
+//
+// If the client has a JobStore, the *Job variants save the job to it.  If the
+// job is queued but cannot be saved, both the Job and the error are returned.
+type Job struct {
+	LinodeID int
+	ID       int
+
+	// Action is the API call that queued the job, e.g. 'linode.boot'.  It is
+	// empty for handles created with NewJob().
+	Action string
+
+	// Interval is how often Wait() checks on the job.  If zero,
+	// DefaultJobInterval is used.
+	Interval time.Duration
+
+	c      *Client
+	mu     sync.Mutex
+	status LinodeJob
+}
+
+// NewJob returns a handle to an existing job.
+func (c *Client) NewJob(linodeID int, jobID int) *Job {
+	return &Job{
+		LinodeID: linodeID,
+		ID:       jobID,
+		c:        c,
+	}
+}
+
+func (c *Client) newJob(linodeID int, jobID int, action string) *Job {
+	j := c.NewJob(linodeID, jobID)
+	j.Action = action
+	return j
+}
+
+// queuedJob returns a handle for a freshly queued job, saving it to the
+// client's JobStore if one is set.  The handle is returned even if saving
+// fails, since the job is running regardless.
+func (c *Client) queuedJob(linodeID int, jobID int, action string) (*Job, error) {
+	j := c.newJob(linodeID, jobID, action)
+	if c.JobStore == nil {
+		return j, nil
+	}
+	return j, c.TrackJob(j)
+}
+
+// Poll fetches the current state of the job from the API.
+func (j *Job) Poll() (LinodeJob, error) {
+	jobs, err := j.c.LinodeJobList(j.LinodeID, Int(j.ID), nil)
+	if err != nil {
+		return LinodeJob{}, err
+	}
+	if len(jobs) != 1 {
+		return LinodeJob{}, fmt.Errorf("job id %d not found", j.ID)
+	}
+
+	j.setStatus(jobs[0])
+	j.c.untrackJob(jobs[0])
+
+	return jobs[0], nil
+}
+
+// Status returns the state of the job as of the last poll.  It is zeroed if
+// the job has never been polled.
+func (j *Job) Status() LinodeJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// Done returns true if the job was finished as of the last poll.
+func (j *Job) Done() bool {
+	return j.Status().Done()
+}
+
+// Wait polls the job until it finishes or the context is done, and returns
+// the final state of the job.
+//
+// If the job finishes unsuccessfully, the returned error is a *JobError.
+func (j *Job) Wait(ctx context.Context) (LinodeJob, error) {
+	interval := j.Interval
+	if interval <= 0 {
+		interval = DefaultJobInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			lj, err := j.Poll()
+			if err != nil {
+				return LinodeJob{}, err
+			}
+			if lj.Done() {
+				return lj, jobResult(lj)
+			}
+		case <-ctx.Done():
+			return j.Status(), ctx.Err()
+		}
+	}
+}
+
+func (j *Job) setStatus(lj LinodeJob) {
+	j.mu.Lock()
+	j.status = lj
+	j.mu.Unlock()
+}
+
+func jobResult(lj LinodeJob) error {
+	if !lj.Success() {
+		return &JobError{Job: lj}
+	}
+	return nil
+}
+
+// LinodeBootJob is LinodeBoot(), returning a Job handle.
+func (c *Client) LinodeBootJob(linodeID int, configID *int) (*Job, error) {
+	jobID, err := c.LinodeBoot(linodeID, configID)
+	if err != nil {
+		return nil, err
+	}
+	return c.queuedJob(linodeID, jobID, "linode.boot")
+}
+
+// LinodeRebootJob is LinodeReboot(), returning a Job handle.
+func (c *Client) LinodeRebootJob(linodeID int, configID *int) (*Job, error) {
+	jobID, err := c.LinodeReboot(linodeID, configID)
+	if err != nil {
+		return nil, err
+	}
+	return c.queuedJob(linodeID, jobID, "linode.reboot")
+}
+
+// LinodeShutdownJob is LinodeShutdown(), returning a Job handle.
+func (c *Client) LinodeShutdownJob(linodeID int) (*Job, error) {
+	jobID, err := c.LinodeShutdown(linodeID)
+	if err != nil {
+		return nil, err
+	}
+	return c.queuedJob(linodeID, jobID, "linode.shutdown")
+}
+
+// LinodeDiskCreateJob is LinodeDiskCreate(), returning a Job handle.
+func (c *Client) LinodeDiskCreateJob(linodeID int, label string, dType string,
+	size int) (job *Job, diskID int, err error) {
+
+	jobID, diskID, err := c.LinodeDiskCreate(linodeID, label, dType, size)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.create")
+	return j, diskID, err
+}
+
+// LinodeDiskCreateFromDistributionJob is LinodeDiskCreateFromDistribution(),
+// returning a Job handle.
+func (c *Client) LinodeDiskCreateFromDistributionJob(linodeID int, distID int, label string,
+	size int, rootPass string, rootSSHKey *string) (job *Job, diskID int, err error) {
+
+	jobID, diskID, err := c.LinodeDiskCreateFromDistribution(linodeID, distID, label, size,
+		rootPass, rootSSHKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.createfromdistribution")
+	return j, diskID, err
+}
+
+// LinodeDiskCreateFromImageJob is LinodeDiskCreateFromImage(), returning a
+// Job handle.
+func (c *Client) LinodeDiskCreateFromImageJob(imageID int, linodeID int, label string, size *int,
+	rootPass *string, rootSSHKey *string) (job *Job, diskID int, err error) {
+
+	jobID, diskID, err := c.LinodeDiskCreateFromImage(imageID, linodeID, label, size,
+		rootPass, rootSSHKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.createfromimage")
+	return j, diskID, err
+}
+
+// LinodeDiskCreateFromStackScriptJob is LinodeDiskCreateFromStackScript(),
+// returning a Job handle.
+func (c *Client) LinodeDiskCreateFromStackScriptJob(linodeID int, ssID int, ssUDFResp string,
+	distID int, label string, size int, rootPass string,
+	rootSSHKey *string) (job *Job, diskID int, err error) {
+
+	jobID, diskID, err := c.LinodeDiskCreateFromStackScript(linodeID, ssID, ssUDFResp, distID,
+		label, size, rootPass, rootSSHKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.createfromstackscript")
+	return j, diskID, err
+}
+
+// LinodeDiskDeleteJob is LinodeDiskDelete(), returning a Job handle.
+func (c *Client) LinodeDiskDeleteJob(linodeID int, diskID int) (*Job, error) {
+	jobID, err := c.LinodeDiskDelete(linodeID, diskID)
+	if err != nil {
+		return nil, err
+	}
+	return c.queuedJob(linodeID, jobID, "linode.disk.delete")
+}
+
+// LinodeDiskDuplicateJob is LinodeDiskDuplicate(), returning a Job handle.
+func (c *Client) LinodeDiskDuplicateJob(linodeID int, diskID int) (job *Job, nDiskID int,
+	err error) {
+
+	jobID, nDiskID, err := c.LinodeDiskDuplicate(linodeID, diskID)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.duplicate")
+	return j, nDiskID, err
+}
+
+// LinodeDiskImagizeJob is LinodeDiskImagize(), returning a Job handle.
+func (c *Client) LinodeDiskImagizeJob(linodeID int, diskID int, description *string,
+	label *string) (job *Job, imageID int, err error) {
+
+	jobID, imageID, err := c.LinodeDiskImagize(linodeID, diskID, description, label)
+	if err != nil {
+		return nil, 0, err
+	}
+	j, err := c.queuedJob(linodeID, jobID, "linode.disk.imagize")
+	return j, imageID, err
+}
+
+// LinodeDiskResizeJob is LinodeDiskResize(), returning a Job handle.
+func (c *Client) LinodeDiskResizeJob(linodeID int, diskID int, size int) (*Job, error) {
+	jobID, err := c.LinodeDiskResize(linodeID, diskID, size)
+	if err != nil {
+		return nil, err
+	}
+	return c.queuedJob(linodeID, jobID, "linode.disk.resize")
+}
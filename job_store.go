@@ -0,0 +1,245 @@
+package linode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TrackedJob is an in-flight job as persisted by a JobStore.
+type TrackedJob struct {
+	LinodeID int       `json:"linode_id"`
+	JobID    int       `json:"job_id"`
+	Action   string    `json:"action"`
+	Queued   time.Time `json:"queued"`
+}
+
+// JobStore persists in-flight jobs so they can be resumed after a restart.
+// Implementations must be safe for concurrent use.
+type JobStore interface {
+	// Save records a job, replacing any existing record for the same
+	// Linode and job IDs.
+	Save(job TrackedJob) error
+
+	// Delete removes a job.  Deleting a job that isn't stored is not an
+	// error.
+	Delete(linodeID int, jobID int) error
+
+	// List returns all stored jobs.
+	List() ([]TrackedJob, error)
+}
+
+// FileJobStore is a JobStore backed by a JSON file.  It should be created by a
+// call to NewFileJobStore().
+type FileJobStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileJobStore returns a FileJobStore that persists jobs to the passed
+// path.  The file is created on the first save.
+func NewFileJobStore(path string) *FileJobStore {
+	return &FileJobStore{path: path}
+}
+
+// Save implements JobStore.
+func (s *FileJobStore) Save(job TrackedJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	for i, j := range jobs {
+		if j.LinodeID == job.LinodeID && j.JobID == job.JobID {
+			jobs[i] = job
+			return s.write(jobs)
+		}
+	}
+
+	return s.write(append(jobs, job))
+}
+
+// Delete implements JobStore.
+func (s *FileJobStore) Delete(linodeID int, jobID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	for i, j := range jobs {
+		if j.LinodeID == linodeID && j.JobID == jobID {
+			return s.write(append(jobs[:i], jobs[i+1:]...))
+		}
+	}
+
+	return nil
+}
+
+// List implements JobStore.
+func (s *FileJobStore) List() ([]TrackedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.read()
+}
+
+func (s *FileJobStore) read() ([]TrackedJob, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []TrackedJob
+	err = json.Unmarshal(data, &jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (s *FileJobStore) write(jobs []TrackedJob) error {
+	if jobs == nil {
+		jobs = []TrackedJob{}
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it into place so a crash can't
+	// leave a truncated store behind.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// TrackJob saves the passed job to the client's JobStore.  Jobs returned by
+// the *Job variants of the job-producing calls are tracked automatically.
+//
+// Tracked jobs are removed from the store once they are seen to finish by
+// Job.Poll(), Job.Wait(), JobWatcher, or WaitForJob().
+func (c *Client) TrackJob(j *Job) error {
+	if c.JobStore == nil {
+		return errors.New("client has no JobStore")
+	}
+
+	return c.JobStore.Save(TrackedJob{
+		LinodeID: j.LinodeID,
+		JobID:    j.ID,
+		Action:   j.Action,
+		Queued:   time.Now(),
+	})
+}
+
+// untrackJob removes a finished job from the client's JobStore, if any.
+// Failures are ignored: a stale entry resolves immediately when resumed.
+func (c *Client) untrackJob(lj LinodeJob) {
+	if c.JobStore == nil || !lj.Done() {
+		return
+	}
+	_ = c.JobStore.Delete(lj.LinodeID, lj.ID)
+}
+
+// untrackLinode removes every job of a deleted Linode from the client's
+// JobStore, if any, since they can never be resumed.  Failures are ignored.
+func (c *Client) untrackLinode(linodeID int) {
+	if c.JobStore == nil {
+		return
+	}
+	tracked, err := c.JobStore.List()
+	if err != nil {
+		return
+	}
+	for _, t := range tracked {
+		if t.LinodeID == linodeID {
+			_ = c.JobStore.Delete(t.LinodeID, t.JobID)
+		}
+	}
+}
+
+// PendingJobs returns handles for every job in the client's JobStore.
+func (c *Client) PendingJobs() ([]*Job, error) {
+	if c.JobStore == nil {
+		return nil, errors.New("client has no JobStore")
+	}
+
+	tracked, err := c.JobStore.List()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*Job, 0, len(tracked))
+	for _, t := range tracked {
+		jobs = append(jobs, c.newJob(t.LinodeID, t.JobID, t.Action))
+	}
+
+	return jobs, nil
+}
+
+// ResumeJobs reloads the jobs in the client's JobStore and waits on them with
+// the client's shared JobWatcher.  The returned handles hold the final state
+// of each job in Status(); failed jobs are not treated as errors.
+//
+// Error will be non-nil if the store can't be read, there is an API error,
+// or the context is done before every job finishes.
+func (c *Client) ResumeJobs(ctx context.Context) ([]*Job, error) {
+	jobs, err := c.PendingJobs()
+	if err != nil {
+		return nil, err
+	}
+
+	w := c.JobWatcher()
+	errs := make(chan error, len(jobs))
+
+	for _, j := range jobs {
+		go func(j *Job) {
+			_, err := w.WaitJob(ctx, j)
+			if _, ok := err.(*JobError); ok {
+				err = nil
+			}
+			errs <- err
+		}(j)
+	}
+
+	var first error
+	for range jobs {
+		err := <-errs
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return jobs, first
+}
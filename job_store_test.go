@@ -0,0 +1,141 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func tempJobStore(t *testing.T) (*FileJobStore, func()) {
+	dir, err := ioutil.TempDir("", "linode-jobs")
+	require.NoError(t, err)
+
+	return NewFileJobStore(filepath.Join(dir, "jobs.json")), func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestFileJobStore(t *testing.T) {
+	s, cleanup := tempJobStore(t)
+	defer cleanup()
+
+	jobs, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, jobs, 0)
+
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1, JobID: 10, Action: "linode.boot"}))
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1, JobID: 11, Action: "linode.disk.create"}))
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1, JobID: 10, Action: "linode.reboot"}))
+
+	// A second store on the same file sees the same jobs.
+	jobs, err = NewFileJobStore(s.path).List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "linode.reboot", jobs[0].Action)
+	assert.Equal(t, 11, jobs[1].JobID)
+
+	require.NoError(t, s.Delete(1, 10))
+	require.NoError(t, s.Delete(1, 99))
+
+	jobs, err = s.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, 11, jobs[0].JobID)
+}
+
+func TestFileJobStoreCorrupt(t *testing.T) {
+	s, cleanup := tempJobStore(t)
+	defer cleanup()
+
+	require.NoError(t, ioutil.WriteFile(s.path, []byte("{"), 0600))
+
+	_, err := s.List()
+	assert.Error(t, err)
+	assert.Error(t, s.Save(TrackedJob{}))
+	assert.Error(t, s.Delete(0, 0))
+}
+
+func mockJobStoreBootOK() []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockLinodeBootOK()...)
+	responses = append(responses, mockJobBootWaitOK()[2])
+
+	return responses
+}
+
+func TestJobStoreTracking(t *testing.T) {
+	s, cleanup := tempJobStore(t)
+	defer cleanup()
+
+	c, ts := clientFor(newMockAPIServer(t, mockJobStoreBootOK()))
+	defer ts.Close()
+	c.JobStore = s
+
+	job, err := c.LinodeBootJob(1146420, Int(1862370))
+	require.NoError(t, err)
+
+	pending, err := c.PendingJobs()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1146420, pending[0].LinodeID)
+	assert.Equal(t, 25167133, pending[0].ID)
+	assert.Equal(t, "linode.boot", pending[0].Action)
+
+	lj, err := job.Poll()
+	require.NoError(t, err)
+	require.True(t, lj.Done())
+
+	pending, err = c.PendingJobs()
+	require.NoError(t, err)
+	assert.Len(t, pending, 0)
+}
+
+func TestResumeJobs(t *testing.T) {
+	s, cleanup := tempJobStore(t)
+	defer cleanup()
+
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1, JobID: 10, Action: "linode.boot"}))
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1, JobID: 11, Action: "linode.disk.resize"}))
+
+	f := newFakeJobAPI(map[int][]int{1: {10, 11}})
+	f.failed[11] = true
+	close(f.ready)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+	c.JobStore = s
+	c.JobWatcher().MinInterval = 1 * time.Millisecond
+
+	jobs, err := c.ResumeJobs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.True(t, jobs[0].Status().Success())
+	assert.Equal(t, "linode.boot", jobs[0].Action)
+	assert.True(t, jobs[1].Done())
+	assert.False(t, jobs[1].Status().Success())
+
+	left, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, left, 0)
+}
+
+func TestJobStoreMissing(t *testing.T) {
+	c := NewClient("foo")
+
+	assert.Error(t, c.TrackJob(c.NewJob(0, 0)))
+
+	_, err := c.PendingJobs()
+	assert.Error(t, err)
+
+	_, err = c.ResumeJobs(context.Background())
+	assert.Error(t, err)
+}
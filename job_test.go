@@ -0,0 +1,117 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockJobBootWaitOK() []mockAPIResponse {
+	var output string
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockLinodeBootOK()...)
+
+	output = `{"ERRORARRAY":[],"DATA":[{"HOST_START_DT":"","HOST_MESSAGE":"","ENTERED_DT":"2015-07-08 20:16:39.0","HOST_FINISH_DT":"","LABEL":"Lassie initiated boot: My Ubuntu 14.04 LTS Profile","JOBID":25167133,"HOST_SUCCESS":"","ACTION":"linode.boot","LINODEID":1146420,"DURATION":""}],"ACTION":"linode.job.list"}`
+	params = map[string]string{
+		"JobID":      "25167133",
+		"LinodeID":   "1146420",
+		"api_action": "linode.job.list",
+		"api_key":    "foo",
+	}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params, output))
+
+	output = `{"ERRORARRAY":[],"DATA":[{"HOST_START_DT":"2015-07-08 20:16:40.0","HOST_MESSAGE":"","ENTERED_DT":"2015-07-08 20:16:39.0","HOST_FINISH_DT":"2015-07-08 20:16:52.0","LABEL":"Lassie initiated boot: My Ubuntu 14.04 LTS Profile","JOBID":25167133,"HOST_SUCCESS":1,"ACTION":"linode.boot","LINODEID":1146420,"DURATION":12}],"ACTION":"linode.job.list"}`
+	responses = append(responses, newMockAPIResponse("linode.job.list", params, output))
+
+	return responses
+}
+
+func TestJobBootWaitOK(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockJobBootWaitOK()))
+	defer ts.Close()
+
+	job, err := c.LinodeBootJob(1146420, Int(1862370))
+	require.NoError(t, err)
+	assert.Equal(t, 1146420, job.LinodeID)
+	assert.Equal(t, 25167133, job.ID)
+	assert.Equal(t, "linode.boot", job.Action)
+	assert.False(t, job.Done())
+
+	job.Interval = 1 * time.Nanosecond
+
+	lj, err := job.Wait(context.Background())
+	require.NoError(t, err)
+	assert.True(t, lj.Success())
+	assert.Equal(t, 12, lj.Duration)
+	assert.True(t, job.Done())
+	assert.Equal(t, lj, job.Status())
+}
+
+func mockJobPollFailed() []mockAPIResponse {
+	var output string
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	output = `{"ERRORARRAY":[],"DATA":[{"HOST_START_DT":"2015-07-03 23:51:51.0","HOST_MESSAGE":"Not enough free space","ENTERED_DT":"2015-07-03 23:51:41.0","HOST_FINISH_DT":"2015-07-03 23:51:51.0","LABEL":"Resize Disk - test-swap","JOBID":25088076,"HOST_SUCCESS":0,"ACTION":"fs.resize","LINODEID":1139016,"DURATION":1}],"ACTION":"linode.job.list"}`
+	params = map[string]string{
+		"JobID":      "25088076",
+		"LinodeID":   "1139016",
+		"api_action": "linode.job.list",
+		"api_key":    "foo",
+	}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params, output))
+	responses = append(responses, newMockAPIResponse("linode.job.list", params, output))
+
+	return responses
+}
+
+func TestJobFailed(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockJobPollFailed()))
+	defer ts.Close()
+
+	job := c.NewJob(1139016, 25088076)
+
+	lj, err := job.Poll()
+	require.NoError(t, err)
+	assert.True(t, lj.Done())
+	assert.False(t, lj.Success())
+
+	job.Interval = 1 * time.Nanosecond
+
+	lj, err = job.Wait(context.Background())
+	require.Error(t, err)
+	jErr, ok := err.(*JobError)
+	require.True(t, ok)
+	assert.Equal(t, "Not enough free space", jErr.Job.HostMessage)
+	assert.Equal(t, "job 25088076 (fs.resize) failed: Not enough free space", err.Error())
+	assert.Equal(t, lj, jErr.Job)
+}
+
+func TestJobNotFound(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockWaitforJobMultiJobs()))
+	defer ts.Close()
+
+	_, err := c.NewJob(1139016, 25088076).Poll()
+	require.Error(t, err)
+}
+
+func TestJobWaitCanceled(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, []mockAPIResponse{}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	job := c.NewJob(0, 0)
+	job.Interval = 1 * time.Hour
+
+	_, err := job.Wait(ctx)
+	require.Equal(t, context.Canceled, err)
+}
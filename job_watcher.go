@@ -0,0 +1,301 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultJobWatcherMaxInterval is the longest a JobWatcher created by
+// Client.JobWatcher() will back off between checks on a Linode.
+const DefaultJobWatcherMaxInterval = 1 * time.Minute
+
+// DefaultJobWatcherMaxErrors is how many checks in a row may fail before a
+// JobWatcher gives up on a Linode.
+const DefaultJobWatcherMaxErrors = 3
+
+// JobWatcher waits on jobs using a single poller per Linode.  However many
+// jobs are being waited on, each Linode's pending jobs are fetched once per
+// interval and the results are fanned out to every waiter.
+//
+// The interval starts at MinInterval and doubles, up to MaxInterval, for
+// each check in which none of the watched jobs finish.  It drops back to
+// MinInterval when a job finishes or a new waiter is added.
+//
+// A check that fails is retried at the next interval.  Only after MaxErrors
+// checks on a Linode fail in a row is the last error returned to every
+// waiter on it.
+type JobWatcher struct {
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	MaxErrors   int
+
+	c       *Client
+	mu      sync.Mutex
+	linodes map[int]*jobPoller
+}
+
+type jobPoller struct {
+	waiters map[int][]chan jobUpdate
+	wake    chan struct{}
+	errors  int
+}
+
+type jobUpdate struct {
+	job LinodeJob
+	err error
+}
+
+// NewJobWatcher returns a new JobWatcher that polls with the passed bounds.
+// Most callers should use the client's shared watcher, from JobWatcher().
+func (c *Client) NewJobWatcher(minInterval time.Duration, maxInterval time.Duration) *JobWatcher {
+	return &JobWatcher{
+		MinInterval: minInterval,
+		MaxInterval: maxInterval,
+		MaxErrors:   DefaultJobWatcherMaxErrors,
+		c:           c,
+		linodes:     make(map[int]*jobPoller),
+	}
+}
+
+// JobWatcher returns the client's shared JobWatcher, which polls between
+// DefaultJobInterval and DefaultJobWatcherMaxInterval.
+func (c *Client) JobWatcher() *JobWatcher {
+	c.watcherOnce.Do(func() {
+		c.watcher = c.NewJobWatcher(DefaultJobInterval, DefaultJobWatcherMaxInterval)
+	})
+	return c.watcher
+}
+
+// Wait waits for the passed job to finish or the context to be done, and
+// returns the final state of the job.
+//
+// If the job finishes unsuccessfully, the returned error is a *JobError.
+func (w *JobWatcher) Wait(ctx context.Context, linodeID int, jobID int) (LinodeJob, error) {
+	ch := make(chan jobUpdate, 1)
+	w.register(linodeID, jobID, ch)
+
+	select {
+	case u := <-ch:
+		if u.err != nil {
+			return LinodeJob{}, u.err
+		}
+		return u.job, jobResult(u.job)
+	case <-ctx.Done():
+		w.unregister(linodeID, jobID, ch)
+		return LinodeJob{}, ctx.Err()
+	}
+}
+
+// WaitJob is Wait() for a Job handle.  The handle's Status() is updated with
+// the final state of the job.
+func (w *JobWatcher) WaitJob(ctx context.Context, j *Job) (LinodeJob, error) {
+	lj, err := w.Wait(ctx, j.LinodeID, j.ID)
+	if lj.ID != 0 {
+		j.setStatus(lj)
+	}
+	return lj, err
+}
+
+func (w *JobWatcher) register(linodeID int, jobID int, ch chan jobUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.linodes[linodeID]
+	if !ok {
+		p = &jobPoller{
+			waiters: make(map[int][]chan jobUpdate),
+			wake:    make(chan struct{}, 1),
+		}
+		w.linodes[linodeID] = p
+		go w.run(linodeID, p)
+	}
+
+	p.waiters[jobID] = append(p.waiters[jobID], ch)
+
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *JobWatcher) unregister(linodeID int, jobID int, ch chan jobUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.linodes[linodeID]
+	if !ok {
+		return
+	}
+
+	chans := p.waiters[jobID]
+	for i, c := range chans {
+		if c == ch {
+			chans = append(chans[:i], chans[i+1:]...)
+			break
+		}
+	}
+
+	if len(chans) == 0 {
+		delete(p.waiters, jobID)
+	} else {
+		p.waiters[jobID] = chans
+	}
+}
+
+func (w *JobWatcher) run(linodeID int, p *jobPoller) {
+	interval := w.MinInterval
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-p.wake:
+			interval = w.MinInterval
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(interval)
+			continue
+		}
+
+		progress, more := w.check(linodeID, p)
+		if !more {
+			return
+		}
+
+		if progress {
+			interval = w.MinInterval
+		} else {
+			interval = nextJobInterval(interval, w.MaxInterval)
+		}
+		timer.Reset(interval)
+	}
+}
+
+// check polls a single Linode and delivers any finished jobs.  It returns
+// whether any waiters were released, and whether the poller should keep
+// running.
+func (w *JobWatcher) check(linodeID int, p *jobPoller) (progress bool, more bool) {
+	w.mu.Lock()
+	if len(p.waiters) == 0 {
+		delete(w.linodes, linodeID)
+		w.mu.Unlock()
+		return false, false
+	}
+	w.mu.Unlock()
+
+	pending, err := w.c.LinodeJobList(linodeID, nil, Bool(true))
+	if err != nil {
+		return w.failed(p, err)
+	}
+
+	pendingIDs := make(map[int]bool)
+	for _, j := range pending {
+		pendingIDs[j.ID] = true
+	}
+
+	var finished []int
+	w.mu.Lock()
+	for id := range p.waiters {
+		if !pendingIDs[id] {
+			finished = append(finished, id)
+		}
+	}
+	w.mu.Unlock()
+
+	if len(finished) == 0 {
+		p.errors = 0
+		return false, true
+	}
+
+	all, err := w.c.LinodeJobList(linodeID, nil, nil)
+	if err != nil {
+		return w.failed(p, err)
+	}
+
+	byID := make(map[int]LinodeJob)
+	for _, j := range all {
+		byID[j.ID] = j
+	}
+
+	for _, id := range finished {
+		j, ok := byID[id]
+		if !ok {
+			// Not in the Linode's recent history, so ask for it directly.
+			jobs, err := w.c.LinodeJobList(linodeID, Int(id), nil)
+			if err != nil {
+				return w.failed(p, err)
+			}
+			if len(jobs) != 1 {
+				w.deliver(p, id, jobUpdate{err: fmt.Errorf("job id %d not found", id)})
+				continue
+			}
+			j = jobs[0]
+		}
+
+		if !j.Done() {
+			// Queued since the pending list was fetched.
+			continue
+		}
+
+		w.c.untrackJob(j)
+		w.deliver(p, id, jobUpdate{job: j})
+	}
+
+	p.errors = 0
+	return true, true
+}
+
+// failed records a failed check, and fails every waiter on the Linode once
+// MaxErrors checks in a row have failed.
+func (w *JobWatcher) failed(p *jobPoller, err error) (progress bool, more bool) {
+	max := w.MaxErrors
+	if max <= 0 {
+		max = DefaultJobWatcherMaxErrors
+	}
+
+	p.errors++
+	if p.errors < max {
+		return false, true
+	}
+
+	p.errors = 0
+	w.deliverAll(p, err)
+	return true, true
+}
+
+func (w *JobWatcher) deliver(p *jobPoller, jobID int, u jobUpdate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ch := range p.waiters[jobID] {
+		ch <- u
+	}
+	delete(p.waiters, jobID)
+}
+
+func (w *JobWatcher) deliverAll(p *jobPoller, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for id, chans := range p.waiters {
+		for _, ch := range chans {
+			ch <- jobUpdate{err: err}
+		}
+		delete(p.waiters, id)
+	}
+}
+
+func nextJobInterval(cur time.Duration, max time.Duration) time.Duration {
+	next := cur * 2
+	if next > max {
+		return max
+	}
+	return next
+}
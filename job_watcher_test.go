@@ -0,0 +1,223 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+// fakeJobAPI serves 'linode.job.list' for a set of jobs which are pending on
+// the first pendingOnly call for their Linode, and finished afterwards.
+type fakeJobAPI struct {
+	mu      sync.Mutex
+	ready   chan struct{}
+	jobs    map[int][]int
+	failed  map[int]bool
+	polls   map[int]int
+	lists   map[int]int
+	actions []string
+}
+
+func newFakeJobAPI(jobs map[int][]int) *fakeJobAPI {
+	return &fakeJobAPI{
+		ready:  make(chan struct{}),
+		jobs:   jobs,
+		failed: make(map[int]bool),
+		polls:  make(map[int]int),
+		lists:  make(map[int]int),
+	}
+}
+
+func (f *fakeJobAPI) call(action string, args map[string]interface{}) (json.RawMessage, error) {
+	if action != "linode.job.list" {
+		return nil, fmt.Errorf("unexpected action %s", action)
+	}
+
+	<-f.ready
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	linodeID := args["LinodeID"].(int)
+
+	var out []map[string]interface{}
+	if _, ok := args["pendingOnly"]; ok {
+		f.polls[linodeID]++
+		if f.polls[linodeID] == 1 {
+			for _, id := range f.jobs[linodeID] {
+				out = append(out, map[string]interface{}{
+					"JOBID": id, "LINODEID": linodeID, "HOST_FINISH_DT": "",
+				})
+			}
+		}
+	} else {
+		f.lists[linodeID]++
+		for _, id := range f.jobs[linodeID] {
+			out = append(out, map[string]interface{}{
+				"JOBID": id, "LINODEID": linodeID, "HOST_FINISH_DT": "2015-07-08 20:16:52.0",
+				"HOST_SUCCESS": !f.failed[id], "HOST_MESSAGE": "nope",
+			})
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+func waitForWaiters(t *testing.T, w *JobWatcher, linodeID int, n int) {
+	deadline := time.Now().Add(1 * time.Second)
+	for time.Now().Before(deadline) {
+		w.mu.Lock()
+		var count int
+		if p, ok := w.linodes[linodeID]; ok {
+			for _, chans := range p.waiters {
+				count += len(chans)
+			}
+		}
+		w.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(1 * time.Millisecond)
+	}
+	require.FailNow(t, "waiters never registered")
+}
+
+func TestJobWatcherMultiplex(t *testing.T) {
+	f := newFakeJobAPI(map[int][]int{1: {10, 11, 12}, 2: {20}})
+	f.failed[12] = true
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+	w := c.NewJobWatcher(1*time.Millisecond, 4*time.Millisecond)
+
+	type result struct {
+		job LinodeJob
+		err error
+	}
+
+	var wg sync.WaitGroup
+	results := make(map[int]result)
+	var mu sync.Mutex
+
+	waits := [][2]int{{1, 10}, {1, 11}, {1, 11}, {1, 12}, {2, 20}}
+	for _, wt := range waits {
+		wg.Add(1)
+		go func(linodeID int, jobID int) {
+			defer wg.Done()
+			lj, err := w.Wait(context.Background(), linodeID, jobID)
+			mu.Lock()
+			results[jobID] = result{lj, err}
+			mu.Unlock()
+		}(wt[0], wt[1])
+	}
+
+	waitForWaiters(t, w, 1, 4)
+	waitForWaiters(t, w, 2, 1)
+	close(f.ready)
+	wg.Wait()
+
+	assert.Equal(t, 2, f.polls[1])
+	assert.Equal(t, 1, f.lists[1])
+	assert.Equal(t, 2, f.polls[2])
+	assert.Equal(t, 1, f.lists[2])
+
+	for _, id := range []int{10, 11, 20} {
+		require.NoError(t, results[id].err)
+		assert.Equal(t, id, results[id].job.ID)
+		assert.True(t, results[id].job.Success())
+	}
+
+	jErr, ok := results[12].err.(*JobError)
+	require.True(t, ok)
+	assert.Equal(t, "nope", jErr.Job.HostMessage)
+}
+
+func TestJobWatcherWaitJob(t *testing.T) {
+	f := newFakeJobAPI(map[int][]int{1: {10}})
+	close(f.ready)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+	w := c.NewJobWatcher(1*time.Millisecond, 4*time.Millisecond)
+
+	job := c.NewJob(1, 10)
+	lj, err := w.WaitJob(context.Background(), job)
+	require.NoError(t, err)
+	assert.Equal(t, lj, job.Status())
+	assert.True(t, job.Done())
+}
+
+func TestJobWatcherCanceled(t *testing.T) {
+	f := newFakeJobAPI(nil)
+
+	c := NewClient("foo")
+	c.apiCall = f.call
+	w := c.NewJobWatcher(1*time.Hour, 1*time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := w.Wait(ctx, 1, 10)
+	require.Equal(t, context.Canceled, err)
+
+	w.mu.Lock()
+	assert.Len(t, w.linodes[1].waiters, 0)
+	w.mu.Unlock()
+}
+
+func TestJobWatcherAPIError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+	w := c.NewJobWatcher(1*time.Millisecond, 1*time.Millisecond)
+
+	_, err := w.Wait(context.Background(), 1, 10)
+	require.Error(t, err)
+	assert.Equal(t, "bar", err.Error())
+}
+
+func TestJobWatcherTransientError(t *testing.T) {
+	f := newFakeJobAPI(map[int][]int{1: {10}})
+	close(f.ready)
+
+	var mu sync.Mutex
+	calls := 0
+	c := NewClient("foo")
+	c.apiCall = func(action string, args map[string]interface{}) (json.RawMessage, error) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n <= 2 {
+			return nil, fmt.Errorf("temporary")
+		}
+		return f.call(action, args)
+	}
+	w := c.NewJobWatcher(1*time.Millisecond, 1*time.Millisecond)
+
+	// Two failures in a row are retried rather than returned.
+	lj, err := w.Wait(context.Background(), 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 10, lj.ID)
+}
+
+func TestJobWatcherShared(t *testing.T) {
+	c := NewClient("foo")
+	w := c.JobWatcher()
+	assert.Equal(t, w, c.JobWatcher())
+	assert.Equal(t, DefaultJobInterval, w.MinInterval)
+	assert.Equal(t, DefaultJobWatcherMaxInterval, w.MaxInterval)
+	assert.Equal(t, DefaultJobWatcherMaxErrors, w.MaxErrors)
+}
+
+func TestNextJobInterval(t *testing.T) {
+	assert.Equal(t, 2*time.Second, nextJobInterval(1*time.Second, 3*time.Second))
+	assert.Equal(t, 3*time.Second, nextJobInterval(2*time.Second, 3*time.Second))
+}
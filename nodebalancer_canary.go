@@ -0,0 +1,205 @@
+package linode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultCanaryPause is how long ShiftCanary() waits after each step before
+// checking the gate, when CanaryOpts.Pause is not set.
+const DefaultCanaryPause = 5 * time.Minute
+
+// DefaultCanaryWeightSteps are the steps used by ShiftCanary() when
+// CanaryOpts.WeightSteps is not set.
+var DefaultCanaryWeightSteps = []int{5, 25, 100}
+
+// CanaryOpts contains the optional arguments to ShiftCanary().
+type CanaryOpts struct {
+	// WeightSteps are the percentages of full weight to move the canary
+	// nodes through, in increasing order from 1 to 100.  They are not
+	// shares of traffic: a canary at 5% of weight 100, next to four nodes
+	// at weight 100, gets about 1.2% of connections.
+	WeightSteps []int
+
+	// Weight is the full weight of a canary node.  If zero, it is the
+	// highest weight of the other nodes in the same config, so that at
+	// 100% the canaries take an equal share.
+	Weight int
+
+	// Pause is how long each step runs before Gate is checked.
+	Pause time.Duration
+
+	// Gate, if set, is called after each step's pause with the step's
+	// percentage of full weight.  If it returns an error, the canary weights
+	// are reverted.
+	Gate func(ctx context.Context, weightPercent int) error
+
+	// OnStep, if set, is called as each step's weights are applied.
+	OnStep func(weightPercent int)
+}
+
+type canaryNode struct {
+	node   NodeBalancerNode
+	target int
+}
+
+// ShiftCanary moves traffic onto the canary nodes of a NodeBalancer in
+// steps, by raising their weights in every config towards their full weight.
+// The share of traffic at each step depends on the weights of the other
+// nodes; see CanaryOpts.WeightSteps.  Canaries are given by
+// label or address, as for DrainNode().  Other nodes are left alone.
+//
+// If the gate fails, ctx is done, or a weight cannot be set, every canary
+// node is put back to the weight it had before, and an error is returned.
+func (c *Client) ShiftCanary(ctx context.Context, nbID int, canaries []string, opts CanaryOpts) error {
+	steps := opts.WeightSteps
+	if len(steps) == 0 {
+		steps = DefaultCanaryWeightSteps
+	}
+	last := 0
+	for _, p := range steps {
+		if p <= last || p > 100 {
+			return fmt.Errorf("nodebalancer: canary weight steps must increase from 1 to 100, got %v", steps)
+		}
+		last = p
+	}
+	if opts.Weight < 0 || opts.Weight > 255 {
+		return fmt.Errorf("nodebalancer: invalid canary weight %d", opts.Weight)
+	}
+	if len(canaries) == 0 {
+		return errors.New("nodebalancer: no canary nodes given")
+	}
+
+	nodes, err := c.canaryNodes(nbID, canaries, opts.Weight)
+	if err != nil {
+		return err
+	}
+
+	pause := opts.Pause
+	if pause <= 0 {
+		pause = DefaultCanaryPause
+	}
+
+	// Every weight set so far, so that a failure can undo it.
+	current := make(map[int]int)
+	for _, cn := range nodes {
+		current[cn.node.ID] = cn.node.Weight
+	}
+
+	revert := func(cause error) error {
+		var failed []string
+		for _, cn := range nodes {
+			if current[cn.node.ID] == cn.node.Weight {
+				continue
+			}
+			err := c.NodeBalancerNodeUpdate(cn.node.ID, nil, nil, Int(cn.node.Weight), nil)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", cn.node.Address, err))
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("nodebalancer: canary failed: %s (revert failed for %v)", cause, failed)
+		}
+		return fmt.Errorf("nodebalancer: canary failed and was reverted: %s", cause)
+	}
+
+	for _, p := range steps {
+		for _, cn := range nodes {
+			w := canaryWeight(cn.target, p)
+			if current[cn.node.ID] == w {
+				continue
+			}
+			err := c.NodeBalancerNodeUpdate(cn.node.ID, nil, nil, Int(w), nil)
+			if err != nil {
+				return revert(fmt.Errorf("setting %s to weight %d: %s", cn.node.Address, w, err))
+			}
+			current[cn.node.ID] = w
+		}
+		if opts.OnStep != nil {
+			opts.OnStep(p)
+		}
+
+		t := time.NewTimer(pause)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return revert(ctx.Err())
+		}
+
+		if opts.Gate != nil {
+			err := opts.Gate(ctx, p)
+			if err != nil {
+				return revert(fmt.Errorf("gate at %d%% weight: %s", p, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// canaryNodes finds the canary nodes in every config of a NodeBalancer, and
+// the full weight of each.
+func (c *Client) canaryNodes(nbID int, canaries []string, weight int) ([]canaryNode, error) {
+	configs, err := c.NodeBalancerConfigList(nbID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []canaryNode
+	matched := make(map[string]bool)
+	for _, conf := range configs {
+		nodes, err := c.NodeBalancerNodeList(conf.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var mine []NodeBalancerNode
+		peak := 0
+		for _, n := range nodes {
+			isCanary := false
+			for _, name := range canaries {
+				if nodeMatches(n, name) {
+					isCanary = true
+					matched[name] = true
+				}
+			}
+			if isCanary {
+				mine = append(mine, n)
+			} else if n.Weight > peak {
+				peak = n.Weight
+			}
+		}
+
+		target := weight
+		if target == 0 {
+			target = peak
+		}
+		if target == 0 {
+			target = 100
+		}
+		for _, n := range mine {
+			out = append(out, canaryNode{n, target})
+		}
+	}
+
+	for _, name := range canaries {
+		if !matched[name] {
+			return nil, fmt.Errorf("nodebalancer: no node matching %q on NodeBalancer %d", name, nbID)
+		}
+	}
+
+	return out, nil
+}
+
+// canaryWeight is percent of target, rounded, and at least 1, the lowest
+// weight the API allows.
+func canaryWeight(target int, percent int) int {
+	w := (target*percent + 50) / 100
+	if w < 1 {
+		return 1
+	}
+	return w
+}
@@ -0,0 +1,105 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockCanaryNodes() []mockAPIResponse {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80},{"CONFIGID":11,"NODEBALANCERID":5,"PORT":443}],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"NODEID":20,"CONFIGID":10,"LABEL":"stable","ADDRESS":"192.168.1.1:80","WEIGHT":200},`+
+			`{"NODEID":21,"CONFIGID":10,"LABEL":"canary","ADDRESS":"192.168.1.2:80","WEIGHT":1}`+
+			`],"ACTION":"nodebalancer.node.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "11"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"NODEID":22,"CONFIGID":11,"LABEL":"stable","ADDRESS":"192.168.1.1:443","WEIGHT":50},`+
+			`{"NODEID":23,"CONFIGID":11,"LABEL":"canary","ADDRESS":"192.168.1.2:443","WEIGHT":1}`+
+			`],"ACTION":"nodebalancer.node.list"}`))
+	return responses
+}
+
+func mockNodeWeight(nodeID string, weight string) mockAPIResponse {
+	return newMockAPIResponse("nodebalancer.node.update", map[string]string{"NodeID": nodeID, "Weight": weight},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":`+nodeID+`},"ACTION":"nodebalancer.node.update"}`)
+}
+
+func TestShiftCanary(t *testing.T) {
+	responses := mockCanaryNodes()
+	// 5%: 10 of 200 on port 80, and 3 of 50 (rounded) on 443.
+	responses = append(responses, mockNodeWeight("21", "10"), mockNodeWeight("23", "3"))
+	responses = append(responses, mockNodeWeight("21", "50"), mockNodeWeight("23", "13"))
+	responses = append(responses, mockNodeWeight("21", "200"), mockNodeWeight("23", "50"))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	var steps, gates []int
+	err := c.ShiftCanary(context.Background(), 5, []string{"192.168.1.2"}, CanaryOpts{
+		Pause: time.Millisecond,
+		Gate: func(ctx context.Context, weightPercent int) error {
+			gates = append(gates, weightPercent)
+			return nil
+		},
+		OnStep: func(weightPercent int) {
+			steps = append(steps, weightPercent)
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 25, 100}, steps)
+	assert.Equal(t, []int{5, 25, 100}, gates)
+}
+
+func TestShiftCanaryRevert(t *testing.T) {
+	responses := mockCanaryNodes()
+	responses = append(responses, mockNodeWeight("21", "20"), mockNodeWeight("23", "5"))
+	responses = append(responses, mockNodeWeight("21", "1"), mockNodeWeight("23", "1"))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	err := c.ShiftCanary(context.Background(), 5, []string{"canary"}, CanaryOpts{
+		WeightSteps: []int{10, 50},
+		Pause:       time.Millisecond,
+		Gate: func(ctx context.Context, weightPercent int) error {
+			return errors.New("error rate too high")
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reverted")
+	assert.Contains(t, err.Error(), "gate at 10%")
+}
+
+func TestShiftCanaryErrors(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockCanaryNodes()))
+	defer ts.Close()
+
+	err := c.ShiftCanary(context.Background(), 5, []string{"canary", "missing"}, CanaryOpts{})
+	assert.Error(t, err)
+
+	for _, opts := range []CanaryOpts{
+		{WeightSteps: []int{50, 25}},
+		{WeightSteps: []int{0, 100}},
+		{WeightSteps: []int{50, 150}},
+		{Weight: 300},
+	} {
+		assert.Error(t, c.ShiftCanary(context.Background(), 5, []string{"canary"}, opts), "%+v", opts)
+	}
+	assert.Error(t, c.ShiftCanary(context.Background(), 5, nil, CanaryOpts{}))
+}
+
+func TestCanaryWeight(t *testing.T) {
+	assert.Equal(t, 1, canaryWeight(10, 1))
+	assert.Equal(t, 5, canaryWeight(100, 5))
+	assert.Equal(t, 255, canaryWeight(255, 100))
+}
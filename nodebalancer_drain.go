@@ -0,0 +1,218 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Defaults for DrainNode() and RestoreNode().
+const (
+	DefaultDrainTimeout     = 5 * time.Minute
+	DefaultRestoreTimeout   = 5 * time.Minute
+	DefaultNodePollInterval = 10 * time.Second
+)
+
+// DrainOpts contains the optional arguments to DrainNode().
+type DrainOpts struct {
+	// Quiesced, if set, is called every Interval with the draining nodes,
+	// and should return true once they have finished their connections.
+	// Without it, DrainNode() waits out Timeout.
+	Quiesced func(ctx context.Context, nodes []NodeBalancerNode) (bool, error)
+
+	// Timeout bounds the wait for the nodes to quiesce.
+	Timeout  time.Duration
+	Interval time.Duration
+
+	// Reject moves the nodes to reject mode once drained, so they receive
+	// no traffic at all.
+	Reject bool
+}
+
+// RestoreOpts contains the optional arguments to RestoreNode().
+type RestoreOpts struct {
+	// Timeout bounds the wait for the nodes to report UP, checking every
+	// Interval.
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+// DrainNode puts the nodes matching node in every config of a NodeBalancer
+// into drain mode, so they stop receiving new connections, and waits for
+// them to quiesce.  node is a node label, an "ip:port" address, or an IP
+// matching any port.
+//
+// It returns true if opts.Quiesced reported the nodes quiesced, and false if
+// Timeout passed first, in which case the drain is still completed.  It is an
+// error for no node to match.
+func (c *Client) DrainNode(ctx context.Context, nbID int, node string, opts DrainOpts) (bool, error) {
+	nodes, err := c.findNodes(nbID, node)
+	if err != nil {
+		return false, err
+	}
+
+	err = c.setNodeMode(nodes, "drain")
+	if err != nil {
+		return false, err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	quiesced := false
+	if opts.Quiesced == nil {
+		<-wctx.Done()
+	} else {
+		quiesced, err = pollNodes(wctx, opts.Interval, func(ctx context.Context) (bool, error) {
+			return opts.Quiesced(ctx, nodes)
+		})
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if err != nil {
+		return false, fmt.Errorf("nodebalancer: waiting for %s to drain: %s", node, err)
+	}
+
+	if opts.Reject {
+		err = c.setNodeMode(nodes, "reject")
+		if err != nil {
+			return quiesced, err
+		}
+	}
+
+	return quiesced, nil
+}
+
+// RestoreNode puts the nodes matching node, as for DrainNode(), back into
+// accept mode, and waits until the NodeBalancer reports each of them UP.
+func (c *Client) RestoreNode(ctx context.Context, nbID int, node string, opts RestoreOpts) error {
+	nodes, err := c.findNodes(nbID, node)
+	if err != nil {
+		return err
+	}
+
+	err = c.setNodeMode(nodes, "accept")
+	if err != nil {
+		return err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRestoreTimeout
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var down []string
+	up, err := pollNodes(wctx, opts.Interval, func(ctx context.Context) (bool, error) {
+		down = down[:0]
+		for _, n := range nodes {
+			current, err := c.NodeBalancerNodeList(n.ConfigID, Int(n.ID))
+			if err != nil {
+				return false, err
+			}
+			if len(current) != 1 {
+				return false, fmt.Errorf("node %d no longer exists", n.ID)
+			}
+			if !strings.EqualFold(current[0].Status, "up") {
+				down = append(down, fmt.Sprintf("%s (%s)", n.Address, current[0].Status))
+			}
+		}
+		return len(down) == 0, nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("nodebalancer: waiting for %s to come up: %s", node, err)
+	}
+	if !up {
+		return fmt.Errorf("nodebalancer: %s did not come up within %s: %s",
+			node, timeout, strings.Join(down, ", "))
+	}
+
+	return nil
+}
+
+// findNodes returns the nodes in every config of a NodeBalancer whose label
+// or address matches node.
+func (c *Client) findNodes(nbID int, node string) ([]NodeBalancerNode, error) {
+	configs, err := c.NodeBalancerConfigList(nbID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []NodeBalancerNode
+	for _, conf := range configs {
+		nodes, err := c.NodeBalancerNodeList(conf.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if nodeMatches(n, node) {
+				found = append(found, n)
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("nodebalancer: no node matching %q on NodeBalancer %d", node, nbID)
+	}
+	return found, nil
+}
+
+func nodeMatches(n NodeBalancerNode, node string) bool {
+	if n.Label == node || n.Address == node {
+		return true
+	}
+	host, _, err := net.SplitHostPort(n.Address)
+	return err == nil && host == node
+}
+
+func (c *Client) setNodeMode(nodes []NodeBalancerNode, mode string) error {
+	for _, n := range nodes {
+		if strings.EqualFold(n.Mode, mode) {
+			continue
+		}
+		err := c.NodeBalancerNodeUpdate(n.ID, nil, nil, nil, String(mode))
+		if err != nil {
+			return fmt.Errorf("nodebalancer: setting %s to %s: %s", n.Address, mode, err)
+		}
+	}
+	return nil
+}
+
+// pollNodes calls done every interval until it returns true or an error, or
+// ctx is done, in which case it returns false.
+func pollNodes(ctx context.Context, interval time.Duration,
+	done func(ctx context.Context) (bool, error)) (bool, error) {
+
+	if interval <= 0 {
+		interval = DefaultNodePollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ok, err := done(ctx)
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		if err != nil || ok {
+			return ok, err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false, nil
+		}
+	}
+}
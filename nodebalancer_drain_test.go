@@ -0,0 +1,112 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockFindNodes() []mockAPIResponse {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80},{"CONFIGID":11,"NODEBALANCERID":5,"PORT":443}],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"NODEID":20,"CONFIGID":10,"LABEL":"app1","ADDRESS":"192.168.1.1:80","MODE":"accept","STATUS":"UP"},`+
+			`{"NODEID":21,"CONFIGID":10,"LABEL":"app2","ADDRESS":"192.168.1.2:80","MODE":"accept","STATUS":"UP"}`+
+			`],"ACTION":"nodebalancer.node.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "11"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"NODEID":22,"CONFIGID":11,"LABEL":"web1","ADDRESS":"192.168.1.1:443","MODE":"drain","STATUS":"UP"}`+
+			`],"ACTION":"nodebalancer.node.list"}`))
+	return responses
+}
+
+func mockNodeMode(nodeID string, mode string) mockAPIResponse {
+	return newMockAPIResponse("nodebalancer.node.update", map[string]string{"NodeID": nodeID, "Mode": mode},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":`+nodeID+`},"ACTION":"nodebalancer.node.update"}`)
+}
+
+func TestDrainNode(t *testing.T) {
+	responses := mockFindNodes()
+	// The node on 443 is already draining.
+	responses = append(responses, mockNodeMode("20", "drain"))
+	responses = append(responses, mockNodeMode("20", "reject"))
+	responses = append(responses, mockNodeMode("22", "reject"))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	calls := 0
+	quiesced, err := c.DrainNode(context.Background(), 5, "192.168.1.1", DrainOpts{
+		Quiesced: func(ctx context.Context, nodes []NodeBalancerNode) (bool, error) {
+			assert.Len(t, nodes, 2)
+			calls++
+			return calls == 2, nil
+		},
+		Interval: time.Millisecond,
+		Reject:   true,
+	})
+	require.NoError(t, err)
+	assert.True(t, quiesced)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDrainNodeTimeout(t *testing.T) {
+	responses := mockFindNodes()
+	responses = append(responses, mockNodeMode("21", "drain"))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	quiesced, err := c.DrainNode(context.Background(), 5, "app2", DrainOpts{Timeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+	assert.False(t, quiesced)
+}
+
+func TestDrainNodeMissing(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockFindNodes()))
+	defer ts.Close()
+
+	_, err := c.DrainNode(context.Background(), 5, "app9", DrainOpts{})
+	assert.Error(t, err)
+}
+
+func TestRestoreNode(t *testing.T) {
+	responses := mockFindNodes()
+	responses = append(responses, mockNodeMode("22", "accept"))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "11", "NodeID": "22"},
+		`{"ERRORARRAY":[],"DATA":[{"NODEID":22,"CONFIGID":11,"LABEL":"web1","ADDRESS":"192.168.1.1:443","MODE":"accept","STATUS":"Unknown"}],"ACTION":"nodebalancer.node.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "11", "NodeID": "22"},
+		`{"ERRORARRAY":[],"DATA":[{"NODEID":22,"CONFIGID":11,"LABEL":"web1","ADDRESS":"192.168.1.1:443","MODE":"accept","STATUS":"UP"}],"ACTION":"nodebalancer.node.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	err := c.RestoreNode(context.Background(), 5, "web1", RestoreOpts{Interval: time.Millisecond})
+	require.NoError(t, err)
+}
+
+func TestRestoreNodeTimeout(t *testing.T) {
+	responses := mockFindNodes()
+	responses = append(responses, mockNodeMode("22", "accept"))
+	for i := 0; i < 100; i++ {
+		responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "11", "NodeID": "22"},
+			`{"ERRORARRAY":[],"DATA":[{"NODEID":22,"CONFIGID":11,"LABEL":"web1","ADDRESS":"192.168.1.1:443","STATUS":"DOWN"}],"ACTION":"nodebalancer.node.list"}`))
+	}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	err := c.RestoreNode(context.Background(), 5, "192.168.1.1:443", RestoreOpts{
+		Timeout:  20 * time.Millisecond,
+		Interval: 5 * time.Millisecond,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "192.168.1.1:443 (DOWN)")
+}
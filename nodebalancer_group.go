@@ -0,0 +1,248 @@
+package linode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultGroupSyncInterval is how often a GroupSync created by
+// NewGroupSync() syncs when run.
+const DefaultGroupSyncInterval = time.Minute
+
+// GroupSyncAction identifies what a GroupSyncChange did.
+type GroupSyncAction string
+
+// Actions taken by GroupSync.
+const (
+	GroupSyncAllocateIP GroupSyncAction = "allocate_ip"
+	GroupSyncAddNode    GroupSyncAction = "add_node"
+	GroupSyncRemoveNode GroupSyncAction = "remove_node"
+)
+
+// GroupSyncChange is a single change made by GroupSync.  LinodeID is zero
+// when removing a node that no Linode in the group owns.
+type GroupSyncChange struct {
+	Action   GroupSyncAction
+	LinodeID int
+	Label    string
+	Address  string
+	NodeID   int
+	DryRun   bool
+	Err      error
+}
+
+func (gc GroupSyncChange) String() string {
+	s := fmt.Sprintf("%s %s", gc.Action, gc.Label)
+	if gc.Address != "" {
+		s += " " + gc.Address
+	}
+	if gc.Err != nil {
+		s += ": " + gc.Err.Error()
+	}
+	return s
+}
+
+// GroupSync keeps the nodes of a NodeBalancer config equal to the private
+// IPs of the Linodes in a display group.  It should be created by a call to
+// NewGroupSync().
+//
+// Each Linode in the group gets one node, labelled with the Linode's label,
+// at its private IP and Port.  A Linode without a private IP is given one;
+// it may need a reboot before the address is configured.  Nodes that do not
+// belong to a Linode in the group are removed, after any new nodes are
+// added.  As a guard against a mistyped group, nothing is removed if the
+// group has no Linodes.
+type GroupSync struct {
+	ConfigID int
+	Group    string
+	Port     int
+
+	// Weight and Mode are used for new nodes, if set.
+	Weight int
+	Mode   string
+
+	Interval time.Duration
+
+	// DryRun reports changes without making them.  A Linode that would be
+	// given a private IP does not get a node, since its address is not yet
+	// known.
+	DryRun bool
+
+	// OnChange and OnError, if set, are called by Run() with each change,
+	// and when a sync fails, respectively.
+	OnChange func(gc GroupSyncChange)
+	OnError  func(err error)
+
+	c  *Client
+	mu sync.Mutex
+}
+
+// NewGroupSync returns a GroupSync that registers the Linodes in group with
+// the config confID on port.
+func (c *Client) NewGroupSync(confID int, group string, port int) *GroupSync {
+	return &GroupSync{
+		ConfigID: confID,
+		Group:    group,
+		Port:     port,
+		Interval: DefaultGroupSyncInterval,
+		c:        c,
+	}
+}
+
+// Run syncs every Interval until ctx is done.
+func (s *GroupSync) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultGroupSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		changes, err := s.SyncOnce(ctx)
+		if s.OnChange != nil {
+			for _, gc := range changes {
+				s.OnChange(gc)
+			}
+		}
+		if err != nil && ctx.Err() == nil && s.OnError != nil {
+			s.OnError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// SyncOnce brings the config's nodes in line with the group once, and
+// returns the changes made.  A failed change does not stop the others; an
+// error is only returned if the Linodes or nodes cannot be listed.
+func (s *GroupSync) SyncOnce(ctx context.Context) ([]GroupSyncChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Port < 1 || s.Port > 65535 {
+		return nil, fmt.Errorf("nodebalancer: invalid port %d", s.Port)
+	}
+	if s.Group == "" {
+		return nil, errors.New("nodebalancer: no display group given")
+	}
+
+	linodes, err := s.c.LinodeList(nil)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := s.c.LinodeIPList(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.c.NodeBalancerNodeList(s.ConfigID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	private := make(map[int][]string)
+	for _, ip := range ips {
+		if !ip.IsPublic {
+			private[ip.LinodeID] = append(private[ip.LinodeID], ip.Address)
+		}
+	}
+	byAddress := make(map[string]NodeBalancerNode)
+	for _, n := range nodes {
+		byAddress[n.Address] = n
+	}
+
+	var changes []GroupSyncChange
+	keep := make(map[int]bool)
+	members := 0
+	for _, l := range linodes {
+		if l.DisplayGroup != s.Group {
+			continue
+		}
+		members++
+		if ctx.Err() != nil {
+			return changes, ctx.Err()
+		}
+
+		addrs := private[l.ID]
+		found := false
+		for _, ip := range addrs {
+			if n, ok := byAddress[s.address(ip)]; ok {
+				keep[n.ID] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		if len(addrs) == 0 {
+			gc := GroupSyncChange{Action: GroupSyncAllocateIP, LinodeID: l.ID, Label: l.Label, DryRun: s.DryRun}
+			if !s.DryRun {
+				var ip string
+				_, ip, gc.Err = s.c.LinodeIPAddPrivate(l.ID)
+				gc.Address = ip
+				addrs = []string{ip}
+			}
+			changes = append(changes, gc)
+			if s.DryRun || gc.Err != nil {
+				continue
+			}
+		}
+
+		gc := GroupSyncChange{
+			Action:   GroupSyncAddNode,
+			LinodeID: l.ID,
+			Label:    l.Label,
+			Address:  s.address(addrs[0]),
+			DryRun:   s.DryRun,
+		}
+		if !s.DryRun {
+			var weight *int
+			if s.Weight != 0 {
+				weight = Int(s.Weight)
+			}
+			var mode *string
+			if s.Mode != "" {
+				mode = String(s.Mode)
+			}
+			gc.NodeID, gc.Err = s.c.NodeBalancerNodeCreate(s.ConfigID, l.Label, gc.Address, weight, mode)
+		}
+		changes = append(changes, gc)
+	}
+
+	if members == 0 {
+		return changes, fmt.Errorf("nodebalancer: display group %q has no Linodes, not removing nodes", s.Group)
+	}
+
+	for _, n := range nodes {
+		if keep[n.ID] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return changes, ctx.Err()
+		}
+
+		gc := GroupSyncChange{Action: GroupSyncRemoveNode, Label: n.Label, Address: n.Address, NodeID: n.ID, DryRun: s.DryRun}
+		if !s.DryRun {
+			gc.Err = s.c.NodeBalancerNodeDelete(n.ID)
+		}
+		changes = append(changes, gc)
+	}
+
+	return changes, nil
+}
+
+func (s *GroupSync) address(ip string) string {
+	return net.JoinHostPort(ip, strconv.Itoa(s.Port))
+}
@@ -0,0 +1,104 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockGroupSync(nodes string) []mockAPIResponse {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("linode.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"LINODEID":1,"LABEL":"web1","LPM_DISPLAYGROUP":"web"},`+
+			`{"LINODEID":2,"LABEL":"web2","LPM_DISPLAYGROUP":"web"},`+
+			`{"LINODEID":3,"LABEL":"db1","LPM_DISPLAYGROUP":"db"}`+
+			`],"ACTION":"linode.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.ip.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"LINODEID":1,"ISPUBLIC":1,"IPADDRESS":"45.33.5.10","IPADDRESSID":5},`+
+			`{"LINODEID":1,"ISPUBLIC":0,"IPADDRESS":"192.168.1.1","IPADDRESSID":6},`+
+			`{"LINODEID":2,"ISPUBLIC":1,"IPADDRESS":"45.33.5.11","IPADDRESSID":7},`+
+			`{"LINODEID":3,"ISPUBLIC":0,"IPADDRESS":"192.168.1.3","IPADDRESSID":8}`+
+			`],"ACTION":"linode.ip.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[`+nodes+`],"ACTION":"nodebalancer.node.list"}`))
+	return responses
+}
+
+const groupSyncNodes = `{"NODEID":20,"CONFIGID":10,"LABEL":"web1","ADDRESS":"192.168.1.1:80"},` +
+	`{"NODEID":21,"CONFIGID":10,"LABEL":"old","ADDRESS":"192.168.1.9:80"}`
+
+func TestGroupSync(t *testing.T) {
+	responses := mockGroupSync(groupSyncNodes)
+	responses = append(responses, newMockAPIResponse("linode.ip.addprivate", map[string]string{"LinodeID": "2"},
+		`{"ERRORARRAY":[],"DATA":{"IPADDRESSID":9,"IPADDRESS":"192.168.1.2"},"ACTION":"linode.ip.addprivate"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.create",
+		map[string]string{"ConfigID": "10", "Label": "web2", "Address": "192.168.1.2:80", "Weight": "50"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":22},"ACTION":"nodebalancer.node.create"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.delete", map[string]string{"NodeID": "21"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":21},"ACTION":"nodebalancer.node.delete"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	s := c.NewGroupSync(10, "web", 80)
+	s.Weight = 50
+	changes, err := s.SyncOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []GroupSyncChange{
+		{Action: GroupSyncAllocateIP, LinodeID: 2, Label: "web2", Address: "192.168.1.2"},
+		{Action: GroupSyncAddNode, LinodeID: 2, Label: "web2", Address: "192.168.1.2:80", NodeID: 22},
+		{Action: GroupSyncRemoveNode, Label: "old", Address: "192.168.1.9:80", NodeID: 21},
+	}, changes)
+}
+
+func TestGroupSyncDryRun(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockGroupSync(groupSyncNodes)))
+	defer ts.Close()
+
+	s := c.NewGroupSync(10, "web", 80)
+	s.DryRun = true
+	changes, err := s.SyncOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "allocate_ip web2", changes[0].String())
+	assert.Equal(t, "remove_node old 192.168.1.9:80", changes[1].String())
+}
+
+func TestGroupSyncErrors(t *testing.T) {
+	responses := mockGroupSync(groupSyncNodes)
+	responses = append(responses, newMockAPIResponse("linode.ip.addprivate", map[string]string{"LinodeID": "2"},
+		`{"ERRORARRAY":[{"ERRORCODE":8,"ERRORMESSAGE":"No more private IPs"}],"DATA":{},"ACTION":"linode.ip.addprivate"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.delete", map[string]string{"NodeID": "21"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":21},"ACTION":"nodebalancer.node.delete"}`))
+	responses = append(responses, mockGroupSync(groupSyncNodes)...)
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// A failed allocation does not stop the removal.
+	changes, err := c.NewGroupSync(10, "web", 80).SyncOnce(context.Background())
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Error(t, changes[0].Err)
+	assert.Equal(t, GroupSyncRemoveNode, changes[1].Action)
+
+	// An empty group removes nothing.
+	changes, err = c.NewGroupSync(10, "wbe", 80).SyncOnce(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, changes, 0)
+
+	_, err = c.NewGroupSync(10, "web", 0).SyncOnce(context.Background())
+	assert.Error(t, err)
+	_, err = c.NewGroupSync(10, "", 80).SyncOnce(context.Background())
+	assert.Error(t, err)
+
+	c.apiCall = apiCallerError
+	_, err = c.NewGroupSync(10, "web", 80).SyncOnce(context.Background())
+	assert.Error(t, err)
+}
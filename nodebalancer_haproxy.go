@@ -0,0 +1,254 @@
+package linode
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HAProxyIssue is a NodeBalancer setting that FormatHAProxy() could not
+// represent exactly.  Node is empty when it applies to the whole config.
+type HAProxyIssue struct {
+	Port    int
+	Node    string
+	Message string
+}
+
+func (i HAProxyIssue) String() string {
+	if i.Node == "" {
+		return fmt.Sprintf("port %d: %s", i.Port, i.Message)
+	}
+	return fmt.Sprintf("port %d: node %s: %s", i.Port, i.Node, i.Message)
+}
+
+// ExportHAProxy returns a NodeBalancer as an HAProxy configuration, along
+// with the settings that could not be represented.  See FormatHAProxy().
+func (c *Client) ExportHAProxy(nbID int) (string, []HAProxyIssue, error) {
+	nbs, err := c.NodeBalancerList(Int(nbID))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(nbs) != 1 {
+		return "", nil, fmt.Errorf("nodebalancer: no NodeBalancer with ID %d", nbID)
+	}
+
+	configs, err := c.NodeBalancerConfigList(nbID, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	nodes := make(map[int][]NodeBalancerNode)
+	for _, conf := range configs {
+		nodes[conf.ID], err = c.NodeBalancerNodeList(conf.ID, nil)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	out, issues := FormatHAProxy(nbs[0], configs, nodes)
+	return out, issues, nil
+}
+
+// FormatHAProxy renders a NodeBalancer, its configs, and their nodes, keyed
+// by config ID, as an HAProxy configuration with a frontend and backend per
+// port.
+//
+// HTTPS is terminated by HAProxy as it is by the NodeBalancer, but the API
+// does not return certificates, so each HTTPS frontend expects a PEM file
+// named after the NodeBalancer and port in HAProxy's working directory.
+// Nodes in drain mode get weight 0, so that only sticky sessions reach them,
+// and nodes in reject mode are disabled.
+func FormatHAProxy(nb NodeBalancer, configs []NodeBalancerConfig,
+	nodes map[int][]NodeBalancerNode) (string, []HAProxyIssue) {
+
+	var buf bytes.Buffer
+	var issues []HAProxyIssue
+
+	fmt.Fprintf(&buf, "# NodeBalancer %s (%d)", nb.Label, nb.ID)
+	if nb.Hostname != "" {
+		fmt.Fprintf(&buf, ", %s", nb.Hostname)
+	}
+	buf.WriteString("\n\n")
+	buf.WriteString("defaults\n")
+	buf.WriteString("    timeout connect 5s\n")
+	buf.WriteString("    timeout client 50s\n")
+	buf.WriteString("    timeout server 50s\n")
+
+	sorted := append([]NodeBalancerConfig(nil), configs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Port < sorted[j].Port })
+
+	for _, conf := range sorted {
+		issue := func(node string, format string, args ...interface{}) {
+			issues = append(issues, HAProxyIssue{conf.Port, node, fmt.Sprintf(format, args...)})
+		}
+
+		protocol := strings.ToLower(conf.Protocol)
+		mode := "http"
+		switch protocol {
+		case "http", "https":
+		case "tcp":
+			mode = "tcp"
+		default:
+			issue("", "unknown protocol %q, config skipped", conf.Protocol)
+			continue
+		}
+		name := haproxyName(fmt.Sprintf("port_%d", conf.Port))
+
+		fmt.Fprintf(&buf, "\nfrontend %s\n", name)
+		if protocol == "https" {
+			cert := haproxyName(fmt.Sprintf("%s_%d.pem", nb.Label, conf.Port))
+			fmt.Fprintf(&buf, "    bind *:%d ssl crt %s\n", conf.Port, cert)
+			issue("", "certificate for %s (%s) must be supplied as %s",
+				orUnknown(conf.SSLCommonName), orUnknown(conf.SSLFingerprint), cert)
+		} else {
+			fmt.Fprintf(&buf, "    bind *:%d\n", conf.Port)
+		}
+		fmt.Fprintf(&buf, "    mode %s\n", mode)
+		if mode == "http" {
+			buf.WriteString("    option forwardfor\n")
+			fmt.Fprintf(&buf, "    http-request set-header X-Forwarded-Proto %s\n", protocol)
+		}
+		if nb.Throttle > 0 {
+			// Connections per second from each client IP.
+			buf.WriteString("    stick-table type ip size 100k expire 10s store conn_rate(1s)\n")
+			buf.WriteString("    tcp-request connection track-sc0 src\n")
+			fmt.Fprintf(&buf, "    tcp-request connection reject if { sc_conn_rate(0) gt %d }\n", nb.Throttle)
+		}
+		fmt.Fprintf(&buf, "    default_backend %s\n", name)
+
+		fmt.Fprintf(&buf, "\nbackend %s\n", name)
+		fmt.Fprintf(&buf, "    mode %s\n", mode)
+
+		switch algo := strings.ToLower(conf.Algorithm); algo {
+		case "roundrobin", "leastconn", "source":
+			fmt.Fprintf(&buf, "    balance %s\n", algo)
+		case "":
+		default:
+			issue("", "unknown algorithm %q, using roundrobin", conf.Algorithm)
+		}
+
+		cookie := false
+		switch stick := strings.ToLower(conf.Stickiness); stick {
+		case "table":
+			buf.WriteString("    stick-table type ip size 200k expire 30m\n")
+			buf.WriteString("    stick on src\n")
+		case "http_cookie":
+			if mode == "http" {
+				buf.WriteString("    cookie NB_SRVID insert indirect nocache\n")
+				cookie = true
+			} else {
+				issue("", "http_cookie stickiness needs HTTP, ignored")
+			}
+		case "none", "":
+		default:
+			issue("", "unknown stickiness %q, ignored", conf.Stickiness)
+		}
+
+		check := ""
+		switch chk := strings.ToLower(conf.Check); chk {
+		case "connection":
+			check = " check"
+		case "http", "http_body":
+			check = " check"
+			path := conf.CheckPath
+			if path == "" {
+				path = "/"
+			}
+			fmt.Fprintf(&buf, "    option httpchk GET %s\n", haproxyQuote(path))
+			if chk == "http_body" {
+				fmt.Fprintf(&buf, "    http-check expect rstring %s\n", haproxyQuote(conf.CheckBody))
+			}
+		case "none", "":
+		default:
+			issue("", "unknown check %q, ignored", conf.Check)
+		}
+		if check != "" {
+			if conf.CheckTimeout > 0 {
+				fmt.Fprintf(&buf, "    timeout check %ds\n", conf.CheckTimeout)
+			}
+			if conf.CheckInterval > 0 {
+				check += fmt.Sprintf(" inter %ds", conf.CheckInterval)
+			}
+			if conf.CheckAttempts > 0 {
+				check += fmt.Sprintf(" fall %d", conf.CheckAttempts)
+			}
+		}
+		if conf.CheckPassive {
+			if check == "" {
+				issue("", "passive checks need an active check in HAProxy, ignored")
+			} else {
+				layer := "layer7"
+				if mode == "tcp" {
+					layer = "layer4"
+				}
+				check += " observe " + layer + " error-limit 1 on-error mark-down"
+			}
+		}
+
+		confNodes := append([]NodeBalancerNode(nil), nodes[conf.ID]...)
+		sort.Slice(confNodes, func(i, j int) bool { return confNodes[i].Label < confNodes[j].Label })
+
+		names := make(map[string]bool)
+		for _, n := range confNodes {
+			server := haproxyName(n.Label)
+			for i := 2; names[server]; i++ {
+				server = haproxyName(n.Label) + "_" + strconv.Itoa(i)
+			}
+			names[server] = true
+
+			line := fmt.Sprintf("    server %s %s", server, n.Address)
+			nodeMode := strings.ToLower(n.Mode)
+			if nodeMode == "drain" {
+				line += " weight 0"
+			} else if n.Weight > 0 {
+				line += fmt.Sprintf(" weight %d", n.Weight)
+			}
+			switch nodeMode {
+			case "accept", "drain", "":
+			case "reject":
+				line += " disabled"
+			case "backup":
+				line += " backup"
+			default:
+				issue(n.Label, "unknown mode %q, treated as accept", n.Mode)
+			}
+			if cookie {
+				line += " cookie " + server
+			}
+			buf.WriteString(line + check + "\n")
+		}
+	}
+
+	return buf.String(), issues
+}
+
+// haproxyName replaces the characters HAProxy does not allow in names.
+func haproxyName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '_', r == '-', r == '.', r == ':':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// haproxyQuote quotes an argument if it contains spaces or quotes.
+func haproxyQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\#") {
+		return s
+	}
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	return `"` + s + `"`
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,125 @@
+// +build !integration
+
+package linode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestFormatHAProxy(t *testing.T) {
+	nb := NodeBalancer{ID: 5, Label: "web", Hostname: "nb-1-2-3-4.newark.nodebalancer.linode.com", Throttle: 10}
+	configs := []NodeBalancerConfig{
+		{ID: 11, Port: 443, Protocol: "https", Algorithm: "leastconn", Stickiness: "http_cookie",
+			Check: "http_body", CheckPath: "/health", CheckBody: "all good", CheckInterval: 5,
+			CheckTimeout: 3, CheckAttempts: 2, CheckPassive: true,
+			SSLCommonName: "www.example.com", SSLFingerprint: "AA:BB"},
+		{ID: 10, Port: 80, Protocol: "tcp", Algorithm: "source", Stickiness: "table", Check: "connection"},
+		{ID: 12, Port: 8080, Protocol: "udp"},
+	}
+	nodes := map[int][]NodeBalancerNode{
+		10: {
+			{ID: 21, Label: "app 2", Address: "192.168.1.2:80", Weight: 100, Mode: "drain"},
+			{ID: 20, Label: "app1", Address: "192.168.1.1:80", Weight: 100, Mode: "accept"},
+		},
+		11: {
+			{ID: 22, Label: "app1", Address: "192.168.1.1:80", Weight: 50, Mode: "reject"},
+			{ID: 23, Label: "app1", Address: "192.168.1.3:80", Weight: 50, Mode: "sideways"},
+		},
+	}
+
+	out, issues := FormatHAProxy(nb, configs, nodes)
+
+	want := `# NodeBalancer web (5), nb-1-2-3-4.newark.nodebalancer.linode.com
+
+defaults
+    timeout connect 5s
+    timeout client 50s
+    timeout server 50s
+
+frontend port_80
+    bind *:80
+    mode tcp
+    stick-table type ip size 100k expire 10s store conn_rate(1s)
+    tcp-request connection track-sc0 src
+    tcp-request connection reject if { sc_conn_rate(0) gt 10 }
+    default_backend port_80
+
+backend port_80
+    mode tcp
+    balance source
+    stick-table type ip size 200k expire 30m
+    stick on src
+    server app_2 192.168.1.2:80 weight 0 check
+    server app1 192.168.1.1:80 weight 100 check
+
+frontend port_443
+    bind *:443 ssl crt web_443.pem
+    mode http
+    option forwardfor
+    http-request set-header X-Forwarded-Proto https
+    stick-table type ip size 100k expire 10s store conn_rate(1s)
+    tcp-request connection track-sc0 src
+    tcp-request connection reject if { sc_conn_rate(0) gt 10 }
+    default_backend port_443
+
+backend port_443
+    mode http
+    balance leastconn
+    cookie NB_SRVID insert indirect nocache
+    option httpchk GET /health
+    http-check expect rstring "all good"
+    timeout check 3s
+    server app1 192.168.1.1:80 weight 50 disabled cookie app1 check inter 5s fall 2 observe layer7 error-limit 1 on-error mark-down
+    server app1_2 192.168.1.3:80 weight 50 cookie app1_2 check inter 5s fall 2 observe layer7 error-limit 1 on-error mark-down
+`
+	assert.Equal(t, want, out)
+
+	var got []string
+	for _, i := range issues {
+		got = append(got, i.String())
+	}
+	assert.Equal(t, []string{
+		"port 443: certificate for www.example.com (AA:BB) must be supplied as web_443.pem",
+		`port 443: node app1: unknown mode "sideways", treated as accept`,
+		`port 8080: unknown protocol "udp", config skipped`,
+	}, got)
+}
+
+func TestFormatHAProxyIssues(t *testing.T) {
+	configs := []NodeBalancerConfig{
+		{ID: 10, Port: 80, Protocol: "tcp", Stickiness: "http_cookie", Check: "none", CheckPassive: true},
+		{ID: 11, Port: 81, Protocol: "http", Algorithm: "random", Stickiness: "forever", Check: "ping"},
+	}
+
+	out, issues := FormatHAProxy(NodeBalancer{ID: 5, Label: "web"}, configs, nil)
+	assert.NotContains(t, out, "cookie")
+	assert.NotContains(t, out, "observe")
+	assert.Len(t, issues, 5)
+}
+
+func TestExportHAProxy(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web"}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80,"PROTOCOL":"http","ALGORITHM":"roundrobin","CHECK":"http","CHECK_PATH":"/"}],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[{"NODEID":20,"CONFIGID":10,"LABEL":"app1","ADDRESS":"192.168.1.1:80","WEIGHT":100,"MODE":"accept"}],"ACTION":"nodebalancer.node.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{"NodeBalancerID": "6"},
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"nodebalancer.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	out, issues, err := c.ExportHAProxy(5)
+	require.NoError(t, err)
+	assert.Len(t, issues, 0)
+	assert.True(t, strings.HasSuffix(out, "    option httpchk GET /\n    server app1 192.168.1.1:80 weight 100 check\n"), out)
+
+	_, _, err = c.ExportHAProxy(6)
+	assert.Error(t, err)
+}
@@ -0,0 +1,411 @@
+package linode
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for NodeMonitor.
+const (
+	DefaultNodeMonitorInterval = time.Minute
+	DefaultNodeDownAfter       = 2
+	DefaultNodeFlapTransitions = 4
+	DefaultNodeFlapWindow      = 15 * time.Minute
+	DefaultNodeMinHealthy      = 1
+)
+
+// NodeHealthEventType identifies the kind of change a NodeHealthEvent
+// describes.
+type NodeHealthEventType string
+
+// Event types sent by NodeMonitor.
+const (
+	NodeHealthDown      NodeHealthEventType = "node_down"
+	NodeHealthUp        NodeHealthEventType = "node_up"
+	NodeHealthFlapping  NodeHealthEventType = "node_flapping"
+	NodeHealthStable    NodeHealthEventType = "node_stable"
+	NodeHealthDegraded  NodeHealthEventType = "config_degraded"
+	NodeHealthRecovered NodeHealthEventType = "config_recovered"
+	NodeHealthError     NodeHealthEventType = "error"
+)
+
+const (
+	nodeStatusUp      = "up"
+	nodeStatusDown    = "down"
+	nodeStatusUnknown = "unknown"
+)
+
+// NodeHealthEvent is a single change observed by NodeMonitor.
+type NodeHealthEvent struct {
+	Type         NodeHealthEventType
+	Time         time.Time
+	NodeBalancer NodeBalancer
+	Config       NodeBalancerConfig
+
+	// Node is set for node events.
+	Node NodeBalancerNode
+
+	// Healthy and Total count the config's UP nodes and all of its nodes,
+	// for config events.
+	Healthy int
+	Total   int
+
+	// Transitions is the number of times the node went UP or DOWN within
+	// the flap window, for NodeHealthFlapping.
+	Transitions int
+
+	// Err is set for NodeHealthError.  Monitoring continues after an error.
+	Err error
+}
+
+// NodeHealthThresholds tune when NodeMonitor sends events.  Zero fields use
+// the monitor's defaults.
+type NodeHealthThresholds struct {
+	// DownAfter is how many polls in a row a node must be DOWN before
+	// NodeHealthDown is sent.
+	DownAfter int
+
+	// A node that goes UP or DOWN FlapTransitions times within FlapWindow
+	// is flapping.  NodeHealthDown and NodeHealthUp are not sent for a
+	// flapping node until it is stable again.
+	FlapTransitions int
+	FlapWindow      time.Duration
+
+	// A config with fewer than MinHealthy nodes UP is degraded.
+	MinHealthy int
+}
+
+// NodeMonitorStats is a snapshot of the nodes seen by the last poll.
+type NodeMonitorStats struct {
+	NodeBalancers int
+	Configs       int
+	Nodes         int
+	Up            int
+	Down          int
+	Unknown       int
+	Flapping      int
+	Degraded      int
+
+	Polls    int
+	Errors   int
+	LastPoll time.Time
+}
+
+// NodeMonitor periodically walks every NodeBalancer, config, and node on the
+// account, tracks the status each node reports, and sends events when they
+// change.  It should be created by a call to NewNodeMonitor().
+//
+// Nodes whose status is neither UP nor DOWN are not counted either way, so a
+// config whose nodes are all unknown, such as one without health checks, is
+// never degraded.  Nodes and configs that are already unhealthy when first
+// seen are reported, but a node that is UP is only reported after it was
+// reported DOWN.
+type NodeMonitor struct {
+	Interval time.Duration
+
+	// Thresholds apply to every NodeBalancer, and NodeBalancerThresholds
+	// override them by NodeBalancer ID.
+	Thresholds             NodeHealthThresholds
+	NodeBalancerThresholds map[int]NodeHealthThresholds
+
+	// OnEvent, if set, is called with each event, in the order the nodes
+	// are listed.
+	OnEvent func(e NodeHealthEvent)
+
+	c       *Client
+	now     func() time.Time
+	mu      sync.Mutex
+	nodes   map[int]*nodeHealth
+	configs map[int]bool
+	stats   NodeMonitorStats
+}
+
+type nodeHealth struct {
+	status      string
+	known       string
+	downPolls   int
+	down        bool
+	flapping    bool
+	transitions []time.Time
+}
+
+// NewNodeMonitor returns a NodeMonitor with the default thresholds.
+func (c *Client) NewNodeMonitor() *NodeMonitor {
+	return &NodeMonitor{
+		Interval: DefaultNodeMonitorInterval,
+		Thresholds: NodeHealthThresholds{
+			DownAfter:       DefaultNodeDownAfter,
+			FlapTransitions: DefaultNodeFlapTransitions,
+			FlapWindow:      DefaultNodeFlapWindow,
+			MinHealthy:      DefaultNodeMinHealthy,
+		},
+		c:       c,
+		now:     time.Now,
+		nodes:   make(map[int]*nodeHealth),
+		configs: make(map[int]bool),
+	}
+}
+
+// Run polls every Interval until ctx is done.  Errors are sent as
+// NodeHealthError events.
+func (m *NodeMonitor) Run(ctx context.Context) error {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultNodeMonitorInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := m.CheckOnce(ctx)
+		if err != nil && ctx.Err() == nil && m.OnEvent != nil {
+			m.OnEvent(NodeHealthEvent{Type: NodeHealthError, Time: m.now(), Err: err})
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// CheckOnce polls every node once and sends the resulting events.  Nodes
+// that cannot be listed keep their previous state, and the first such error
+// is returned after the others are checked.  If ctx is done partway through,
+// the events for the nodes already checked are still sent.
+func (m *NodeMonitor) CheckOnce(ctx context.Context) error {
+	m.mu.Lock()
+	events, err := m.poll(ctx)
+	m.mu.Unlock()
+
+	// Events are sent without the lock, so that OnEvent may call Stats().
+	if m.OnEvent != nil {
+		for _, e := range events {
+			m.OnEvent(e)
+		}
+	}
+	return err
+}
+
+// poll walks the nodes once, updating their state, and returns the events
+// to send, including those for the nodes walked before ctx was done.  m.mu
+// must be held.
+func (m *NodeMonitor) poll(ctx context.Context) ([]NodeHealthEvent, error) {
+	now := m.now()
+	m.stats.Polls++
+
+	nbs, err := m.c.NodeBalancerList(nil)
+	if err != nil {
+		m.stats.Errors++
+		return nil, err
+	}
+
+	var firstErr error
+	stats := NodeMonitorStats{NodeBalancers: len(nbs)}
+	seenNodes := make(map[int]bool)
+	seenConfigs := make(map[int]bool)
+	var events []NodeHealthEvent
+	fail := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, nb := range nbs {
+		if ctx.Err() != nil {
+			// The nodes already walked have changed state, so their events
+			// must not be lost.
+			return events, ctx.Err()
+		}
+
+		th := m.thresholds(nb.ID)
+		configs, err := m.c.NodeBalancerConfigList(nb.ID, nil)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		for _, conf := range configs {
+			stats.Configs++
+			seenConfigs[conf.ID] = true
+
+			nodes, err := m.c.NodeBalancerNodeList(conf.ID, nil)
+			if err != nil {
+				fail(err)
+				continue
+			}
+
+			healthy, known := 0, 0
+			for _, n := range nodes {
+				seenNodes[n.ID] = true
+				e := NodeHealthEvent{Time: now, NodeBalancer: nb, Config: conf, Node: n}
+
+				h := m.nodes[n.ID]
+				if h == nil {
+					h = &nodeHealth{}
+					m.nodes[n.ID] = h
+				}
+				for _, t := range h.observe(nodeStatus(n.Status), now, th) {
+					e.Type = t
+					e.Transitions = len(h.transitions)
+					events = append(events, e)
+				}
+
+				stats.Nodes++
+				switch h.status {
+				case nodeStatusUp:
+					stats.Up++
+					healthy++
+					known++
+				case nodeStatusDown:
+					stats.Down++
+					known++
+				default:
+					stats.Unknown++
+				}
+				if h.flapping {
+					stats.Flapping++
+				}
+			}
+
+			degraded := known > 0 && healthy < th.MinHealthy
+			if degraded != m.configs[conf.ID] {
+				e := NodeHealthEvent{Time: now, NodeBalancer: nb, Config: conf, Healthy: healthy, Total: len(nodes)}
+				e.Type = NodeHealthRecovered
+				if degraded {
+					e.Type = NodeHealthDegraded
+				}
+				events = append(events, e)
+			}
+			m.configs[conf.ID] = degraded
+			if degraded {
+				stats.Degraded++
+			}
+		}
+	}
+
+	// Forget nodes and configs that were deleted, unless some were not
+	// listed.
+	if firstErr == nil {
+		for id := range m.nodes {
+			if !seenNodes[id] {
+				delete(m.nodes, id)
+			}
+		}
+		for id := range m.configs {
+			if !seenConfigs[id] {
+				delete(m.configs, id)
+			}
+		}
+	}
+
+	stats.Polls = m.stats.Polls
+	stats.Errors = m.stats.Errors
+	if firstErr != nil {
+		stats.Errors++
+	}
+	stats.LastPoll = now
+	m.stats = stats
+
+	return events, firstErr
+}
+
+// Stats returns a snapshot of the last poll.
+func (m *NodeMonitor) Stats() NodeMonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// thresholds returns the thresholds for a NodeBalancer, with zero fields
+// filled in.
+func (m *NodeMonitor) thresholds(nbID int) NodeHealthThresholds {
+	th := m.NodeBalancerThresholds[nbID]
+	if th.DownAfter <= 0 {
+		th.DownAfter = m.Thresholds.DownAfter
+	}
+	if th.FlapTransitions <= 0 {
+		th.FlapTransitions = m.Thresholds.FlapTransitions
+	}
+	if th.FlapWindow <= 0 {
+		th.FlapWindow = m.Thresholds.FlapWindow
+	}
+	if th.MinHealthy <= 0 {
+		th.MinHealthy = m.Thresholds.MinHealthy
+	}
+
+	if th.DownAfter <= 0 {
+		th.DownAfter = DefaultNodeDownAfter
+	}
+	if th.FlapTransitions <= 0 {
+		th.FlapTransitions = DefaultNodeFlapTransitions
+	}
+	if th.FlapWindow <= 0 {
+		th.FlapWindow = DefaultNodeFlapWindow
+	}
+	if th.MinHealthy <= 0 {
+		th.MinHealthy = DefaultNodeMinHealthy
+	}
+	return th
+}
+
+// observe records a node's status at now, and returns the events it causes.
+func (h *nodeHealth) observe(status string, now time.Time, th NodeHealthThresholds) []NodeHealthEventType {
+	var events []NodeHealthEventType
+
+	// Only changes between UP and DOWN count towards flapping.
+	if status != nodeStatusUnknown {
+		if h.known != "" && h.known != status {
+			h.transitions = append(h.transitions, now)
+		}
+		h.known = status
+	}
+	h.status = status
+
+	cutoff := now.Add(-th.FlapWindow)
+	for len(h.transitions) > 0 && !h.transitions[0].After(cutoff) {
+		h.transitions = h.transitions[1:]
+	}
+	flapping := len(h.transitions) >= th.FlapTransitions
+	if flapping != h.flapping {
+		h.flapping = flapping
+		if flapping {
+			events = append(events, NodeHealthFlapping)
+		} else {
+			events = append(events, NodeHealthStable)
+		}
+	}
+
+	if status == nodeStatusDown {
+		h.downPolls++
+	} else {
+		h.downPolls = 0
+	}
+
+	if h.flapping {
+		return events
+	}
+	switch {
+	case !h.down && h.downPolls >= th.DownAfter:
+		h.down = true
+		events = append(events, NodeHealthDown)
+	case h.down && status == nodeStatusUp:
+		h.down = false
+		events = append(events, NodeHealthUp)
+	}
+	return events
+}
+
+func nodeStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "up":
+		return nodeStatusUp
+	case "down":
+		return nodeStatusDown
+	}
+	return nodeStatusUnknown
+}
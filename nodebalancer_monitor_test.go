@@ -0,0 +1,205 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+// mockMonitorPoll returns the responses for one poll of a NodeBalancer with
+// one config and a node per status.
+func mockMonitorPoll(statuses ...string) []mockAPIResponse {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web"}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80}],"ACTION":"nodebalancer.config.list"}`))
+
+	nodes := ""
+	for i, status := range statuses {
+		if i > 0 {
+			nodes += ","
+		}
+		nodes += fmt.Sprintf(`{"NODEID":%d,"CONFIGID":10,"LABEL":"app%d","STATUS":%q}`, 20+i, i, status)
+	}
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[`+nodes+`],"ACTION":"nodebalancer.node.list"}`))
+	return responses
+}
+
+type monitorEvents []NodeHealthEvent
+
+func (e *monitorEvents) add(ev NodeHealthEvent) {
+	*e = append(*e, ev)
+}
+
+// take returns the types of the events so far, with the node or config ID,
+// and clears them.
+func (e *monitorEvents) take() []string {
+	var out []string
+	for _, ev := range *e {
+		id := ev.Node.ID
+		if id == 0 {
+			id = ev.Config.ID
+		}
+		out = append(out, fmt.Sprintf("%s %d", ev.Type, id))
+	}
+	*e = nil
+	return out
+}
+
+func TestNodeMonitor(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockMonitorPoll("UP", "DOWN")...)
+	responses = append(responses, mockMonitorPoll("DOWN", "DOWN")...)
+	responses = append(responses, mockMonitorPoll("DOWN", "UP")...)
+	responses = append(responses, mockMonitorPoll("Unknown", "UP")...)
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	m := c.NewNodeMonitor()
+	var events monitorEvents
+	m.OnEvent = events.add
+	ctx := context.Background()
+
+	// app1 needs two DOWN polls.
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Nil(t, events.take())
+
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Equal(t, []string{"node_down 21", "config_degraded 10"}, events.take())
+	stats := m.Stats()
+	assert.Equal(t, 1, stats.Degraded)
+	assert.Equal(t, 2, stats.Down)
+
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Equal(t, []string{"node_down 20", "node_up 21", "config_recovered 10"}, events.take())
+
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Nil(t, events.take())
+	stats = m.Stats()
+	assert.Equal(t, NodeMonitorStats{NodeBalancers: 1, Configs: 1, Nodes: 2, Up: 1, Unknown: 1,
+		Polls: 4, LastPoll: stats.LastPoll}, stats)
+}
+
+func TestNodeMonitorFlapping(t *testing.T) {
+	var responses []mockAPIResponse
+	for _, status := range []string{"UP", "DOWN", "UP", "DOWN", "DOWN"} {
+		responses = append(responses, mockMonitorPoll(status, "UP")...)
+	}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	now := time.Now()
+	m := c.NewNodeMonitor()
+	m.now = func() time.Time { return now }
+	m.NodeBalancerThresholds = map[int]NodeHealthThresholds{
+		5: {DownAfter: 1, FlapTransitions: 3, FlapWindow: time.Hour},
+	}
+	var events monitorEvents
+	m.OnEvent = events.add
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		require.NoError(t, m.CheckOnce(ctx))
+	}
+	// The third transition is reported as flapping, not down.
+	assert.Equal(t, []string{"node_down 20", "node_up 20", "node_flapping 20"}, events.take())
+	assert.Equal(t, 1, m.Stats().Flapping)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Equal(t, []string{"node_stable 20", "node_down 20"}, events.take())
+}
+
+func TestNodeMonitorErrors(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockMonitorPoll("DOWN")...)
+	responses = append(responses, mockMonitorPoll("DOWN")[:2]...)
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[{"ERRORCODE":8,"ERRORMESSAGE":"Temporary failure"}],"DATA":{},"ACTION":"nodebalancer.node.list"}`))
+	responses = append(responses, mockMonitorPoll("DOWN")...)
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	m := c.NewNodeMonitor()
+	var events monitorEvents
+	m.OnEvent = events.add
+	ctx := context.Background()
+
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Error(t, m.CheckOnce(ctx))
+	assert.Equal(t, 1, m.Stats().Errors)
+
+	// The failed poll neither counted nor reset the node's DOWN polls.
+	require.NoError(t, m.CheckOnce(ctx))
+	assert.Equal(t, []string{"config_degraded 10", "node_down 20"}, events.take())
+
+	m = c.NewNodeMonitor()
+	c.apiCall = apiCallerError
+	assert.Error(t, m.CheckOnce(ctx))
+	assert.Equal(t, NodeMonitorStats{Polls: 1, Errors: 1}, m.Stats())
+}
+
+func TestNodeMonitorCancel(t *testing.T) {
+	responses := []mockAPIResponse{
+		newMockAPIResponse("nodebalancer.list", map[string]string{},
+			`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web"},{"NODEBALANCERID":6,"LABEL":"api"}],"ACTION":"nodebalancer.list"}`),
+	}
+	responses = append(responses, mockMonitorPoll("DOWN")[1:]...)
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// Cancel once the first NodeBalancer's nodes are listed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	call := c.apiCall
+	c.apiCall = func(action string, params map[string]interface{}) (json.RawMessage, error) {
+		out, err := call(action, params)
+		if action == "nodebalancer.node.list" {
+			cancel()
+		}
+		return out, err
+	}
+
+	m := c.NewNodeMonitor()
+	m.NodeBalancerThresholds = map[int]NodeHealthThresholds{5: {DownAfter: 1}}
+	var events monitorEvents
+	m.OnEvent = events.add
+
+	// The node was marked down, so its event is sent.
+	assert.Equal(t, context.Canceled, m.CheckOnce(ctx))
+	assert.Equal(t, []string{"node_down 20", "config_degraded 10"}, events.take())
+}
+
+func TestNodeMonitorStatsFromEvent(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockMonitorPoll("DOWN")))
+	defer ts.Close()
+
+	m := c.NewNodeMonitor()
+	var degraded []int
+	m.OnEvent = func(e NodeHealthEvent) {
+		degraded = append(degraded, m.Stats().Degraded)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.CheckOnce(context.Background()) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("CheckOnce deadlocked calling OnEvent")
+	}
+	assert.Equal(t, []int{1}, degraded)
+}
@@ -0,0 +1,710 @@
+package linode
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NodeBalancerChange is a single change in a NodeBalancerPlan.
+type NodeBalancerChange struct {
+	Kind ChangeKind
+
+	// Resource is "nodebalancer", "config", or "node".
+	Resource string
+
+	// Port is the config being changed, and Node the label of the node, if
+	// any.
+	NodeBalancer string
+	Port         int
+	Node         string
+
+	Diffs []FieldDiff
+
+	apply func(*nbApplier) error
+}
+
+func (nc NodeBalancerChange) String() string {
+	name := nc.NodeBalancer
+	if nc.Port != 0 {
+		name += ":" + strconv.Itoa(nc.Port)
+	}
+	if nc.Node != "" {
+		name += "/" + nc.Node
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s %s", changeSymbols[nc.Kind], nc.Resource, name)
+	for _, d := range nc.Diffs {
+		fmt.Fprintf(&buf, "\n    %s: %q -> %q", d.Field, d.Old, d.New)
+	}
+
+	return buf.String()
+}
+
+// NodeBalancerPlan is the set of changes needed to bring a NodeBalancer in
+// line with a NodeBalancerSpec.  It should be created by a call to
+// PlanNodeBalancer().
+type NodeBalancerPlan struct {
+	Label   string
+	Changes []NodeBalancerChange
+
+	nbID      int
+	configIDs map[int]int
+}
+
+// Empty returns true if the plan has no changes.
+func (p *NodeBalancerPlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *NodeBalancerPlan) String() string {
+	if p.Empty() {
+		return "No changes.\n"
+	}
+
+	var buf bytes.Buffer
+	for _, c := range p.Changes {
+		buf.WriteString(c.String())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+type nbApplier struct {
+	c         *Client
+	nbID      int
+	configIDs map[int]int
+}
+
+// PlanNodeBalancer compares spec with the NodeBalancer of the same label, and
+// returns the smallest set of changes needed to make them match.  The
+// NodeBalancer is created if it does not exist.  Configs are matched by port
+// and nodes by label; configs and nodes missing from spec are deleted.
+//
+// Fields left empty in spec are not managed, except CheckPassive, which is
+// always set.  Since the API does not return certificates, SSLCert is
+// compared with the config's certificate fingerprint, and is only sent along
+// with SSLKey when it differs.  Certificates are checked with
+// ValidateSSLCert() first.
+//
+// Nodes backed by a Linode are resolved to its private IP.  Nothing is
+// changed until the plan is passed to ApplyNodeBalancer().
+func (c *Client) PlanNodeBalancer(spec NodeBalancerSpec) (*NodeBalancerPlan, error) {
+	err := validateNodeBalancerSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := c.nodeAddresses(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	nbs, err := c.NodeBalancerList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nb *NodeBalancer
+	for i := range nbs {
+		if nbs[i].Label == spec.Label {
+			if nb != nil {
+				return nil, fmt.Errorf("nodebalancer: more than one NodeBalancer is labeled %q", spec.Label)
+			}
+			nb = &nbs[i]
+		}
+	}
+
+	plan := &NodeBalancerPlan{Label: spec.Label, configIDs: make(map[int]int)}
+
+	if nb == nil {
+		plan.Changes = append(plan.Changes, planNodeBalancerCreate(spec))
+		for _, cs := range spec.Configs {
+			cc, err := planNBConfigCreate(spec.Label, cs, addrs)
+			if err != nil {
+				return nil, err
+			}
+			plan.Changes = append(plan.Changes, cc...)
+		}
+		return plan, nil
+	}
+
+	plan.nbID = nb.ID
+	if nb.DatacenterID != spec.DatacenterID {
+		return nil, fmt.Errorf("nodebalancer: %s is in datacenter %d, not %d, and cannot be moved",
+			spec.Label, nb.DatacenterID, spec.DatacenterID)
+	}
+	if spec.Throttle != 0 && spec.Throttle != nb.Throttle {
+		id := nb.ID
+		throttle := spec.Throttle
+		plan.Changes = append(plan.Changes, NodeBalancerChange{
+			Kind:         ChangeUpdate,
+			Resource:     "nodebalancer",
+			NodeBalancer: spec.Label,
+			Diffs:        []FieldDiff{intDiff("throttle", nb.Throttle, spec.Throttle)},
+			apply: func(a *nbApplier) error {
+				return a.c.NodeBalancerUpdate(id, nil, Int(throttle))
+			},
+		})
+	}
+
+	configs, err := c.NodeBalancerConfigList(nb.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+	byPort := make(map[int]NodeBalancerConfig)
+	for _, conf := range configs {
+		byPort[conf.Port] = conf
+		plan.configIDs[conf.Port] = conf.ID
+	}
+
+	var creates, deletes []NodeBalancerChange
+	wanted := make(map[int]bool)
+	for _, cs := range spec.Configs {
+		wanted[cs.Port] = true
+
+		conf, ok := byPort[cs.Port]
+		if !ok {
+			cc, err := planNBConfigCreate(spec.Label, cs, addrs)
+			if err != nil {
+				return nil, err
+			}
+			creates = append(creates, cc...)
+			continue
+		}
+
+		if nc, ok, err := planNBConfigUpdate(spec.Label, cs, conf); err != nil {
+			return nil, err
+		} else if ok {
+			plan.Changes = append(plan.Changes, nc)
+		}
+
+		nodes, err := c.NodeBalancerNodeList(conf.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		nc, err := planNBNodes(spec.Label, cs, nodes, addrs)
+		if err != nil {
+			return nil, err
+		}
+		plan.Changes = append(plan.Changes, nc...)
+	}
+
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Port < configs[j].Port })
+	for _, conf := range configs {
+		if wanted[conf.Port] {
+			continue
+		}
+		nbID, confID := nb.ID, conf.ID
+		deletes = append(deletes, NodeBalancerChange{
+			Kind:         ChangeDelete,
+			Resource:     "config",
+			NodeBalancer: spec.Label,
+			Port:         conf.Port,
+			apply: func(a *nbApplier) error {
+				return a.c.NodeBalancerConfigDelete(nbID, confID)
+			},
+		})
+	}
+
+	// Ports are freed before they are reused.
+	plan.Changes = append(plan.Changes, deletes...)
+	plan.Changes = append(plan.Changes, creates...)
+
+	return plan, nil
+}
+
+func validateNodeBalancerSpec(spec NodeBalancerSpec) error {
+	if spec.Label == "" {
+		return errors.New("nodebalancer: label is required")
+	}
+	if spec.DatacenterID == 0 {
+		return fmt.Errorf("nodebalancer: %s: datacenter_id is required", spec.Label)
+	}
+
+	ports := make(map[int]bool)
+	for _, cs := range spec.Configs {
+		if cs.Port < 1 || cs.Port > 65535 {
+			return fmt.Errorf("nodebalancer: %s: invalid port %d", spec.Label, cs.Port)
+		}
+		if ports[cs.Port] {
+			return fmt.Errorf("nodebalancer: %s: duplicate port %d", spec.Label, cs.Port)
+		}
+		ports[cs.Port] = true
+
+		switch strings.ToLower(cs.Protocol) {
+		case "http", "tcp":
+		case "https":
+			if (cs.SSLCert == "") != (cs.SSLKey == "") {
+				return fmt.Errorf("nodebalancer: %s:%d: ssl_cert and ssl_key must be set together",
+					spec.Label, cs.Port)
+			}
+			if cs.SSLCert != "" {
+				_, err := ValidateSSLCert(cs.SSLCert, cs.SSLKey, SSLValidateOpts{})
+				if err != nil {
+					return fmt.Errorf("nodebalancer: %s:%d: %s", spec.Label, cs.Port,
+						strings.TrimPrefix(err.Error(), "nodebalancer: "))
+				}
+			}
+		default:
+			return fmt.Errorf("nodebalancer: %s:%d: invalid protocol %q", spec.Label, cs.Port, cs.Protocol)
+		}
+
+		labels := make(map[string]bool)
+		for _, ns := range cs.Nodes {
+			if ns.Label == "" || labels[ns.Label] {
+				return fmt.Errorf("nodebalancer: %s:%d: node labels must be unique and non-empty",
+					spec.Label, cs.Port)
+			}
+			labels[ns.Label] = true
+
+			if (ns.Address == "") == (ns.Linode == "") {
+				return fmt.Errorf("nodebalancer: %s:%d: node %s needs exactly one of address and linode",
+					spec.Label, cs.Port, ns.Label)
+			}
+			if ns.Linode != "" && (ns.Port < 1 || ns.Port > 65535) {
+				return fmt.Errorf("nodebalancer: %s:%d: node %s needs a port",
+					spec.Label, cs.Port, ns.Label)
+			}
+		}
+	}
+
+	return nil
+}
+
+// nodeAddresses resolves the Linode-backed nodes in spec to the private IP
+// of each Linode, keyed by Linode label.
+func (c *Client) nodeAddresses(spec NodeBalancerSpec) (map[string]string, error) {
+	needed := false
+	for _, cs := range spec.Configs {
+		for _, ns := range cs.Nodes {
+			if ns.Linode != "" {
+				needed = true
+			}
+		}
+	}
+	if !needed {
+		return nil, nil
+	}
+
+	linodes, err := c.LinodeList(nil)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := c.LinodeIPList(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	private := make(map[int]string)
+	for _, ip := range ips {
+		if !ip.IsPublic {
+			private[ip.LinodeID] = ip.Address
+		}
+	}
+
+	addrs := make(map[string]string)
+	for _, l := range linodes {
+		if ip, ok := private[l.ID]; ok {
+			addrs[l.Label] = ip
+		}
+	}
+
+	return addrs, nil
+}
+
+// nodeAddress returns the "ip:port" address of a node.
+func nodeAddress(ns NodeBalancerNodeSpec, addrs map[string]string) (string, error) {
+	if ns.Address != "" {
+		return ns.Address, nil
+	}
+	ip, ok := addrs[ns.Linode]
+	if !ok {
+		return "", fmt.Errorf("nodebalancer: node %s: Linode %q does not exist or has no private IP",
+			ns.Label, ns.Linode)
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(ns.Port)), nil
+}
+
+func planNodeBalancerCreate(spec NodeBalancerSpec) NodeBalancerChange {
+	diffs := []FieldDiff{intDiff("datacenter_id", 0, spec.DatacenterID)}
+	var throttle *int
+	if spec.Throttle != 0 {
+		throttle = Int(spec.Throttle)
+		diffs = append(diffs, intDiff("throttle", 0, spec.Throttle))
+	}
+
+	dc, label := spec.DatacenterID, spec.Label
+	return NodeBalancerChange{
+		Kind:         ChangeCreate,
+		Resource:     "nodebalancer",
+		NodeBalancer: spec.Label,
+		Diffs:        diffs,
+		apply: func(a *nbApplier) error {
+			id, err := a.c.NodeBalancerCreate(dc, String(label), throttle)
+			if err != nil {
+				return err
+			}
+			a.nbID = id
+			return nil
+		},
+	}
+}
+
+// planNBConfigCreate creates a config and all of its nodes.
+func planNBConfigCreate(nb string, cs NodeBalancerConfigSpec,
+	addrs map[string]string) ([]NodeBalancerChange, error) {
+
+	if strings.EqualFold(cs.Protocol, "https") && cs.SSLCert == "" {
+		return nil, fmt.Errorf("nodebalancer: %s:%d: https configs need ssl_cert and ssl_key", nb, cs.Port)
+	}
+
+	var opts NodeBalancerConfigCreateOpts
+	opts.Port = Int(cs.Port)
+	diffs := []FieldDiff{intDiff("port", 0, cs.Port)}
+
+	setString := func(field string, p **string, v string) {
+		if v != "" {
+			*p = String(v)
+			diffs = append(diffs, FieldDiff{field, "", v})
+		}
+	}
+	setInt := func(field string, p **int, v int) {
+		if v != 0 {
+			*p = Int(v)
+			diffs = append(diffs, intDiff(field, 0, v))
+		}
+	}
+
+	setString("protocol", &opts.Protocol, strings.ToLower(cs.Protocol))
+	setString("algorithm", &opts.Algorithm, strings.ToLower(cs.Algorithm))
+	setString("stickiness", &opts.Stickiness, strings.ToLower(cs.Stickiness))
+	setString("check", &opts.Check, strings.ToLower(cs.Check))
+	setInt("check_interval", &opts.CheckInterval, cs.CheckInterval)
+	setInt("check_timeout", &opts.CheckTimeout, cs.CheckTimeout)
+	setInt("check_attempts", &opts.CheckAttempts, cs.CheckAttempts)
+	setString("check_path", &opts.CheckPath, cs.CheckPath)
+	setString("check_body", &opts.CheckBody, cs.CheckBody)
+	opts.CheckPassive = Bool(cs.CheckPassive)
+	if cs.CheckPassive {
+		diffs = append(diffs, boolDiff("check_passive", false, true))
+	}
+	if cs.SSLCert != "" {
+		fp, err := certFingerprint(cs.SSLCert)
+		if err != nil {
+			return nil, fmt.Errorf("nodebalancer: %s:%d: %s", nb, cs.Port, err)
+		}
+		opts.SSLCert = String(cs.SSLCert)
+		opts.SSLKey = String(cs.SSLKey)
+		diffs = append(diffs, FieldDiff{"ssl_cert", "", fp})
+	}
+
+	port := cs.Port
+	changes := []NodeBalancerChange{{
+		Kind:         ChangeCreate,
+		Resource:     "config",
+		NodeBalancer: nb,
+		Port:         cs.Port,
+		Diffs:        diffs,
+		apply: func(a *nbApplier) error {
+			id, err := a.c.NodeBalancerConfigCreate(a.nbID, opts)
+			if err != nil {
+				return err
+			}
+			a.configIDs[port] = id
+			return nil
+		},
+	}}
+
+	for _, ns := range cs.Nodes {
+		nc, err := planNBNodeCreate(nb, port, ns, addrs)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, nc)
+	}
+
+	return changes, nil
+}
+
+func planNBConfigUpdate(nb string, cs NodeBalancerConfigSpec,
+	conf NodeBalancerConfig) (NodeBalancerChange, bool, error) {
+
+	var opts NodeBalancerConfigUpdateOpts
+	var diffs []FieldDiff
+
+	setString := func(field string, p **string, old string, v string) {
+		if v != "" && !strings.EqualFold(v, old) {
+			*p = String(v)
+			diffs = append(diffs, FieldDiff{field, old, v})
+		}
+	}
+	setInt := func(field string, p **int, old int, v int) {
+		if v != 0 && v != old {
+			*p = Int(v)
+			diffs = append(diffs, intDiff(field, old, v))
+		}
+	}
+
+	setString("protocol", &opts.Protocol, conf.Protocol, strings.ToLower(cs.Protocol))
+	setString("algorithm", &opts.Algorithm, conf.Algorithm, strings.ToLower(cs.Algorithm))
+	setString("stickiness", &opts.Stickiness, conf.Stickiness, strings.ToLower(cs.Stickiness))
+	setString("check", &opts.Check, conf.Check, strings.ToLower(cs.Check))
+	setInt("check_interval", &opts.CheckInterval, conf.CheckInterval, cs.CheckInterval)
+	setInt("check_timeout", &opts.CheckTimeout, conf.CheckTimeout, cs.CheckTimeout)
+	setInt("check_attempts", &opts.CheckAttempts, conf.CheckAttempts, cs.CheckAttempts)
+	if cs.CheckPath != "" && cs.CheckPath != conf.CheckPath {
+		opts.CheckPath = String(cs.CheckPath)
+		diffs = append(diffs, FieldDiff{"check_path", conf.CheckPath, cs.CheckPath})
+	}
+	if cs.CheckBody != "" && cs.CheckBody != conf.CheckBody {
+		opts.CheckBody = String(cs.CheckBody)
+		diffs = append(diffs, FieldDiff{"check_body", conf.CheckBody, cs.CheckBody})
+	}
+	if cs.CheckPassive != conf.CheckPassive {
+		opts.CheckPassive = Bool(cs.CheckPassive)
+		diffs = append(diffs, boolDiff("check_passive", conf.CheckPassive, cs.CheckPassive))
+	}
+
+	if strings.EqualFold(cs.Protocol, "https") {
+		if cs.SSLCert == "" && conf.SSLFingerprint == "" {
+			return NodeBalancerChange{}, false,
+				fmt.Errorf("nodebalancer: %s:%d: https configs need ssl_cert and ssl_key", nb, cs.Port)
+		}
+		if cs.SSLCert != "" {
+			fp, err := certFingerprint(cs.SSLCert)
+			if err != nil {
+				return NodeBalancerChange{}, false, fmt.Errorf("nodebalancer: %s:%d: %s", nb, cs.Port, err)
+			}
+			same, err := certMatchesFingerprint(cs.SSLCert, conf.SSLFingerprint)
+			if err != nil {
+				return NodeBalancerChange{}, false, fmt.Errorf("nodebalancer: %s:%d: %s", nb, cs.Port, err)
+			}
+			if !same {
+				opts.SSLCert = String(cs.SSLCert)
+				opts.SSLKey = String(cs.SSLKey)
+				diffs = append(diffs, FieldDiff{"ssl_cert", conf.SSLFingerprint, fp})
+			}
+		}
+	}
+
+	if len(diffs) == 0 {
+		return NodeBalancerChange{}, false, nil
+	}
+
+	id := conf.ID
+	return NodeBalancerChange{
+		Kind:         ChangeUpdate,
+		Resource:     "config",
+		NodeBalancer: nb,
+		Port:         cs.Port,
+		Diffs:        diffs,
+		apply: func(a *nbApplier) error {
+			return a.c.NodeBalancerConfigUpdate(id, opts)
+		},
+	}, true, nil
+}
+
+// planNBNodes matches the nodes of an existing config by label.  Deletes go
+// first, so that addresses are freed before they are reused.
+func planNBNodes(nb string, cs NodeBalancerConfigSpec, nodes []NodeBalancerNode,
+	addrs map[string]string) ([]NodeBalancerChange, error) {
+
+	byLabel := make(map[string]NodeBalancerNode)
+	for _, n := range nodes {
+		byLabel[n.Label] = n
+	}
+
+	var deletes, updates, creates []NodeBalancerChange
+	wanted := make(map[string]bool)
+	for _, ns := range cs.Nodes {
+		wanted[ns.Label] = true
+
+		n, ok := byLabel[ns.Label]
+		if !ok {
+			nc, err := planNBNodeCreate(nb, cs.Port, ns, addrs)
+			if err != nil {
+				return nil, err
+			}
+			creates = append(creates, nc)
+			continue
+		}
+
+		addr, err := nodeAddress(ns, addrs)
+		if err != nil {
+			return nil, err
+		}
+
+		var address, mode *string
+		var weight *int
+		var diffs []FieldDiff
+		if addr != n.Address {
+			address = String(addr)
+			diffs = append(diffs, FieldDiff{"address", n.Address, addr})
+		}
+		if ns.Weight != 0 && ns.Weight != n.Weight {
+			weight = Int(ns.Weight)
+			diffs = append(diffs, intDiff("weight", n.Weight, ns.Weight))
+		}
+		if ns.Mode != "" && !strings.EqualFold(ns.Mode, n.Mode) {
+			mode = String(strings.ToLower(ns.Mode))
+			diffs = append(diffs, FieldDiff{"mode", n.Mode, *mode})
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+
+		id := n.ID
+		updates = append(updates, NodeBalancerChange{
+			Kind:         ChangeUpdate,
+			Resource:     "node",
+			NodeBalancer: nb,
+			Port:         cs.Port,
+			Node:         ns.Label,
+			Diffs:        diffs,
+			apply: func(a *nbApplier) error {
+				return a.c.NodeBalancerNodeUpdate(id, nil, address, weight, mode)
+			},
+		})
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Label < nodes[j].Label })
+	for _, n := range nodes {
+		if wanted[n.Label] {
+			continue
+		}
+		id := n.ID
+		deletes = append(deletes, NodeBalancerChange{
+			Kind:         ChangeDelete,
+			Resource:     "node",
+			NodeBalancer: nb,
+			Port:         cs.Port,
+			Node:         n.Label,
+			Diffs:        []FieldDiff{{"address", n.Address, ""}},
+			apply: func(a *nbApplier) error {
+				return a.c.NodeBalancerNodeDelete(id)
+			},
+		})
+	}
+
+	changes := append(deletes, updates...)
+	return append(changes, creates...), nil
+}
+
+func planNBNodeCreate(nb string, port int, ns NodeBalancerNodeSpec,
+	addrs map[string]string) (NodeBalancerChange, error) {
+
+	addr, err := nodeAddress(ns, addrs)
+	if err != nil {
+		return NodeBalancerChange{}, err
+	}
+
+	diffs := []FieldDiff{{"address", "", addr}}
+	var weight *int
+	var mode *string
+	if ns.Weight != 0 {
+		weight = Int(ns.Weight)
+		diffs = append(diffs, intDiff("weight", 0, ns.Weight))
+	}
+	if ns.Mode != "" {
+		mode = String(strings.ToLower(ns.Mode))
+		diffs = append(diffs, FieldDiff{"mode", "", *mode})
+	}
+
+	label := ns.Label
+	return NodeBalancerChange{
+		Kind:         ChangeCreate,
+		Resource:     "node",
+		NodeBalancer: nb,
+		Port:         port,
+		Node:         ns.Label,
+		Diffs:        diffs,
+		apply: func(a *nbApplier) error {
+			confID, ok := a.configIDs[port]
+			if !ok {
+				return fmt.Errorf("no config for port %d", port)
+			}
+			_, err := a.c.NodeBalancerNodeCreate(confID, label, addr, weight, mode)
+			return err
+		},
+	}, nil
+}
+
+// ApplyNodeBalancer makes the changes in plan, in order, stopping at the
+// first error.
+func (c *Client) ApplyNodeBalancer(plan *NodeBalancerPlan) error {
+	a := &nbApplier{c: c, nbID: plan.nbID, configIDs: make(map[int]int)}
+	for port, id := range plan.configIDs {
+		a.configIDs[port] = id
+	}
+
+	for _, nc := range plan.Changes {
+		err := nc.apply(a)
+		if err != nil {
+			return fmt.Errorf("nodebalancer: %s: %s", strings.SplitN(nc.String(), "\n", 2)[0], err)
+		}
+	}
+
+	return nil
+}
+
+// certFingerprint returns the SHA-256 fingerprint of the first certificate
+// in a PEM bundle, as colon-separated hex.
+func certFingerprint(certPEM string) (string, error) {
+	der, err := firstCertDER(certPEM)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return colonHex(sum[:]), nil
+}
+
+// certMatchesFingerprint reports whether the first certificate in a PEM
+// bundle has the given SHA-1 or SHA-256 fingerprint.  Separators and case
+// are ignored.
+func certMatchesFingerprint(certPEM string, fingerprint string) (bool, error) {
+	der, err := firstCertDER(certPEM)
+	if err != nil {
+		return false, err
+	}
+
+	fp := strings.ToLower(strings.NewReplacer(":", "", " ", "", "-", "").Replace(fingerprint))
+	if fp == "" {
+		return false, nil
+	}
+
+	s1 := sha1.Sum(der)
+	s256 := sha256.Sum256(der)
+	return fp == hex.EncodeToString(s1[:]) || fp == hex.EncodeToString(s256[:]), nil
+}
+
+func firstCertDER(certPEM string) ([]byte, error) {
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("ssl_cert contains no PEM certificate")
+		}
+		if block.Type == "CERTIFICATE" {
+			return block.Bytes, nil
+		}
+	}
+}
+
+func colonHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, ":")
+}
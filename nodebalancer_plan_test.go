@@ -0,0 +1,233 @@
+// +build !integration
+
+package linode
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+var (
+	testCert    = newTestCert("www.example.com", false, time.Now().Add(365*24*time.Hour), nil)
+	testCertPEM = testCert.certPEM
+	testKeyPEM  = testCert.keyPEM
+)
+
+func testNodeBalancerSpec() NodeBalancerSpec {
+	return NodeBalancerSpec{
+		Label:        "web",
+		DatacenterID: 2,
+		Configs: []NodeBalancerConfigSpec{
+			{
+				Port:         80,
+				Protocol:     "http",
+				Algorithm:    "leastconn",
+				CheckPassive: true,
+				Nodes: []NodeBalancerNodeSpec{
+					{Label: "app1", Linode: "app1", Port: 80, Weight: 50},
+					{Label: "app2", Address: "192.168.1.2:80"},
+				},
+			},
+			{
+				Port:     443,
+				Protocol: "https",
+				SSLCert:  testCertPEM,
+				SSLKey:   testKeyPEM,
+				Nodes: []NodeBalancerNodeSpec{
+					{Label: "app1", Linode: "app1", Port: 80, Mode: "Accept"},
+				},
+			},
+		},
+	}
+}
+
+func mockNodeBalancerLinodes() []mockAPIResponse {
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("linode.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1,"LABEL":"app1","DATACENTERID":2,"PLANID":1}],"ACTION":"linode.list"}`))
+	responses = append(responses, newMockAPIResponse("linode.ip.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1,"ISPUBLIC":1,"IPADDRESS":"45.33.5.10","IPADDRESSID":5},`+
+			`{"LINODEID":1,"ISPUBLIC":0,"IPADDRESS":"192.168.1.1","IPADDRESSID":6}],"ACTION":"linode.ip.list"}`))
+	return responses
+}
+
+func TestPlanNodeBalancerUpdate(t *testing.T) {
+	responses := mockNodeBalancerLinodes()
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web","DATACENTERID":2,"CLIENTCONNTHROTTLE":0}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"CONFIGID":11,"NODEBALANCERID":5,"PORT":8080,"PROTOCOL":"http"},`+
+			`{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80,"PROTOCOL":"http","ALGORITHM":"roundrobin","CHECK_PASSIVE":1}`+
+			`],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"NODEID":20,"CONFIGID":10,"LABEL":"app1","ADDRESS":"192.168.1.1:80","WEIGHT":100,"MODE":"accept"},`+
+			`{"NODEID":21,"CONFIGID":10,"LABEL":"old","ADDRESS":"192.168.1.9:80","WEIGHT":100,"MODE":"accept"}`+
+			`],"ACTION":"nodebalancer.node.list"}`))
+
+	// Apply.
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.update",
+		map[string]string{"ConfigID": "10", "Algorithm": "leastconn"},
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":10},"ACTION":"nodebalancer.config.update"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.delete", map[string]string{"NodeID": "21"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":21},"ACTION":"nodebalancer.node.delete"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.update",
+		map[string]string{"NodeID": "20", "Weight": "50"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":20},"ACTION":"nodebalancer.node.update"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.create",
+		map[string]string{"ConfigID": "10", "Label": "app2", "Address": "192.168.1.2:80"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":22},"ACTION":"nodebalancer.node.create"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.delete",
+		map[string]string{"NodeBalancerID": "5", "ConfigID": "11"},
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":11},"ACTION":"nodebalancer.config.delete"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.create",
+		map[string]string{"NodeBalancerID": "5", "Port": "443", "Protocol": "https", "ssl_cert": testCertPEM, "ssl_key": testKeyPEM, "check_passive": "0"},
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":12},"ACTION":"nodebalancer.config.create"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.create",
+		map[string]string{"ConfigID": "12", "Label": "app1", "Address": "192.168.1.1:80", "Mode": "accept"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":23},"ACTION":"nodebalancer.node.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	plan, err := c.PlanNodeBalancer(testNodeBalancerSpec())
+	require.NoError(t, err)
+
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+
+	assert.Equal(t, `~ config web:80
+    algorithm: "roundrobin" -> "leastconn"
+- node web:80/old
+    address: "192.168.1.9:80" -> ""
+~ node web:80/app1
+    weight: "100" -> "50"
++ node web:80/app2
+    address: "" -> "192.168.1.2:80"
+- config web:8080
++ config web:443
+    port: "0" -> "443"
+    protocol: "" -> "https"
+    ssl_cert: "" -> "`+fp+`"
++ node web:443/app1
+    address: "" -> "192.168.1.1:80"
+    mode: "" -> "accept"
+`, plan.String())
+
+	require.NoError(t, c.ApplyNodeBalancer(plan))
+}
+
+func TestPlanNodeBalancerCreate(t *testing.T) {
+	spec := NodeBalancerSpec{
+		Label:        "api",
+		DatacenterID: 3,
+		Throttle:     10,
+		Configs: []NodeBalancerConfigSpec{{
+			Port:     80,
+			Protocol: "tcp",
+			Nodes:    []NodeBalancerNodeSpec{{Label: "a", Address: "192.168.1.5:80"}},
+		}},
+	}
+
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.create",
+		map[string]string{"DatacenterID": "3", "Label": "api", "ClientConnThrottle": "10"},
+		`{"ERRORARRAY":[],"DATA":{"NodeBalancerID":6},"ACTION":"nodebalancer.create"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.create",
+		map[string]string{"NodeBalancerID": "6", "Port": "80", "Protocol": "tcp"},
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":13},"ACTION":"nodebalancer.config.create"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.create",
+		map[string]string{"ConfigID": "13", "Label": "a", "Address": "192.168.1.5:80"},
+		`{"ERRORARRAY":[],"DATA":{"NodeID":24},"ACTION":"nodebalancer.node.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	plan, err := c.PlanNodeBalancer(spec)
+	require.NoError(t, err)
+	require.Len(t, plan.Changes, 3)
+	assert.Equal(t, "+ nodebalancer api", strings.SplitN(plan.Changes[0].String(), "\n", 2)[0])
+
+	require.NoError(t, c.ApplyNodeBalancer(plan))
+}
+
+func TestPlanNodeBalancerUnchanged(t *testing.T) {
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+
+	spec := NodeBalancerSpec{
+		Label:        "web",
+		DatacenterID: 2,
+		Configs: []NodeBalancerConfigSpec{{
+			Port:     443,
+			Protocol: "https",
+			SSLCert:  testCertPEM,
+			SSLKey:   testKeyPEM,
+		}},
+	}
+
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web","DATACENTERID":2}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":10,"NODEBALANCERID":5,"PORT":443,"PROTOCOL":"https","SSL_FINGERPRINT":"`+
+			strings.ToLower(fp)+`"}],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.node.list", map[string]string{"ConfigID": "10"},
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"nodebalancer.node.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	plan, err := c.PlanNodeBalancer(spec)
+	require.NoError(t, err)
+	assert.True(t, plan.Empty(), plan.String())
+}
+
+func TestPlanNodeBalancerErrors(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.PlanNodeBalancer(testNodeBalancerSpec())
+	assert.Error(t, err)
+
+	for _, spec := range []NodeBalancerSpec{
+		{DatacenterID: 2},
+		{Label: "web"},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 0, Protocol: "http"}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 80, Protocol: "http"}, {Port: 80, Protocol: "tcp"}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 80, Protocol: "udp"}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 443, Protocol: "https", SSLCert: testCertPEM}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 80, Protocol: "http",
+			Nodes: []NodeBalancerNodeSpec{{Label: "a", Address: "192.168.1.1:80"}, {Label: "a", Address: "192.168.1.2:80"}}}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 80, Protocol: "http",
+			Nodes: []NodeBalancerNodeSpec{{Label: "a", Address: "192.168.1.1:80", Linode: "app1"}}}}},
+		{Label: "web", DatacenterID: 2, Configs: []NodeBalancerConfigSpec{{Port: 80, Protocol: "http",
+			Nodes: []NodeBalancerNodeSpec{{Label: "a", Linode: "app1"}}}}},
+	} {
+		assert.Error(t, validateNodeBalancerSpec(spec), "%+v", spec)
+	}
+}
+
+func TestCertFingerprint(t *testing.T) {
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+	assert.Len(t, fp, 95)
+
+	ok, err := certMatchesFingerprint(testCertPEM, strings.Replace(fp, ":", "", -1))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = certMatchesFingerprint(testCertPEM, "")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = certFingerprint("not a certificate")
+	assert.Error(t, err)
+}
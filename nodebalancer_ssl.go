@@ -0,0 +1,237 @@
+package linode
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SSLCertInfo describes a certificate checked by ValidateSSLCert().
+type SSLCertInfo struct {
+	CommonName  string
+	DNSNames    []string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Fingerprint string
+
+	// Chain is the number of certificates in the bundle, including the
+	// leaf.
+	Chain int
+}
+
+// SSLValidateOpts contains the optional arguments to ValidateSSLCert().
+type SSLValidateOpts struct {
+	// Hostname, if set, must be covered by the certificate's names.
+	Hostname string
+
+	// MinValidity is how long the certificates must remain valid for.
+	MinValidity time.Duration
+
+	// Now is the time to check expiry against.  If zero, the current time
+	// is used.
+	Now time.Time
+}
+
+// ValidateSSLCert checks an ssl_cert and ssl_key pair before it is sent to a
+// NodeBalancer: both must parse as PEM, the key must match the first
+// certificate, and each certificate in the bundle must be signed by the one
+// after it.  No certificate may be expired or not yet valid.
+func ValidateSSLCert(certPEM string, keyPEM string, opts SSLValidateOpts) (*SSLCertInfo, error) {
+	certs, err := parseCertChain(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("nodebalancer: ssl_key: %s", strings.TrimPrefix(err.Error(), "tls: "))
+	}
+
+	for i := 0; i+1 < len(certs); i++ {
+		if certs[i].CheckSignatureFrom(certs[i+1]) == nil {
+			continue
+		}
+		for _, issuer := range certs {
+			if issuer != certs[i] && certs[i].CheckSignatureFrom(issuer) == nil {
+				return nil, fmt.Errorf("nodebalancer: ssl_cert: chain is out of order, %q should come right after %q",
+					issuer.Subject.CommonName, certs[i].Subject.CommonName)
+			}
+		}
+		return nil, fmt.Errorf("nodebalancer: ssl_cert: %q is not signed by the next certificate, %q",
+			certs[i].Subject.CommonName, certs[i+1].Subject.CommonName)
+	}
+
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	for _, cert := range certs {
+		name := cert.Subject.CommonName
+		switch {
+		case now.Before(cert.NotBefore):
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: %q is not valid until %s",
+				name, cert.NotBefore.Format(time.RFC3339))
+		case now.After(cert.NotAfter):
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: %q expired at %s",
+				name, cert.NotAfter.Format(time.RFC3339))
+		case now.Add(opts.MinValidity).After(cert.NotAfter):
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: %q expires at %s, within %s",
+				name, cert.NotAfter.Format(time.RFC3339), opts.MinValidity)
+		}
+	}
+
+	leaf := certs[0]
+	if opts.Hostname != "" {
+		err = leaf.VerifyHostname(opts.Hostname)
+		if err != nil {
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: %s", err)
+		}
+	}
+
+	fp, err := certFingerprint(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSLCertInfo{
+		CommonName:  leaf.Subject.CommonName,
+		DNSNames:    leaf.DNSNames,
+		NotBefore:   leaf.NotBefore,
+		NotAfter:    leaf.NotAfter,
+		Fingerprint: fp,
+		Chain:       len(certs),
+	}, nil
+}
+
+func parseCertChain(certPEM string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(certPEM)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: unexpected %s block", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("nodebalancer: ssl_cert: certificate %d: %s", len(certs)+1, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("nodebalancer: ssl_cert contains no PEM certificate")
+	}
+	return certs, nil
+}
+
+// ServesCert reports whether the config is using the first certificate in
+// certPEM.  The config's fingerprint must match, and its common name too if
+// it has one.
+func (conf NodeBalancerConfig) ServesCert(certPEM string) (bool, error) {
+	ok, err := certMatchesFingerprint(certPEM, conf.SSLFingerprint)
+	if err != nil || !ok || conf.SSLCommonName == "" {
+		return ok, err
+	}
+
+	certs, err := parseCertChain(certPEM)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(conf.SSLCommonName, certs[0].Subject.CommonName), nil
+}
+
+// SSLRotateOpts contains the optional arguments to RotateSSLCert().
+type SSLRotateOpts struct {
+	// NodeBalancerIDs limits the rotation to these NodeBalancers.  If
+	// empty, every NodeBalancer on the account is checked.
+	NodeBalancerIDs []int
+
+	// Validate is passed to ValidateSSLCert() for the new certificate.
+	Validate SSLValidateOpts
+
+	// DryRun finds the configs to rotate without changing them.
+	DryRun bool
+}
+
+// SSLRotation is the result of rotating the certificate of one config.
+type SSLRotation struct {
+	NodeBalancerID int
+	Label          string
+	ConfigID       int
+	Port           int
+	Err            error
+}
+
+// RotateSSLCert replaces oldCertPEM with certPEM and keyPEM on every HTTPS
+// config that is serving it, as reported by ServesCert().  The new pair is
+// validated first, and nothing is changed if it is invalid.
+//
+// Every config found gets a result.  A failed update does not stop the
+// others; an error is only returned if the new pair is invalid or the
+// NodeBalancers cannot be listed.
+func (c *Client) RotateSSLCert(oldCertPEM string, certPEM string, keyPEM string,
+	opts SSLRotateOpts) ([]SSLRotation, error) {
+
+	_, err := firstCertDER(oldCertPEM)
+	if err != nil {
+		return nil, fmt.Errorf("nodebalancer: old %s", err)
+	}
+	_, err = ValidateSSLCert(certPEM, keyPEM, opts.Validate)
+	if err != nil {
+		return nil, err
+	}
+
+	nbs, err := c.NodeBalancerList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int]bool)
+	for _, id := range opts.NodeBalancerIDs {
+		wanted[id] = true
+	}
+
+	var results []SSLRotation
+	for _, nb := range nbs {
+		if len(wanted) > 0 && !wanted[nb.ID] {
+			continue
+		}
+
+		configs, err := c.NodeBalancerConfigList(nb.ID, nil)
+		if err != nil {
+			return results, err
+		}
+
+		for _, conf := range configs {
+			if !strings.EqualFold(conf.Protocol, "https") {
+				continue
+			}
+			ok, err := conf.ServesCert(oldCertPEM)
+			if err != nil {
+				return results, err
+			}
+			if !ok {
+				continue
+			}
+
+			r := SSLRotation{NodeBalancerID: nb.ID, Label: nb.Label, ConfigID: conf.ID, Port: conf.Port}
+			if !opts.DryRun {
+				r.Err = c.NodeBalancerConfigUpdate(conf.ID, NodeBalancerConfigUpdateOpts{
+					SSLCert: String(certPEM),
+					SSLKey:  String(keyPEM),
+				})
+			}
+			results = append(results, r)
+		}
+	}
+
+	return results, nil
+}
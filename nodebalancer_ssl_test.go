@@ -0,0 +1,224 @@
+// +build !integration
+
+package linode
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+type testCertPair struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+var testCertSerial int64
+
+// newTestCert makes a certificate for cn, signed by parent, or self-signed
+// if parent is nil.
+func newTestCert(cn string, ca bool, notAfter time.Time, parent *testCertPair) *testCertPair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	testCertSerial++
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(testCertSerial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notAfter.Add(-2 * 365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if !ca {
+		tmpl.DNSNames = []string{cn}
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return &testCertPair{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func TestValidateSSLCert(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	root := newTestCert("Test Root", true, year.Add(24*time.Hour), nil)
+	inter := newTestCert("Test Intermediate", true, year, root)
+	leaf := newTestCert("www.example.com", false, year, inter)
+
+	info, err := ValidateSSLCert(leaf.certPEM+inter.certPEM, leaf.keyPEM, SSLValidateOpts{
+		Hostname:    "www.example.com",
+		MinValidity: 30 * 24 * time.Hour,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "www.example.com", info.CommonName)
+	assert.Equal(t, []string{"www.example.com"}, info.DNSNames)
+	assert.Equal(t, 2, info.Chain)
+	fp, err := certFingerprint(leaf.certPEM)
+	require.NoError(t, err)
+	assert.Equal(t, fp, info.Fingerprint)
+
+	_, err = ValidateSSLCert(leaf.certPEM+inter.certPEM+root.certPEM, leaf.keyPEM, SSLValidateOpts{})
+	assert.NoError(t, err)
+
+	// The key matches, but the intermediate comes first.
+	_, err = ValidateSSLCert(leaf.certPEM+root.certPEM+inter.certPEM, leaf.keyPEM, SSLValidateOpts{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order")
+}
+
+func TestValidateSSLCertErrors(t *testing.T) {
+	year := time.Now().Add(365 * 24 * time.Hour)
+	leaf := newTestCert("www.example.com", false, year, nil)
+	other := newTestCert("other.example.com", false, year, nil)
+	expired := newTestCert("www.example.com", false, time.Now().Add(-time.Hour), nil)
+
+	tests := []struct {
+		cert, key string
+		opts      SSLValidateOpts
+		want      string
+	}{
+		{"", leaf.keyPEM, SSLValidateOpts{}, "no PEM certificate"},
+		{"not a certificate", leaf.keyPEM, SSLValidateOpts{}, "no PEM certificate"},
+		{leaf.keyPEM, leaf.keyPEM, SSLValidateOpts{}, "unexpected EC PRIVATE KEY block"},
+		{leaf.certPEM, "", SSLValidateOpts{}, "ssl_key"},
+		{leaf.certPEM, other.keyPEM, SSLValidateOpts{}, "ssl_key"},
+		{leaf.certPEM + other.certPEM, leaf.keyPEM, SSLValidateOpts{}, "not signed"},
+		{expired.certPEM, expired.keyPEM, SSLValidateOpts{}, "expired"},
+		{leaf.certPEM, leaf.keyPEM, SSLValidateOpts{Now: time.Now().Add(-3 * 365 * 24 * time.Hour)}, "not valid until"},
+		{leaf.certPEM, leaf.keyPEM, SSLValidateOpts{MinValidity: 2 * 365 * 24 * time.Hour}, "expires"},
+		{leaf.certPEM, leaf.keyPEM, SSLValidateOpts{Hostname: "api.example.com"}, "api.example.com"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateSSLCert(tt.cert, tt.key, tt.opts)
+		if assert.Error(t, err, tt.want) {
+			assert.Contains(t, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestNodeBalancerConfigServesCert(t *testing.T) {
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+
+	conf := NodeBalancerConfig{SSLFingerprint: strings.ToLower(fp), SSLCommonName: "www.example.com"}
+	ok, err := conf.ServesCert(testCertPEM)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	conf.SSLCommonName = "other.example.com"
+	ok, err = conf.ServesCert(testCertPEM)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = NodeBalancerConfig{SSLCommonName: "www.example.com"}.ServesCert(testCertPEM)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = conf.ServesCert("")
+	assert.Error(t, err)
+}
+
+func TestRotateSSLCert(t *testing.T) {
+	renewed := newTestCert("www.example.com", false, time.Now().Add(2*365*24*time.Hour), nil)
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web"},{"NODEBALANCERID":6,"LABEL":"api"}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "5"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"CONFIGID":10,"NODEBALANCERID":5,"PORT":80,"PROTOCOL":"http"},`+
+			`{"CONFIGID":11,"NODEBALANCERID":5,"PORT":443,"PROTOCOL":"https","SSL_FINGERPRINT":"`+fp+`","SSL_COMMONNAME":"www.example.com"},`+
+			`{"CONFIGID":12,"NODEBALANCERID":5,"PORT":8443,"PROTOCOL":"https","SSL_FINGERPRINT":"00:11","SSL_COMMONNAME":"www.example.com"}`+
+			`],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.update",
+		map[string]string{"ConfigID": "11", "ssl_cert": renewed.certPEM, "ssl_key": renewed.keyPEM},
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":11},"ACTION":"nodebalancer.config.update"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "6"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"CONFIGID":20,"NODEBALANCERID":6,"PORT":443,"PROTOCOL":"https","SSL_FINGERPRINT":"`+fp+`","SSL_COMMONNAME":"www.example.com"}`+
+			`],"ACTION":"nodebalancer.config.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.update", map[string]string{"ConfigID": "20"},
+		`{"ERRORARRAY":[{"ERRORCODE":8,"ERRORMESSAGE":"Invalid certificate"}],"DATA":{},"ACTION":"nodebalancer.config.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	results, err := c.RotateSSLCert(testCertPEM, renewed.certPEM, renewed.keyPEM, SSLRotateOpts{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, SSLRotation{NodeBalancerID: 5, Label: "web", ConfigID: 11, Port: 443}, results[0])
+	assert.Equal(t, 20, results[1].ConfigID)
+	assert.Error(t, results[1].Err)
+}
+
+func TestRotateSSLCertDryRun(t *testing.T) {
+	fp, err := certFingerprint(testCertPEM)
+	require.NoError(t, err)
+
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web"},{"NODEBALANCERID":6,"LABEL":"api"}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.config.list", map[string]string{"NodeBalancerID": "6"},
+		`{"ERRORARRAY":[],"DATA":[{"CONFIGID":20,"NODEBALANCERID":6,"PORT":443,"PROTOCOL":"https","SSL_FINGERPRINT":"`+fp+`"}],"ACTION":"nodebalancer.config.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	renewed := newTestCert("www.example.com", false, time.Now().Add(2*365*24*time.Hour), nil)
+	results, err := c.RotateSSLCert(testCertPEM, renewed.certPEM, renewed.keyPEM, SSLRotateOpts{
+		NodeBalancerIDs: []int{6},
+		DryRun:          true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []SSLRotation{{NodeBalancerID: 6, Label: "api", ConfigID: 20, Port: 443}}, results)
+}
+
+func TestRotateSSLCertInvalid(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.RotateSSLCert(testCertPEM, testCertPEM, "not a key", SSLRotateOpts{})
+	assert.Error(t, err)
+
+	_, err = c.RotateSSLCert("", testCertPEM, testKeyPEM, SSLRotateOpts{})
+	assert.Error(t, err)
+
+	_, err = c.RotateSSLCert(testCertPEM, testCertPEM, testKeyPEM, SSLRotateOpts{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,229 @@
+package linode
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// ProvisionStep identifies a step of Provision().
+type ProvisionStep string
+
+// Steps of Provision(), in the order they run.
+const (
+	ProvisionCreate    ProvisionStep = "create"
+	ProvisionUpdate    ProvisionStep = "update"
+	ProvisionRootDisk  ProvisionStep = "root disk"
+	ProvisionSwapDisk  ProvisionStep = "swap disk"
+	ProvisionConfig    ProvisionStep = "config"
+	ProvisionBoot      ProvisionStep = "boot"
+	ProvisionRollback  ProvisionStep = "rollback"
+	ProvisionSucceeded ProvisionStep = "done"
+)
+
+const (
+	defaultConfigLabel = "default"
+	defaultRootLabel   = "root"
+	defaultSwapLabel   = "swap"
+)
+
+// ProvisionSpec describes a Linode to be built by Provision().
+type ProvisionSpec struct {
+	DatacenterID int
+	PlanID       int
+	PaymentTerm  *int
+
+	Label        string
+	DisplayGroup string
+
+	DistributionID int
+	RootPass       string
+	RootSSHKey     *string
+
+	// RootSize is the size of the root disk in MB.  If zero, the root disk
+	// takes all space not used by swap.
+	RootSize int
+
+	// SwapSize is the size of the swap disk in MB.  If zero, no swap disk is
+	// created.
+	SwapSize int
+
+	KernelID int
+
+	// ConfigLabel is the label of the boot configuration.  It defaults to
+	// "default".
+	ConfigLabel string
+	ConfigOpts  LinodeConfigCreateOpts
+}
+
+// Provisioned is the result of a successful Provision().
+type Provisioned struct {
+	LinodeID   int
+	RootDiskID int
+	SwapDiskID int
+	ConfigID   int
+
+	// JobStoreErr is the first error saving a job to the client's JobStore,
+	// if any.  It does not stop provisioning, since the job runs regardless.
+	JobStoreErr error
+}
+
+// ProvisionError is returned when Provision() fails.  If a Linode had been
+// created, it has been deleted unless RollbackErr is set.
+type ProvisionError struct {
+	Step        ProvisionStep
+	LinodeID    int
+	Err         error
+	RollbackErr error
+}
+
+func (e *ProvisionError) Error() string {
+	msg := fmt.Sprintf("provision: %s: %s", e.Step, e.Err)
+	if e.RollbackErr != nil {
+		msg += fmt.Sprintf(" (rollback of Linode %d failed: %s)", e.LinodeID, e.RollbackErr)
+	}
+	return msg
+}
+
+// Provision creates a Linode, creates its root and swap disks and boot
+// configuration, sets its label and display group, and boots it, waiting for
+// each job with the client's shared JobWatcher.
+//
+// If progress is non-nil, it is called as each step starts.
+//
+// If any step fails, the Linode is deleted, along with its jobs in the
+// client's JobStore, and a *ProvisionError is returned.
+// Failing to save a job to the client's JobStore is not a failed step; see
+// Provisioned.JobStoreErr.
+func (c *Client) Provision(ctx context.Context, spec ProvisionSpec,
+	progress func(ProvisionStep)) (Provisioned, error) {
+
+	report := func(s ProvisionStep) {
+		if progress != nil {
+			progress(s)
+		}
+	}
+
+	var p Provisioned
+	var step ProvisionStep
+
+	fail := func(err error) (Provisioned, error) {
+		pErr := &ProvisionError{Step: step, LinodeID: p.LinodeID, Err: err}
+		if p.LinodeID != 0 {
+			report(ProvisionRollback)
+			pErr.RollbackErr = c.LinodeDelete(p.LinodeID, Bool(true))
+			if pErr.RollbackErr == nil {
+				c.untrackLinode(p.LinodeID)
+			}
+		}
+		return Provisioned{}, pErr
+	}
+
+	// queued drops the error of a *Job call that queued its job but could
+	// not save it to the JobStore.
+	queued := func(j *Job, err error) error {
+		if j != nil && err != nil {
+			if p.JobStoreErr == nil {
+				p.JobStoreErr = err
+			}
+			return nil
+		}
+		return err
+	}
+
+	w := c.JobWatcher()
+	var err error
+
+	step = ProvisionCreate
+	report(step)
+	p.LinodeID, err = c.LinodeCreate(spec.DatacenterID, spec.PlanID, spec.PaymentTerm)
+	if err != nil {
+		return fail(err)
+	}
+
+	step = ProvisionUpdate
+	report(step)
+	opts := LinodeOpts{}
+	if spec.Label != "" {
+		opts.Label = String(spec.Label)
+	}
+	if spec.DisplayGroup != "" {
+		opts.DisplayGroup = String(spec.DisplayGroup)
+	}
+	err = c.LinodeUpdate(p.LinodeID, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	step = ProvisionRootDisk
+	report(step)
+	rootSize := spec.RootSize
+	if rootSize == 0 {
+		linodes, err := c.LinodeList(Int(p.LinodeID))
+		if err != nil {
+			return fail(err)
+		}
+		if len(linodes) != 1 {
+			return fail(fmt.Errorf("linode id %d not found", p.LinodeID))
+		}
+		rootSize = linodes[0].TotalHD - spec.SwapSize
+	}
+
+	var job *Job
+	job, p.RootDiskID, err = c.LinodeDiskCreateFromDistributionJob(p.LinodeID, spec.DistributionID,
+		defaultRootLabel, rootSize, spec.RootPass, spec.RootSSHKey)
+	err = queued(job, err)
+	if err != nil {
+		return fail(err)
+	}
+	_, err = w.WaitJob(ctx, job)
+	if err != nil {
+		return fail(err)
+	}
+
+	diskList := strconv.Itoa(p.RootDiskID)
+
+	if spec.SwapSize != 0 {
+		step = ProvisionSwapDisk
+		report(step)
+		job, p.SwapDiskID, err = c.LinodeDiskCreateJob(p.LinodeID, defaultSwapLabel, "swap",
+			spec.SwapSize)
+		err = queued(job, err)
+		if err != nil {
+			return fail(err)
+		}
+		_, err = w.WaitJob(ctx, job)
+		if err != nil {
+			return fail(err)
+		}
+		diskList += "," + strconv.Itoa(p.SwapDiskID)
+	}
+
+	step = ProvisionConfig
+	report(step)
+	configLabel := spec.ConfigLabel
+	if configLabel == "" {
+		configLabel = defaultConfigLabel
+	}
+	p.ConfigID, err = c.LinodeConfigCreate(p.LinodeID, spec.KernelID, configLabel, diskList,
+		spec.ConfigOpts)
+	if err != nil {
+		return fail(err)
+	}
+
+	step = ProvisionBoot
+	report(step)
+	job, err = c.LinodeBootJob(p.LinodeID, Int(p.ConfigID))
+	err = queued(job, err)
+	if err != nil {
+		return fail(err)
+	}
+	_, err = w.WaitJob(ctx, job)
+	if err != nil {
+		return fail(err)
+	}
+
+	report(ProvisionSucceeded)
+
+	return p, nil
+}
@@ -0,0 +1,234 @@
+// +build !integration
+
+package linode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockProvisionJobDone(jobID string, success string) []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	params := map[string]string{
+		"LinodeID":    "1146420",
+		"pendingOnly": "1",
+	}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"linode.job.list"}`))
+
+	params = map[string]string{
+		"LinodeID": "1146420",
+	}
+	responses = append(responses, newMockAPIResponse("linode.job.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"HOST_FINISH_DT":"2015-07-08 20:16:52.0","JOBID":`+jobID+
+			`,"HOST_SUCCESS":`+success+`,"HOST_MESSAGE":"boom","LINODEID":1146420}],"ACTION":"linode.job.list"}`))
+
+	return responses
+}
+
+func mockProvisionStart() []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{
+		"DatacenterID": "2",
+		"PlanID":       "1",
+	}
+	responses = append(responses, newMockAPIResponse("linode.create", params,
+		`{"ERRORARRAY":[],"DATA":{"LinodeID":1146420},"ACTION":"linode.create"}`))
+
+	params = map[string]string{
+		"LinodeID":         "1146420",
+		"label":            "web1",
+		"lpm_displayGroup": "web",
+	}
+	responses = append(responses, newMockAPIResponse("linode.update", params,
+		`{"ERRORARRAY":[],"DATA":{"LinodeID":1146420},"ACTION":"linode.update"}`))
+
+	params = map[string]string{
+		"LinodeID": "1146420",
+	}
+	responses = append(responses, newMockAPIResponse("linode.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"LINODEID":1146420,"TOTALHD":24576}],"ACTION":"linode.list"}`))
+
+	params = map[string]string{
+		"LinodeID":       "1146420",
+		"DistributionID": "130",
+		"Label":          "root",
+		"Size":           "24320",
+		"rootPass":       rootPass,
+		"rootSSHKey":     rootSSHKey,
+	}
+	responses = append(responses, newMockAPIResponse("linode.disk.createfromdistribution", params,
+		`{"ERRORARRAY":[],"DATA":{"JobID":100,"DiskID":3582630},"ACTION":"linode.disk.createfromdistribution"}`))
+	responses = append(responses, mockProvisionJobDone("100", "1")...)
+
+	params = map[string]string{
+		"LinodeID": "1146420",
+		"Label":    "swap",
+		"Type":     "swap",
+		"Size":     "256",
+	}
+	responses = append(responses, newMockAPIResponse("linode.disk.create", params,
+		`{"ERRORARRAY":[],"DATA":{"JobID":101,"DiskID":3582631},"ACTION":"linode.disk.create"}`))
+
+	return responses
+}
+
+func testProvisionSpec() ProvisionSpec {
+	return ProvisionSpec{
+		DatacenterID:   2,
+		PlanID:         1,
+		Label:          "web1",
+		DisplayGroup:   "web",
+		DistributionID: 130,
+		RootPass:       rootPass,
+		RootSSHKey:     String(rootSSHKey),
+		SwapSize:       256,
+		KernelID:       138,
+	}
+}
+
+func mockProvisionOK() []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockProvisionStart()...)
+	responses = append(responses, mockProvisionJobDone("101", "1")...)
+
+	params = map[string]string{
+		"LinodeID": "1146420",
+		"KernelID": "138",
+		"Label":    "default",
+		"DiskList": "3582630,3582631",
+	}
+	responses = append(responses, newMockAPIResponse("linode.config.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ConfigID":1862370},"ACTION":"linode.config.create"}`))
+
+	params = map[string]string{
+		"LinodeID": "1146420",
+		"ConfigID": "1862370",
+	}
+	responses = append(responses, newMockAPIResponse("linode.boot", params,
+		`{"ERRORARRAY":[],"DATA":{"JobID":102},"ACTION":"linode.boot"}`))
+	responses = append(responses, mockProvisionJobDone("102", "1")...)
+
+	return responses
+}
+
+func TestProvisionOK(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockProvisionOK()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+
+	var steps []ProvisionStep
+	p, err := c.Provision(context.Background(), testProvisionSpec(), func(s ProvisionStep) {
+		steps = append(steps, s)
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, Provisioned{
+		LinodeID:   1146420,
+		RootDiskID: 3582630,
+		SwapDiskID: 3582631,
+		ConfigID:   1862370,
+	}, p)
+
+	assert.Equal(t, []ProvisionStep{ProvisionCreate, ProvisionUpdate, ProvisionRootDisk,
+		ProvisionSwapDisk, ProvisionConfig, ProvisionBoot, ProvisionSucceeded}, steps)
+}
+
+func mockProvisionRollback() []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	responses = append(responses, mockProvisionStart()...)
+	responses = append(responses, mockProvisionJobDone("101", "0")...)
+
+	params := map[string]string{
+		"LinodeID":   "1146420",
+		"skipChecks": "true",
+	}
+	responses = append(responses, newMockAPIResponse("linode.delete", params,
+		`{"ERRORARRAY":[],"DATA":{"LinodeID":1146420},"ACTION":"linode.delete"}`))
+
+	return responses
+}
+
+func TestProvisionRollback(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockProvisionRollback()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+
+	var steps []ProvisionStep
+	_, err := c.Provision(context.Background(), testProvisionSpec(), func(s ProvisionStep) {
+		steps = append(steps, s)
+	})
+	require.Error(t, err)
+
+	pErr, ok := err.(*ProvisionError)
+	require.True(t, ok)
+	assert.Equal(t, ProvisionSwapDisk, pErr.Step)
+	assert.Equal(t, 1146420, pErr.LinodeID)
+	assert.NoError(t, pErr.RollbackErr)
+
+	jErr, ok := pErr.Err.(*JobError)
+	require.True(t, ok)
+	assert.Equal(t, "boom", jErr.Job.HostMessage)
+
+	assert.Equal(t, ProvisionRollback, steps[len(steps)-1])
+}
+
+func TestProvisionRollbackUntracksJobs(t *testing.T) {
+	s, cleanup := tempJobStore(t)
+	defer cleanup()
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 1146420, JobID: 99, Action: "linode.boot"}))
+	require.NoError(t, s.Save(TrackedJob{LinodeID: 7, JobID: 5, Action: "linode.boot"}))
+
+	c, ts := clientFor(newMockAPIServer(t, mockProvisionRollback()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+	c.JobStore = s
+
+	_, err := c.Provision(context.Background(), testProvisionSpec(), nil)
+	require.Error(t, err)
+
+	// Only the jobs of the deleted Linode are gone.
+	left, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, left, 1)
+	assert.Equal(t, 7, left[0].LinodeID)
+}
+
+func TestProvisionCreateError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.Provision(context.Background(), testProvisionSpec(), nil)
+	require.Error(t, err)
+	assert.Equal(t, "provision: create: bar", err.Error())
+}
+
+type failingJobStore struct{}
+
+func (failingJobStore) Save(job TrackedJob) error            { return errors.New("disk full") }
+func (failingJobStore) Delete(linodeID int, jobID int) error { return nil }
+func (failingJobStore) List() ([]TrackedJob, error)          { return nil, nil }
+
+func TestProvisionJobStoreError(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockProvisionOK()))
+	defer ts.Close()
+	c.JobWatcher().MinInterval = 1 * time.Nanosecond
+	c.JobStore = failingJobStore{}
+
+	p, err := c.Provision(context.Background(), testProvisionSpec(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1862370, p.ConfigID)
+	require.Error(t, p.JobStoreErr)
+	assert.Equal(t, "disk full", p.JobStoreErr.Error())
+}
@@ -0,0 +1,263 @@
+package linode
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Reverse DNS audit checks, as reported in RDNSFinding.Check.
+const (
+	RDNSDefault   = "default-rdns"
+	RDNSUnhosted  = "rdns-unhosted"
+	RDNSMissing   = "rdns-missing"
+	RDNSMismatch  = "rdns-mismatch"
+	RDNSForeignIP = "foreign-ip"
+)
+
+// defaultRDNSSuffixes are the names Linode assigns to IPs before reverse DNS
+// is set.
+var defaultRDNSSuffixes = []string{
+	".members.linode.com",
+	".ip.linodeusercontent.com",
+}
+
+// RDNSFinding is a single problem found by AuditRDNS().
+type RDNSFinding struct {
+	Severity LintSeverity
+	Check    string
+
+	// Address is the IP concerned, and LinodeID the Linode it is assigned
+	// to, or zero if it is not assigned to one.
+	Address  string
+	LinodeID int
+
+	// Name is the reverse DNS name for checks on a Linode's IP, or the
+	// record's FQDN for checks on a record, in which case DomainID and
+	// ResourceID identify the record.
+	Name       string
+	DomainID   int
+	ResourceID int
+
+	Message string
+}
+
+func (f RDNSFinding) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", f.Severity, f.Check, f.Address, f.Message)
+}
+
+// RDNSFindings is the result of AuditRDNS(), ordered from most to least
+// severe.
+type RDNSFindings []RDNSFinding
+
+// Worst returns the highest severity in fs, or -1 if fs is empty.
+func (fs RDNSFindings) Worst() LintSeverity {
+	worst := LintSeverity(-1)
+	for _, f := range fs {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+func (fs RDNSFindings) String() string {
+	var buf bytes.Buffer
+	for _, f := range fs {
+		buf.WriteString(f.String())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// AuditRDNS cross-references the account's IPs with the records in its
+// master domains, reporting:
+//
+//   - public IPs that still have the default reverse DNS name (warning)
+//   - reverse DNS names outside of any hosted domain, which cannot be
+//     checked (info)
+//   - reverse DNS names with no A or AAAA record (error), or whose records
+//     do not include the IP (error)
+//   - A records pointing at IPs not assigned to any of the account's
+//     Linodes or NodeBalancers, such as released IPs (warning)
+//   - AAAA records pointing at IPs not assigned to a NodeBalancer (info),
+//     since Linodes' IPv6 addresses are not listed by the API
+func (c *Client) AuditRDNS() (RDNSFindings, error) {
+	ips, err := c.LinodeIPList(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nbs, err := c.NodeBalancerList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make(map[int][]DomainResource)
+	for _, d := range domains {
+		if !strings.EqualFold(d.Type, "master") {
+			continue
+		}
+		resources[d.ID], err = c.DomainResourceList(d.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return auditRDNS(ips, nbs, domains, resources), nil
+}
+
+type rdnsRecord struct {
+	domainID   int
+	resourceID int
+	fqdn       string
+	ip         net.IP
+}
+
+func auditRDNS(ips []LinodeIP, nbs []NodeBalancer, domains []Domain,
+	resources map[int][]DomainResource) RDNSFindings {
+
+	var fs RDNSFindings
+
+	owned := make(map[string]bool)
+	own := func(addr string) {
+		if parsed := net.ParseIP(addr); parsed != nil {
+			owned[parsed.String()] = true
+		}
+	}
+	for _, ip := range ips {
+		own(ip.Address)
+	}
+	for _, nb := range nbs {
+		own(nb.IPv4Addr)
+		own(nb.IPv6Addr)
+	}
+
+	forward := make(map[string][]rdnsRecord)
+	var records []rdnsRecord
+	for _, d := range domains {
+		for _, r := range resources[d.ID] {
+			if !strings.EqualFold(r.Type, "a") && !strings.EqualFold(r.Type, "aaaa") {
+				continue
+			}
+			ip := net.ParseIP(r.Target)
+			if ip == nil {
+				// Left to LintZone().
+				continue
+			}
+			rec := rdnsRecord{
+				domainID:   d.ID,
+				resourceID: r.ID,
+				fqdn:       cloneName(strings.ToLower(r.Name), strings.ToLower(d.Domain)),
+				ip:         ip,
+			}
+			forward[rec.fqdn] = append(forward[rec.fqdn], rec)
+			records = append(records, rec)
+		}
+	}
+
+	for _, ip := range ips {
+		if !ip.IsPublic {
+			continue
+		}
+
+		f := RDNSFinding{Address: ip.Address, LinodeID: ip.LinodeID}
+		add := func(sev LintSeverity, check string, format string, args ...interface{}) {
+			f.Severity = sev
+			f.Check = check
+			f.Message = fmt.Sprintf(format, args...)
+			fs = append(fs, f)
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(ip.RDNSName, "."))
+		f.Name = name
+
+		if isDefaultRDNS(name) {
+			add(LintWarning, RDNSDefault, "Linode %d has the default reverse DNS name %q", ip.LinodeID, name)
+			continue
+		}
+
+		d, ok := bestDomain(domains, name)
+		if !ok {
+			add(LintInfo, RDNSUnhosted, "%s is not in a hosted domain, so cannot be checked", name)
+			continue
+		}
+		f.DomainID = d.ID
+
+		recs := forward[name]
+		if len(recs) == 0 {
+			add(LintError, RDNSMissing, "%s has no A or AAAA record", name)
+			continue
+		}
+
+		addr := net.ParseIP(ip.Address)
+		var targets []string
+		match := false
+		for _, rec := range recs {
+			if rec.ip.Equal(addr) {
+				match = true
+				break
+			}
+			targets = append(targets, rec.ip.String())
+		}
+		if !match {
+			add(LintError, RDNSMismatch, "%s resolves to %s, not %s",
+				name, strings.Join(targets, ", "), ip.Address)
+		}
+	}
+
+	for _, rec := range records {
+		if owned[rec.ip.String()] {
+			continue
+		}
+		f := RDNSFinding{
+			Severity:   LintWarning,
+			Check:      RDNSForeignIP,
+			Address:    rec.ip.String(),
+			Name:       rec.fqdn,
+			DomainID:   rec.domainID,
+			ResourceID: rec.resourceID,
+			Message:    fmt.Sprintf("%s points at an IP not assigned to any Linode or NodeBalancer", rec.fqdn),
+		}
+		if rec.ip.To4() == nil {
+			f.Severity = LintInfo
+			f.Message = fmt.Sprintf("%s points at an IPv6 address that cannot be checked", rec.fqdn)
+		}
+		fs = append(fs, f)
+	}
+
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Address != b.Address {
+			return a.Address < b.Address
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.Name < b.Name
+	})
+
+	return fs
+}
+
+func isDefaultRDNS(name string) bool {
+	if name == "" {
+		return true
+	}
+	for _, suffix := range defaultRDNSSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
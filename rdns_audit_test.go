@@ -0,0 +1,83 @@
+// +build !integration
+
+package linode
+
+import (
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestAuditRDNS(t *testing.T) {
+	var responses []mockAPIResponse
+
+	responses = append(responses, newMockAPIResponse("linode.ip.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"IPADDRESSID":1,"LINODEID":10,"ISPUBLIC":1,"IPADDRESS":"192.0.2.1","RDNS_NAME":"web.example.com"},`+
+			`{"IPADDRESSID":2,"LINODEID":11,"ISPUBLIC":1,"IPADDRESS":"192.0.2.2","RDNS_NAME":"li1-2.members.linode.com"},`+
+			`{"IPADDRESSID":3,"LINODEID":12,"ISPUBLIC":1,"IPADDRESS":"192.0.2.3","RDNS_NAME":"db.example.com"},`+
+			`{"IPADDRESSID":4,"LINODEID":13,"ISPUBLIC":1,"IPADDRESS":"192.0.2.4","RDNS_NAME":"mail.example.com"},`+
+			`{"IPADDRESSID":5,"LINODEID":14,"ISPUBLIC":1,"IPADDRESS":"192.0.2.5","RDNS_NAME":"host.example.org"},`+
+			`{"IPADDRESSID":6,"LINODEID":10,"ISPUBLIC":0,"IPADDRESS":"192.168.128.1","RDNS_NAME":""}`+
+			`],"ACTION":"linode.ip.list"}`))
+	responses = append(responses, newMockAPIResponse("nodebalancer.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[{"NODEBALANCERID":5,"LABEL":"web","ADDRESS4":"203.0.113.5","ADDRESS6":"2001:db8::5"}],"ACTION":"nodebalancer.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","AXFR_IPS":"none"},`+
+			`{"DOMAINID":31,"DOMAIN":"example.net","TYPE":"slave","MASTER_IPS":"192.0.2.9","AXFR_IPS":"none"}`+
+			`],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+
+			`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"web","TARGET":"192.0.2.1"},`+
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"mail","TARGET":"192.0.2.1"},`+
+			`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"a","NAME":"old","TARGET":"198.51.100.7"},`+
+			`{"RESOURCEID":4,"DOMAINID":30,"TYPE":"a","NAME":"internal","TARGET":"192.168.128.1"},`+
+			`{"RESOURCEID":5,"DOMAINID":30,"TYPE":"cname","NAME":"www","TARGET":"web.example.com"},`+
+			`{"RESOURCEID":6,"DOMAINID":30,"TYPE":"a","NAME":"lb","TARGET":"203.0.113.5"},`+
+			`{"RESOURCEID":7,"DOMAINID":30,"TYPE":"aaaa","NAME":"lb","TARGET":"2001:db8::5"},`+
+			`{"RESOURCEID":8,"DOMAINID":30,"TYPE":"aaaa","NAME":"web","TARGET":"2001:db8::1"}`+
+			`],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	fs, err := c.AuditRDNS()
+	require.NoError(t, err)
+
+	var got []string
+	for _, f := range fs {
+		got = append(got, f.Check+" "+f.Address+" "+f.Name)
+	}
+	assert.Equal(t, []string{
+		"rdns-missing 192.0.2.3 db.example.com",
+		"rdns-mismatch 192.0.2.4 mail.example.com",
+		"default-rdns 192.0.2.2 li1-2.members.linode.com",
+		"foreign-ip 198.51.100.7 old.example.com",
+		"rdns-unhosted 192.0.2.5 host.example.org",
+		"foreign-ip 2001:db8::1 web.example.com",
+	}, got)
+
+	assert.Equal(t, LintError, fs.Worst())
+	assert.Equal(t, 13, fs[1].LinodeID)
+	assert.Equal(t, 30, fs[1].DomainID)
+	assert.Equal(t, "error: rdns-mismatch: 192.0.2.4: mail.example.com resolves to 192.0.2.1, not 192.0.2.4", fs[1].String())
+	assert.Equal(t, 3, fs[3].ResourceID)
+	assert.Equal(t, LintInfo, fs[5].Severity)
+}
+
+func TestAuditRDNSError(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.AuditRDNS()
+	assert.Error(t, err)
+}
+
+func TestIsDefaultRDNS(t *testing.T) {
+	assert.True(t, isDefaultRDNS(""))
+	assert.True(t, isDefaultRDNS("li1-2.members.linode.com"))
+	assert.True(t, isDefaultRDNS("192-0-2-1.ip.linodeusercontent.com"))
+	assert.False(t, isDefaultRDNS("web.example.com"))
+}
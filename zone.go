@@ -0,0 +1,734 @@
+package linode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// allowedTTLs are the only TTLs the DNS manager stores.  Any other value is
+// rounded up to the next one.
+var allowedTTLs = []int{300, 3600, 7200, 14400, 28800, 57600, 86400, 172800,
+	345600, 604800, 1209600, 2419200}
+
+// snapTTL rounds ttl up to the next TTL the DNS manager accepts, the same way
+// the API does, so 301 becomes 3600.  Values above the largest are capped to
+// it.  Zero, meaning the domain default, is left alone.
+func snapTTL(ttl int) int {
+	if ttl <= 0 {
+		return 0
+	}
+
+	for _, t := range allowedTTLs {
+		if ttl <= t {
+			return t
+		}
+	}
+
+	return allowedTTLs[len(allowedTTLs)-1]
+}
+
+// ZoneOwnerPrefix is prepended to a record name to form the name of the TXT
+// record marking the name as managed by an owner.  See ZonePlanOpts.
+const ZoneOwnerPrefix = "_linode-owner"
+
+// ZonePlanOpts contains the optional arguments to PlanZone().
+type ZonePlanOpts struct {
+	// Owner, if set, limits the plan to names managed by the owner, so
+	// records created by hand or by other tools are left alone.  A name is
+	// managed by Owner when it has a TXT record at ZoneOwnerPrefix + "." +
+	// name (or just ZoneOwnerPrefix for the apex) containing
+	// "heritage=linode,owner=<Owner>".  The plan creates and deletes these
+	// markers along with the records they cover.
+	//
+	// If Owner is empty, every record in the domain is managed, and records
+	// missing from the spec are deleted.
+	Owner string
+}
+
+// ZoneChange is a single change in a ZonePlan.
+type ZoneChange struct {
+	Kind ChangeKind
+
+	// Resource is "domain" or "record".
+	Resource string
+
+	Domain string
+
+	// Record is the record being changed, as it exists before the change.
+	// For creates, it is the record to be created.
+	Record RecordSpec
+
+	Diffs []FieldDiff
+
+	rank  int
+	apply func(*zoneApplier) error
+}
+
+func (zc ZoneChange) String() string {
+	var buf bytes.Buffer
+	if zc.Resource == "domain" {
+		fmt.Fprintf(&buf, "%s domain %s", changeSymbols[zc.Kind], zc.Domain)
+	} else {
+		name := zc.Record.Name
+		if name == "" {
+			name = "@"
+		}
+		fmt.Fprintf(&buf, "%s record %s %s %s", changeSymbols[zc.Kind], name,
+			zc.Record.Type, zc.Record.Target)
+	}
+	for _, d := range zc.Diffs {
+		fmt.Fprintf(&buf, "\n    %s: %q -> %q", d.Field, d.Old, d.New)
+	}
+
+	return buf.String()
+}
+
+// ZonePlan is the set of changes needed to bring a domain in line with a
+// DomainSpec.  It should be created by a call to PlanZone().
+type ZonePlan struct {
+	Domain  string
+	Changes []ZoneChange
+
+	domainID int
+}
+
+// Empty returns true if the plan has no changes.
+func (p *ZonePlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+func (p *ZonePlan) String() string {
+	if p.Empty() {
+		return "No changes.\n"
+	}
+
+	var buf bytes.Buffer
+	for _, c := range p.Changes {
+		buf.WriteString(c.String())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+type zoneRecord struct {
+	id   int
+	spec RecordSpec
+}
+
+type zoneKey struct {
+	name  string
+	rType string
+}
+
+// PlanZone compares the records and SOA settings in spec with the domain of
+// the same name, and returns the smallest set of changes needed to make them
+// match.  The domain is created if it does not exist.  Fields left empty in
+// spec are not managed.
+//
+// Record names may be relative to the domain or fully qualified, and TTLs are
+// rounded the same way the API rounds them, so that an applied plan is
+// followed by an empty one.  Nothing is changed until the plan is passed to
+// ApplyZone().
+func (c *Client) PlanZone(spec DomainSpec, opts ZonePlanOpts) (*ZonePlan, error) {
+	err := validateDomainSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ZonePlan{Domain: spec.Domain}
+
+	var have []zoneRecord
+	var domain *Domain
+	for i, d := range domains {
+		if strings.EqualFold(d.Domain, spec.Domain) {
+			domain = &domains[i]
+			break
+		}
+	}
+
+	if domain == nil {
+		plan.Changes = append(plan.Changes, planDomainCreate(spec))
+	} else {
+		plan.domainID = domain.ID
+
+		if zc, ok := planDomainUpdate(spec, *domain); ok {
+			plan.Changes = append(plan.Changes, zc)
+		}
+
+		resources, err := c.DomainResourceList(domain.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range resources {
+			have = append(have, zoneRecord{r.ID, normalizeRecord(recordSpecFor(r), spec.Domain)})
+		}
+	}
+
+	var want []RecordSpec
+	for _, rs := range spec.Records {
+		want = append(want, normalizeRecord(rs, spec.Domain))
+	}
+
+	if opts.Owner != "" {
+		have, want, err = ownedRecords(have, want, opts.Owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plan.Changes = append(plan.Changes, planRecords(have, want, opts.Owner)...)
+
+	return plan, nil
+}
+
+// findDomain returns the master domain that fqdn belongs to, preferring the
+// longest match so that delegated subdomains win.
+func (c *Client) findDomain(fqdn string) (Domain, error) {
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return Domain{}, err
+	}
+
+	best, ok := bestDomain(domains, fqdn)
+	if !ok {
+		return Domain{}, fmt.Errorf("dns: no domain found for %s", fqdn)
+	}
+
+	return best, nil
+}
+
+// bestDomain is findDomain() for an already fetched list of domains.
+func bestDomain(domains []Domain, fqdn string) (Domain, bool) {
+	var best Domain
+	for _, d := range domains {
+		if !strings.EqualFold(d.Type, "master") {
+			continue
+		}
+		if _, ok := zoneRelative(fqdn, d.Domain); ok && len(d.Domain) > len(best.Domain) {
+			best = d
+		}
+	}
+	return best, best.ID != 0
+}
+
+func validateDomainSpec(spec DomainSpec) error {
+	if spec.Domain == "" {
+		return errors.New("zone: domain must not be empty")
+	}
+
+	for _, rs := range spec.Records {
+		if rs.Type == "" || rs.Target == "" {
+			return fmt.Errorf("zone: %s: every record must have a type and target", spec.Domain)
+		}
+	}
+
+	return nil
+}
+
+func planDomainCreate(spec DomainSpec) ZoneChange {
+	dType := spec.Type
+	if dType == "" {
+		dType = "master"
+	}
+
+	var opts DomainCreateOpts
+	diffs := []FieldDiff{{"type", "", dType}}
+
+	if spec.Description != "" {
+		opts.Description = String(spec.Description)
+		diffs = append(diffs, FieldDiff{"description", "", spec.Description})
+	}
+	if spec.SOAEmail != "" {
+		opts.SOAEmail = String(spec.SOAEmail)
+		diffs = append(diffs, FieldDiff{"soa_email", "", spec.SOAEmail})
+	}
+	if spec.RefreshSec != 0 {
+		opts.RefreshSec = Int(snapTTL(spec.RefreshSec))
+		diffs = append(diffs, FieldDiff{"refresh_sec", "", strconv.Itoa(*opts.RefreshSec)})
+	}
+	if spec.RetrySec != 0 {
+		opts.RetrySec = Int(snapTTL(spec.RetrySec))
+		diffs = append(diffs, FieldDiff{"retry_sec", "", strconv.Itoa(*opts.RetrySec)})
+	}
+	if spec.ExpireSec != 0 {
+		opts.ExpireSec = Int(snapTTL(spec.ExpireSec))
+		diffs = append(diffs, FieldDiff{"expire_sec", "", strconv.Itoa(*opts.ExpireSec)})
+	}
+	if spec.TTLSec != 0 {
+		opts.TTLSec = Int(snapTTL(spec.TTLSec))
+		diffs = append(diffs, FieldDiff{"ttl_sec", "", strconv.Itoa(*opts.TTLSec)})
+	}
+	if spec.DisplayGroup != "" {
+		opts.DisplayGroup = String(spec.DisplayGroup)
+		diffs = append(diffs, FieldDiff{"display_group", "", spec.DisplayGroup})
+	}
+	if spec.Status != 0 {
+		opts.Status = Int(spec.Status)
+		diffs = append(diffs, FieldDiff{"status", "", strconv.Itoa(spec.Status)})
+	}
+	if len(spec.MasterIPs) > 0 {
+		opts.MasterIPs = String(ipListString(spec.MasterIPs))
+		diffs = append(diffs, FieldDiff{"master_ips", "", *opts.MasterIPs})
+	}
+	if len(spec.AXFRIPs) > 0 {
+		opts.AXFRIPs = String(ipListString(spec.AXFRIPs))
+		diffs = append(diffs, FieldDiff{"axfr_ips", "", *opts.AXFRIPs})
+	}
+
+	return ZoneChange{
+		Kind:     ChangeCreate,
+		Resource: "domain",
+		Domain:   spec.Domain,
+		Diffs:    diffs,
+		apply: func(a *zoneApplier) error {
+			id, err := a.c.DomainCreate(spec.Domain, dType, opts)
+			if err != nil {
+				return err
+			}
+			a.domainID = id
+			return nil
+		},
+	}
+}
+
+func planDomainUpdate(spec DomainSpec, d Domain) (ZoneChange, bool) {
+	var opts DomainUpdateOpts
+	var diffs []FieldDiff
+
+	if spec.Type != "" && !strings.EqualFold(spec.Type, d.Type) {
+		opts.Type = String(spec.Type)
+		diffs = append(diffs, FieldDiff{"type", d.Type, spec.Type})
+	}
+	// Description can only be set when the domain is created, since
+	// DomainUpdate() cannot change it.
+	if spec.SOAEmail != "" && spec.SOAEmail != d.SOAEmail {
+		opts.SOAEmail = String(spec.SOAEmail)
+		diffs = append(diffs, FieldDiff{"soa_email", d.SOAEmail, spec.SOAEmail})
+	}
+	if v := snapTTL(spec.RefreshSec); v != 0 && v != d.RefreshSec {
+		opts.RefreshSec = Int(v)
+		diffs = append(diffs, intDiff("refresh_sec", d.RefreshSec, v))
+	}
+	if v := snapTTL(spec.RetrySec); v != 0 && v != d.RetrySec {
+		opts.RetrySec = Int(v)
+		diffs = append(diffs, intDiff("retry_sec", d.RetrySec, v))
+	}
+	if v := snapTTL(spec.ExpireSec); v != 0 && v != d.ExpireSec {
+		opts.ExpireSec = Int(v)
+		diffs = append(diffs, intDiff("expire_sec", d.ExpireSec, v))
+	}
+	if v := snapTTL(spec.TTLSec); v != 0 && v != d.TTLSec {
+		opts.TTLSec = Int(v)
+		diffs = append(diffs, intDiff("ttl_sec", d.TTLSec, v))
+	}
+	if spec.DisplayGroup != "" && spec.DisplayGroup != d.DisplayGroup {
+		opts.DisplayGroup = String(spec.DisplayGroup)
+		diffs = append(diffs, FieldDiff{"display_group", d.DisplayGroup, spec.DisplayGroup})
+	}
+	if spec.Status != 0 && spec.Status != d.Status {
+		opts.Status = Int(spec.Status)
+		diffs = append(diffs, intDiff("status", d.Status, spec.Status))
+	}
+	if old, new := ipListString(splitIPList(d.MasterIPs)), ipListString(spec.MasterIPs); new != "" && old != new {
+		opts.MasterIPs = String(new)
+		diffs = append(diffs, FieldDiff{"master_ips", old, new})
+	}
+	if old, new := ipListString(splitIPList(d.AXFRIPs)), ipListString(spec.AXFRIPs); new != "" && old != new {
+		opts.AXFRIPs = String(new)
+		diffs = append(diffs, FieldDiff{"axfr_ips", old, new})
+	}
+
+	if len(diffs) == 0 {
+		return ZoneChange{}, false
+	}
+
+	return ZoneChange{
+		Kind:     ChangeUpdate,
+		Resource: "domain",
+		Domain:   spec.Domain,
+		Diffs:    diffs,
+		apply: func(a *zoneApplier) error {
+			return a.c.DomainUpdate(a.domainID, opts)
+		},
+	}, true
+}
+
+// ipListString sorts ips and joins them the way the API expects.
+func ipListString(ips []string) string {
+	sorted := append([]string(nil), ips...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ";")
+}
+
+// normalizeRecord puts a record in the form returned by the API, so that
+// records can be compared field by field.
+func normalizeRecord(rs RecordSpec, domain string) RecordSpec {
+	rs.Type = strings.ToUpper(rs.Type)
+	rs.Name = relativeName(rs.Name, domain)
+	rs.TTLSec = snapTTL(rs.TTLSec)
+
+	switch rs.Type {
+	case "CNAME", "MX", "NS", "SRV":
+		rs.Target = strings.TrimSuffix(rs.Target, ".")
+	}
+
+	if rs.Type != "MX" && rs.Type != "SRV" {
+		rs.Priority = 0
+	}
+	if rs.Type != "SRV" {
+		rs.Weight = 0
+		rs.Port = 0
+		rs.Protocol = ""
+	}
+
+	return rs
+}
+
+// relativeName returns name relative to domain, or an empty string for the
+// apex.
+func relativeName(name string, domain string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+
+	if name == "@" || name == domain {
+		return ""
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+func zoneOwnerName(name string) string {
+	if name == "" {
+		return ZoneOwnerPrefix
+	}
+	return ZoneOwnerPrefix + "." + name
+}
+
+// zoneOwnedName is the inverse of zoneOwnerName.
+func zoneOwnedName(marker string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(marker, ZoneOwnerPrefix), ".")
+}
+
+func zoneOwnerValue(owner string) string {
+	return "heritage=linode,owner=" + owner
+}
+
+func isOwnerRecord(rs RecordSpec) bool {
+	return rs.Type == "TXT" && (rs.Name == ZoneOwnerPrefix ||
+		strings.HasPrefix(rs.Name, ZoneOwnerPrefix+"."))
+}
+
+// ownedRecords limits have to the names managed by owner, and adds an owner
+// marker to want for every wanted name.  It is an error to want a name that
+// has records owned by someone else.
+func ownedRecords(have []zoneRecord, want []RecordSpec, owner string) ([]zoneRecord, []RecordSpec, error) {
+	marker := zoneOwnerValue(owner)
+
+	owned := make(map[string]bool)
+	for _, r := range have {
+		if isOwnerRecord(r.spec) && r.spec.Target == marker {
+			owned[r.spec.Name] = true
+		}
+	}
+
+	taken := make(map[string]bool)
+	var mine []zoneRecord
+	for _, r := range have {
+		if owned[zoneOwnerName(r.spec.Name)] || (isOwnerRecord(r.spec) && r.spec.Target == marker) {
+			mine = append(mine, r)
+		} else if isOwnerRecord(r.spec) {
+			taken[zoneOwnedName(r.spec.Name)] = true
+		} else {
+			taken[r.spec.Name] = true
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, rs := range want {
+		if isOwnerRecord(rs) {
+			return nil, nil, fmt.Errorf("zone: %s records are reserved for ownership markers", ZoneOwnerPrefix)
+		}
+		if taken[rs.Name] && !owned[zoneOwnerName(rs.Name)] {
+			return nil, nil, fmt.Errorf("zone: %q has records not managed by %s", rs.Name, owner)
+		}
+		names[rs.Name] = true
+	}
+
+	for name := range names {
+		want = append(want, RecordSpec{Type: "TXT", Name: zoneOwnerName(name), Target: marker})
+	}
+
+	return mine, want, nil
+}
+
+// Changes are ordered so that names are claimed before their records are
+// created and released after their records are deleted.
+const (
+	rankDomain = iota
+	rankMarkerCreate
+	rankRecord
+	rankMarkerDelete
+)
+
+var kindOrder = map[ChangeKind]int{
+	ChangeUpdate: 0,
+	ChangeDelete: 1,
+	ChangeCreate: 2,
+}
+
+// planRecords matches have with want by name and type.  Identical records are
+// left alone, then records are paired for updates, preferring ones with the
+// same target, and whatever is left over is created or deleted.
+func planRecords(have []zoneRecord, want []RecordSpec, owner string) []ZoneChange {
+	haveByKey := make(map[zoneKey][]zoneRecord)
+	wantByKey := make(map[zoneKey][]RecordSpec)
+	keys := make(map[zoneKey]bool)
+
+	for _, r := range have {
+		k := zoneKey{r.spec.Name, r.spec.Type}
+		haveByKey[k] = append(haveByKey[k], r)
+		keys[k] = true
+	}
+	for _, rs := range want {
+		k := zoneKey{rs.Name, rs.Type}
+		wantByKey[k] = append(wantByKey[k], rs)
+		keys[k] = true
+	}
+
+	var changes []ZoneChange
+	for k := range keys {
+		changes = append(changes, planRecordSet(haveByKey[k], wantByKey[k])...)
+	}
+
+	for i := range changes {
+		zc := &changes[i]
+		zc.rank = rankRecord
+		if owner != "" && isOwnerRecord(zc.Record) {
+			switch zc.Kind {
+			case ChangeCreate:
+				zc.rank = rankMarkerCreate
+			case ChangeDelete:
+				zc.rank = rankMarkerDelete
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.Record.Name != b.Record.Name {
+			return a.Record.Name < b.Record.Name
+		}
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Record.Type != b.Record.Type {
+			return a.Record.Type < b.Record.Type
+		}
+		return a.Record.Target < b.Record.Target
+	})
+
+	return changes
+}
+
+func planRecordSet(have []zoneRecord, want []RecordSpec) []ZoneChange {
+	sort.Slice(have, func(i, j int) bool {
+		if have[i].spec.Target != have[j].spec.Target {
+			return have[i].spec.Target < have[j].spec.Target
+		}
+		return have[i].id < have[j].id
+	})
+	sortRecordSpecs(want)
+
+	used := make([]bool, len(have))
+	var unmatched []RecordSpec
+
+	// Identical records need no change.
+	for _, rs := range want {
+		found := false
+		for i, r := range have {
+			if !used[i] && r.spec == rs {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, rs)
+		}
+	}
+
+	var changes []ZoneChange
+	var creates []RecordSpec
+
+	// Records with the same target are updated in place first.
+	for _, rs := range unmatched {
+		found := false
+		for i, r := range have {
+			if !used[i] && r.spec.Target == rs.Target {
+				used[i] = true
+				found = true
+				changes = append(changes, planRecordUpdate(r, rs))
+				break
+			}
+		}
+		if !found {
+			creates = append(creates, rs)
+		}
+	}
+
+	// Then any remaining records are reused for a new target.
+	for _, rs := range creates {
+		found := false
+		for i, r := range have {
+			if !used[i] {
+				used[i] = true
+				found = true
+				changes = append(changes, planRecordUpdate(r, rs))
+				break
+			}
+		}
+		if !found {
+			changes = append(changes, planRecordCreate(rs))
+		}
+	}
+
+	for i, r := range have {
+		if !used[i] {
+			changes = append(changes, planRecordDelete(r))
+		}
+	}
+
+	return changes
+}
+
+func planRecordCreate(rs RecordSpec) ZoneChange {
+	opts := DomainResourceCreateOpts{
+		Name:   String(rs.Name),
+		Target: String(rs.Target),
+	}
+
+	var diffs []FieldDiff
+	if rs.TTLSec != 0 {
+		opts.TTLSec = Int(rs.TTLSec)
+		diffs = append(diffs, FieldDiff{"ttl_sec", "", strconv.Itoa(rs.TTLSec)})
+	}
+	if rs.Priority != 0 {
+		opts.Priority = Int(rs.Priority)
+		diffs = append(diffs, FieldDiff{"priority", "", strconv.Itoa(rs.Priority)})
+	}
+	if rs.Weight != 0 {
+		opts.Weight = Int(rs.Weight)
+		diffs = append(diffs, FieldDiff{"weight", "", strconv.Itoa(rs.Weight)})
+	}
+	if rs.Port != 0 {
+		opts.Port = Int(rs.Port)
+		diffs = append(diffs, FieldDiff{"port", "", strconv.Itoa(rs.Port)})
+	}
+	if rs.Protocol != "" {
+		opts.Protocol = String(rs.Protocol)
+		diffs = append(diffs, FieldDiff{"protocol", "", rs.Protocol})
+	}
+
+	return ZoneChange{
+		Kind:     ChangeCreate,
+		Resource: "record",
+		Record:   rs,
+		Diffs:    diffs,
+		apply: func(a *zoneApplier) error {
+			_, err := a.c.DomainResourceCreate(a.domainID, strings.ToLower(rs.Type), opts)
+			return err
+		},
+	}
+}
+
+func planRecordUpdate(r zoneRecord, rs RecordSpec) ZoneChange {
+	var opts DomainResourceUpdateOpts
+	var diffs []FieldDiff
+	old := r.spec
+
+	if old.Target != rs.Target {
+		opts.Target = String(rs.Target)
+		diffs = append(diffs, FieldDiff{"target", old.Target, rs.Target})
+	}
+	if old.TTLSec != rs.TTLSec {
+		opts.TTLSec = Int(rs.TTLSec)
+		diffs = append(diffs, intDiff("ttl_sec", old.TTLSec, rs.TTLSec))
+	}
+	if old.Priority != rs.Priority {
+		opts.Priority = Int(rs.Priority)
+		diffs = append(diffs, intDiff("priority", old.Priority, rs.Priority))
+	}
+	if old.Weight != rs.Weight {
+		opts.Weight = Int(rs.Weight)
+		diffs = append(diffs, intDiff("weight", old.Weight, rs.Weight))
+	}
+	if old.Port != rs.Port {
+		opts.Port = Int(rs.Port)
+		diffs = append(diffs, intDiff("port", old.Port, rs.Port))
+	}
+	if old.Protocol != rs.Protocol {
+		opts.Protocol = String(rs.Protocol)
+		diffs = append(diffs, FieldDiff{"protocol", old.Protocol, rs.Protocol})
+	}
+
+	id := r.id
+	return ZoneChange{
+		Kind:     ChangeUpdate,
+		Resource: "record",
+		Record:   old,
+		Diffs:    diffs,
+		apply: func(a *zoneApplier) error {
+			opts.DomainID = Int(a.domainID)
+			return a.c.DomainResourceUpdate(id, opts)
+		},
+	}
+}
+
+func planRecordDelete(r zoneRecord) ZoneChange {
+	id := r.id
+	return ZoneChange{
+		Kind:     ChangeDelete,
+		Resource: "record",
+		Record:   r.spec,
+		apply: func(a *zoneApplier) error {
+			return a.c.DomainResourceDelete(a.domainID, id)
+		},
+	}
+}
+
+type zoneApplier struct {
+	c        *Client
+	domainID int
+}
+
+// ApplyZone makes the changes in plan, in order, stopping at the first
+// error.
+func (c *Client) ApplyZone(plan *ZonePlan) error {
+	_, err := c.applyZone(plan)
+	return err
+}
+
+// applyZone applies plan and returns the ID of the domain.
+func (c *Client) applyZone(plan *ZonePlan) (int, error) {
+	a := &zoneApplier{c: c, domainID: plan.domainID}
+
+	for _, zc := range plan.Changes {
+		err := zc.apply(a)
+		if err != nil {
+			return a.domainID, fmt.Errorf("zone: %s: %s", strings.SplitN(zc.String(), "\n", 2)[0], err)
+		}
+	}
+
+	return a.domainID, nil
+}
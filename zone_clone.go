@@ -0,0 +1,141 @@
+package linode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// CloneFailure is a record that CloneDomain() could not copy.
+type CloneFailure struct {
+	Resource DomainResource
+	Err      error
+}
+
+// CloneDomainError is returned by CloneDomain() when the domain was created
+// but some of its records could not be copied.
+type CloneDomainError struct {
+	DomainID int
+	Failed   []CloneFailure
+}
+
+func (e *CloneDomainError) Error() string {
+	f := e.Failed[0]
+	msg := fmt.Sprintf("dns: copying %s %s to domain %d: %s",
+		strings.ToUpper(f.Resource.Type), f.Resource.Name, e.DomainID, f.Err)
+	if len(e.Failed) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Failed)-1)
+	}
+	return msg
+}
+
+// CloneDomain creates a domain called newName with the same settings and
+// records as an existing domain, and returns its ID.  Any setting in opts
+// overrides the one copied from the source.
+//
+// Record targets and an SOA email within the source domain are rewritten to
+// point into the new domain, so that, for example, a CNAME for www pointing
+// at old.com points at new.com in the copy.  TXT records are copied as is.
+//
+// If the domain is created but some records cannot be copied, the rest are
+// still copied, and the new ID is returned along with a *CloneDomainError
+// listing the failures.
+func (c *Client) CloneDomain(srcDomainID int, newName string, opts DomainCreateOpts) (int, error) {
+	newName = strings.ToLower(strings.TrimSuffix(newName, "."))
+	if newName == "" {
+		return 0, errors.New("dns: new domain name is required")
+	}
+
+	domains, err := c.DomainList(Int(srcDomainID))
+	if err != nil {
+		return 0, err
+	}
+	if len(domains) != 1 {
+		return 0, fmt.Errorf("dns: no domain with ID %d", srcDomainID)
+	}
+	src := domains[0]
+
+	resources, err := c.DomainResourceList(srcDomainID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := c.DomainCreate(newName, src.Type, cloneDomainOpts(src, newName, opts))
+	if err != nil {
+		return 0, err
+	}
+
+	a := &zoneApplier{c: c, domainID: id}
+	var failed []CloneFailure
+	for _, r := range resources {
+		rs := normalizeRecord(recordSpecFor(r), src.Domain)
+		rs.Target = cloneTarget(rs.Type, rs.Target, src.Domain, newName)
+
+		err = planRecordCreate(rs).apply(a)
+		if err != nil {
+			failed = append(failed, CloneFailure{Resource: r, Err: err})
+		}
+	}
+
+	if len(failed) > 0 {
+		return id, &CloneDomainError{DomainID: id, Failed: failed}
+	}
+	return id, nil
+}
+
+// cloneDomainOpts fills in the settings opts leaves unset from src.
+func cloneDomainOpts(src Domain, newName string, opts DomainCreateOpts) DomainCreateOpts {
+	setString := func(p **string, v string) {
+		if *p == nil && v != "" {
+			*p = String(v)
+		}
+	}
+	setInt := func(p **int, v int) {
+		if *p == nil && v != 0 {
+			*p = Int(v)
+		}
+	}
+
+	email := src.SOAEmail
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		if host, ok := zoneRelative(email[i+1:], src.Domain); ok {
+			email = email[:i+1] + cloneName(host, newName)
+		}
+	}
+
+	setString(&opts.Description, src.Description)
+	setString(&opts.SOAEmail, email)
+	setInt(&opts.RefreshSec, src.RefreshSec)
+	setInt(&opts.RetrySec, src.RetrySec)
+	setInt(&opts.ExpireSec, src.ExpireSec)
+	setInt(&opts.TTLSec, src.TTLSec)
+	setString(&opts.DisplayGroup, src.DisplayGroup)
+	setInt(&opts.Status, src.Status)
+	setString(&opts.MasterIPs, src.MasterIPs)
+	setString(&opts.AXFRIPs, src.AXFRIPs)
+
+	return opts
+}
+
+// cloneTarget rewrites a hostname target within domain to the same name
+// within newDomain.
+func cloneTarget(rType string, target string, domain string, newDomain string) string {
+	switch rType {
+	case "CNAME", "MX", "NS", "SRV":
+	default:
+		return target
+	}
+
+	host, ok := zoneRelative(target, domain)
+	if !ok {
+		return target
+	}
+	return cloneName(host, newDomain)
+}
+
+func cloneName(host string, domain string) string {
+	if host == "" {
+		return domain
+	}
+	return host + "." + domain
+}
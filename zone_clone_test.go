@@ -0,0 +1,109 @@
+// +build !integration
+
+package linode
+
+import (
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func mockCloneSource(records string) []mockAPIResponse {
+	var responses []mockAPIResponse
+
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"old.com","TYPE":"master","DESCRIPTION":"brand",`+
+			`"SOA_EMAIL":"hostmaster@old.com","REFRESH_SEC":0,"RETRY_SEC":0,"EXPIRE_SEC":0,"TTL_SEC":3600,`+
+			`"LPM_DISPLAYGROUP":"web","STATUS":1,"MASTER_IPS":"","AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[`+records+`],"ACTION":"domain.resource.list"}`))
+
+	return responses
+}
+
+func TestCloneDomain(t *testing.T) {
+	responses := mockCloneSource(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"","TARGET":"192.0.2.1","TTL_SEC":0},` +
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"cname","NAME":"www","TARGET":"old.com","TTL_SEC":300},` +
+			`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"mx","NAME":"","TARGET":"mail.old.com","PRIORITY":10},` +
+			`{"RESOURCEID":4,"DOMAINID":30,"TYPE":"cname","NAME":"cdn","TARGET":"cdn.example.net"},` +
+			`{"RESOURCEID":5,"DOMAINID":30,"TYPE":"txt","NAME":"","TARGET":"v=spf1 include:old.com -all"}`)
+
+	params := map[string]string{
+		"Domain":           "new.com",
+		"Type":             "master",
+		"Description":      "brand",
+		"SOA_Email":        "hostmaster@new.com",
+		"TTL_sec":          "3600",
+		"lpm_displayGroup": "launch",
+		"status":           "1",
+	}
+	responses = append(responses, newMockAPIResponse("domain.create", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.create"}`))
+
+	creates := []map[string]string{
+		{"DomainID": "31", "Type": "a", "Name": "", "Target": "192.0.2.1"},
+		{"DomainID": "31", "Type": "cname", "Name": "www", "Target": "new.com", "TTL_sec": "300"},
+		{"DomainID": "31", "Type": "mx", "Name": "", "Target": "mail.new.com", "Priority": "10"},
+		{"DomainID": "31", "Type": "cname", "Name": "cdn", "Target": "cdn.example.net"},
+		{"DomainID": "31", "Type": "txt", "Name": "", "Target": "v=spf1 include:old.com -all"},
+	}
+	for _, p := range creates {
+		responses = append(responses, newMockAPIResponse("domain.resource.create", p,
+			`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.create"}`))
+	}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	id, err := c.CloneDomain(30, "New.com.", DomainCreateOpts{DisplayGroup: String("launch")})
+	require.NoError(t, err)
+	assert.Equal(t, 31, id)
+}
+
+func TestCloneDomainFailures(t *testing.T) {
+	responses := mockCloneSource(
+		`{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"192.0.2.1"},` +
+			`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"api","TARGET":"192.0.2.2"}`)
+	responses = append(responses, newMockAPIResponse("domain.create", map[string]string{"Domain": "new.com"},
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.create"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create", map[string]string{"Name": "www"},
+		`{"ERRORARRAY":[{"ERRORCODE":8,"ERRORMESSAGE":"Limit exceeded"}],"DATA":{},"ACTION":"domain.resource.create"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.create", map[string]string{"Name": "api"},
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":2},"ACTION":"domain.resource.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	id, err := c.CloneDomain(30, "new.com", DomainCreateOpts{})
+	assert.Equal(t, 31, id)
+	require.Error(t, err)
+
+	cErr, ok := err.(*CloneDomainError)
+	require.True(t, ok)
+	assert.Equal(t, 31, cErr.DomainID)
+	require.Len(t, cErr.Failed, 1)
+	assert.Equal(t, 1, cErr.Failed[0].Resource.ID)
+}
+
+func TestCloneDomainMissing(t *testing.T) {
+	responses := []mockAPIResponse{newMockAPIResponse("domain.list", map[string]string{"DomainID": "30"},
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"domain.list"}`)}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	_, err := c.CloneDomain(30, "new.com", DomainCreateOpts{})
+	assert.Error(t, err)
+
+	_, err = c.CloneDomain(30, "", DomainCreateOpts{})
+	assert.Error(t, err)
+}
+
+func TestCloneTarget(t *testing.T) {
+	assert.Equal(t, "new.com", cloneTarget("CNAME", "old.com", "old.com", "new.com"))
+	assert.Equal(t, "a.b.new.com", cloneTarget("SRV", "a.b.old.com", "old.com", "new.com"))
+	assert.Equal(t, "cold.com", cloneTarget("CNAME", "cold.com", "old.com", "new.com"))
+	assert.Equal(t, "old.com", cloneTarget("TXT", "old.com", "old.com", "new.com"))
+}
@@ -0,0 +1,317 @@
+package linode
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// LintSeverity is the severity of a LintFinding.
+type LintSeverity int
+
+// Severities of lint findings, from least to most severe.
+const (
+	LintInfo LintSeverity = iota
+	LintWarning
+	LintError
+)
+
+func (s LintSeverity) String() string {
+	switch s {
+	case LintInfo:
+		return "info"
+	case LintWarning:
+		return "warning"
+	case LintError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Lint checks, as reported in LintFinding.Check.
+const (
+	LintCNAMEConflict   = "cname-conflict"
+	LintCNAMEDangling   = "cname-dangling"
+	LintCNAMEChain      = "cname-chain"
+	LintMXTarget        = "mx-target"
+	LintSRVIncomplete   = "srv-incomplete"
+	LintSRVTarget       = "srv-target"
+	LintTXTLength       = "txt-length"
+	LintAddress         = "address"
+	LintDuplicate       = "duplicate"
+	LintTTL             = "ttl"
+	LintApexUnsupported = "apex"
+)
+
+// LintFinding is a single problem found by LintZone().
+type LintFinding struct {
+	Severity LintSeverity
+	Check    string
+
+	// Name is the record name relative to the domain, or "" for the apex,
+	// and ResourceID is the record's ID, if it has one.
+	Name       string
+	ResourceID int
+
+	Message string
+}
+
+func (f LintFinding) String() string {
+	name := f.Name
+	if name == "" {
+		name = "@"
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", f.Severity, f.Check, name, f.Message)
+}
+
+// LintFindings is the result of LintZone(), ordered from most to least
+// severe.
+type LintFindings []LintFinding
+
+// Worst returns the highest severity in fs, or -1 if fs is empty.
+func (fs LintFindings) Worst() LintSeverity {
+	worst := LintSeverity(-1)
+	for _, f := range fs {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+// Err returns an error listing every finding of at least severity min, or
+// nil if there are none.  It is meant as a gate before applying a zone.
+func (fs LintFindings) Err(min LintSeverity) error {
+	var failed LintFindings
+	for _, f := range fs {
+		if f.Severity >= min {
+			failed = append(failed, f)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &ZoneLintError{Findings: failed}
+}
+
+func (fs LintFindings) String() string {
+	var buf bytes.Buffer
+	for _, f := range fs {
+		buf.WriteString(f.String())
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// ZoneLintError is returned by LintFindings.Err().
+type ZoneLintError struct {
+	Findings LintFindings
+}
+
+func (e *ZoneLintError) Error() string {
+	if len(e.Findings) == 1 {
+		return "zone lint: " + e.Findings[0].String()
+	}
+	return fmt.Sprintf("zone lint: %s (and %d more)", e.Findings[0], len(e.Findings)-1)
+}
+
+type lintRecord struct {
+	id int
+	RecordSpec
+}
+
+// LintZone checks a domain's records for common mistakes:
+//
+//   - CNAMEs sharing a name with other records, or at the apex
+//   - CNAMEs pointing at names in the domain that have no records, or at
+//     other CNAMEs
+//   - MX and SRV targets in the domain with no A or AAAA record
+//   - SRV records with no port, weight, or protocol
+//   - TXT values longer than a single 255-byte string
+//   - invalid A and AAAA addresses, duplicate records, and TTLs the API will
+//     round
+//
+// Targets outside the domain are not checked.  The records may come from
+// DomainResourceList(), or from a zone file via LintDomainSpec().
+func LintZone(d Domain, resources []DomainResource) LintFindings {
+	domain := strings.ToLower(strings.TrimSuffix(d.Domain, "."))
+
+	var records []lintRecord
+	byName := make(map[string][]lintRecord)
+	for _, r := range resources {
+		lr := lintRecord{r.ID, recordSpecFor(r)}
+		lr.Name = strings.ToLower(lr.Name)
+		records = append(records, lr)
+		byName[lr.Name] = append(byName[lr.Name], lr)
+	}
+
+	var fs LintFindings
+	add := func(sev LintSeverity, check string, r lintRecord, format string, args ...interface{}) {
+		fs = append(fs, LintFinding{
+			Severity:   sev,
+			Check:      check,
+			Name:       r.Name,
+			ResourceID: r.id,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	// hasAddress reports whether target has an A or AAAA record, or is
+	// outside the domain and so can't be checked, and whether it is a CNAME.
+	hasAddress := func(target string) (bool, bool) {
+		name, ok := lintTargetName(target, domain)
+		if !ok {
+			return true, false
+		}
+		isCNAME := false
+		for _, r := range byName[name] {
+			if r.Type == "A" || r.Type == "AAAA" {
+				return true, false
+			}
+			if r.Type == "CNAME" {
+				isCNAME = true
+			}
+		}
+		return false, isCNAME
+	}
+
+	seen := make(map[RecordSpec]bool)
+	for _, r := range records {
+		key := normalizeRecord(r.RecordSpec, domain)
+		key.TTLSec = 0
+		if seen[key] {
+			add(LintWarning, LintDuplicate, r, "duplicate %s record for %s", r.Type, r.Target)
+		}
+		seen[key] = true
+
+		if r.TTLSec != 0 && snapTTL(r.TTLSec) != r.TTLSec {
+			add(LintInfo, LintTTL, r, "TTL %d will be rounded up to %d", r.TTLSec, snapTTL(r.TTLSec))
+		}
+
+		switch r.Type {
+		case "A", "AAAA":
+			ip := net.ParseIP(r.Target)
+			if ip == nil || (r.Type == "A") != (ip.To4() != nil) {
+				add(LintError, LintAddress, r, "%q is not a valid %s address", r.Target, r.Type)
+			}
+
+		case "CNAME":
+			if r.Name == "" {
+				add(LintError, LintApexUnsupported, r, "a CNAME cannot be at the apex")
+			}
+			if n := len(byName[r.Name]); n > 1 {
+				add(LintError, LintCNAMEConflict, r, "CNAME shares its name with %d other records", n-1)
+			}
+			if name, ok := lintTargetName(r.Target, domain); ok {
+				targets := byName[name]
+				if len(targets) == 0 {
+					add(LintError, LintCNAMEDangling, r, "CNAME target %s has no records", r.Target)
+				}
+				for _, t := range targets {
+					if t.Type == "CNAME" {
+						add(LintWarning, LintCNAMEChain, r, "CNAME target %s is itself a CNAME", r.Target)
+						break
+					}
+				}
+			}
+
+		case "MX":
+			if ok, isCNAME := hasAddress(r.Target); !ok {
+				if isCNAME {
+					add(LintError, LintMXTarget, r, "MX target %s is a CNAME", r.Target)
+				} else {
+					add(LintError, LintMXTarget, r, "MX target %s has no A or AAAA record", r.Target)
+				}
+			}
+
+		case "SRV":
+			if r.Port == 0 {
+				add(LintError, LintSRVIncomplete, r, "SRV record has no port")
+			}
+			if r.Weight == 0 {
+				add(LintWarning, LintSRVIncomplete, r, "SRV record has no weight")
+			}
+			if r.Protocol == "" {
+				add(LintError, LintSRVIncomplete, r, "SRV record has no protocol")
+			}
+			if r.Target != "." {
+				if ok, _ := hasAddress(r.Target); !ok {
+					add(LintError, LintSRVTarget, r, "SRV target %s has no A or AAAA record", r.Target)
+				}
+			}
+
+		case "TXT":
+			if len(r.Target) > 255 {
+				add(LintError, LintTXTLength, r, "TXT value is %d bytes; a single string is limited to 255", len(r.Target))
+			}
+		}
+	}
+
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.ResourceID < b.ResourceID
+	})
+
+	return fs
+}
+
+// lintTargetName returns target relative to domain, and false if target is
+// outside of it.  Targets without a dot are relative.
+func lintTargetName(target string, domain string) (string, bool) {
+	target = strings.ToLower(strings.TrimSuffix(target, "."))
+	if !strings.Contains(target, ".") {
+		return target, true
+	}
+	return zoneRelative(target, domain)
+}
+
+// LintDomainSpec lints the records in spec, such as one read by
+// ParseZone().
+func LintDomainSpec(spec DomainSpec) LintFindings {
+	d := Domain{Domain: spec.Domain, Type: spec.Type, TTLSec: spec.TTLSec}
+
+	var resources []DomainResource
+	for _, rs := range spec.Records {
+		resources = append(resources, DomainResource{
+			Type:     rs.Type,
+			Name:     relativeName(rs.Name, spec.Domain),
+			Target:   rs.Target,
+			TTLSec:   rs.TTLSec,
+			Priority: rs.Priority,
+			Weight:   rs.Weight,
+			Port:     rs.Port,
+			Protocol: rs.Protocol,
+		})
+	}
+
+	return LintZone(d, resources)
+}
+
+// LintDomain lints a live domain's records.
+func (c *Client) LintDomain(domainID int) (LintFindings, error) {
+	domains, err := c.DomainList(Int(domainID))
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) != 1 {
+		return nil, fmt.Errorf("zone: no domain with ID %d", domainID)
+	}
+
+	resources, err := c.DomainResourceList(domainID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return LintZone(domains[0], resources), nil
+}
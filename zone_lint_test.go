@@ -0,0 +1,87 @@
+// +build !integration
+
+package linode
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestLintZone(t *testing.T) {
+	d := Domain{ID: 30, Domain: "example.com"}
+	resources := []DomainResource{
+		{ID: 1, Type: "a", Name: "www", Target: "192.0.2.1"},
+		{ID: 2, Type: "cname", Name: "www", Target: "web.example.com"},
+		{ID: 3, Type: "cname", Name: "blog", Target: "gone.example.com"},
+		{ID: 4, Type: "cname", Name: "alias", Target: "blog.example.com"},
+		{ID: 5, Type: "mx", Name: "", Target: "mail.example.com", Priority: 10},
+		{ID: 6, Type: "mx", Name: "", Target: "alias", Priority: 20},
+		{ID: 7, Type: "mx", Name: "", Target: "mx.other.net", Priority: 30},
+		{ID: 8, Type: "srv", Name: "_sip._tcp", Target: "sip.example.com", Protocol: "tcp"},
+		{ID: 9, Type: "a", Name: "sip", Target: "192.0.2.2"},
+		{ID: 10, Type: "txt", Name: "", Target: strings.Repeat("x", 256)},
+		{ID: 11, Type: "a", Name: "bad", Target: "2001:db8::1"},
+		{ID: 12, Type: "a", Name: "sip", Target: "192.0.2.2", TTLSec: 500},
+	}
+
+	fs := LintZone(d, resources)
+
+	expected := `error: mx-target: @: MX target mail.example.com has no A or AAAA record
+error: mx-target: @: MX target alias is a CNAME
+error: txt-length: @: TXT value is 256 bytes; a single string is limited to 255
+error: srv-incomplete: _sip._tcp: SRV record has no port
+error: address: bad: "2001:db8::1" is not a valid A address
+error: cname-dangling: blog: CNAME target gone.example.com has no records
+error: cname-conflict: www: CNAME shares its name with 1 other records
+error: cname-dangling: www: CNAME target web.example.com has no records
+warning: srv-incomplete: _sip._tcp: SRV record has no weight
+warning: cname-chain: alias: CNAME target blog.example.com is itself a CNAME
+warning: duplicate: sip: duplicate A record for 192.0.2.2
+info: ttl: sip: TTL 500 will be rounded up to 3600
+`
+	assert.Equal(t, expected, fs.String())
+	assert.Equal(t, LintError, fs.Worst())
+
+	err := fs.Err(LintError)
+	require.Error(t, err)
+	lErr, ok := err.(*ZoneLintError)
+	require.True(t, ok)
+	assert.Len(t, lErr.Findings, 8)
+	assert.Equal(t, "zone lint: error: mx-target: @: MX target mail.example.com has no A or AAAA record (and 7 more)", err.Error())
+}
+
+func TestLintZoneClean(t *testing.T) {
+	spec, err := ParseZone("example.com", strings.NewReader(testZoneFile))
+	require.NoError(t, err)
+
+	fs := LintDomainSpec(spec)
+	assert.Len(t, fs, 0)
+	assert.Equal(t, LintSeverity(-1), fs.Worst())
+	assert.NoError(t, fs.Err(LintInfo))
+}
+
+func TestLintDomain(t *testing.T) {
+	var responses []mockAPIResponse
+
+	params := map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":1,"DOMAINID":30,"TYPE":"cname","NAME":"","TARGET":"www.other.net"}],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	fs, err := c.LintDomain(30)
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+	assert.Equal(t, LintFinding{
+		Severity:   LintError,
+		Check:      LintApexUnsupported,
+		ResourceID: 1,
+		Message:    "a CNAME cannot be at the apex",
+	}, fs[0])
+}
@@ -0,0 +1,239 @@
+// +build !integration
+
+package linode
+
+import (
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestSnapTTL(t *testing.T) {
+	tests := []struct {
+		in  int
+		out int
+	}{
+		{0, 0},
+		{-1, 0},
+		{1, 300},
+		{300, 300},
+		{301, 3600},
+		{1900, 3600},
+		{2000, 3600},
+		{3600, 3600},
+		{86401, 172800},
+		{5000000, 2419200},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, snapTTL(tt.in), "%d", tt.in)
+	}
+}
+
+func mockZoneExisting(records string) []mockAPIResponse {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","SOA_EMAIL":"old@example.com","STATUS":1,"TTL_SEC":300,"MASTER_IPS":"","AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+
+	params = map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[`+records+`],"ACTION":"domain.resource.list"}`))
+
+	return responses
+}
+
+const testZoneRecords = `{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"1.1.1.1","TTL_SEC":300,"PRIORITY":10,"WEIGHT":5,"PORT":80},` +
+	`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"2.2.2.2","TTL_SEC":300,"PRIORITY":10,"WEIGHT":5,"PORT":80},` +
+	`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"cname","NAME":"blog","TARGET":"old.example.com","TTL_SEC":0},` +
+	`{"RESOURCEID":4,"DOMAINID":30,"TYPE":"mx","NAME":"","TARGET":"mail.example.com","PRIORITY":10},` +
+	`{"RESOURCEID":5,"DOMAINID":30,"TYPE":"txt","NAME":"foo","TARGET":"by hand"}`
+
+func testZoneSpec() DomainSpec {
+	return DomainSpec{
+		Domain:   "example.com",
+		SOAEmail: "hostmaster@example.com",
+		TTLSec:   3600,
+		Records: []RecordSpec{
+			{Type: "A", Name: "www.example.com.", Target: "1.1.1.1", TTLSec: 299},
+			{Type: "A", Name: "www", Target: "3.3.3.3", TTLSec: 300},
+			{Type: "CNAME", Name: "blog", Target: "new.example.com."},
+			{Type: "MX", Name: "@", Target: "mail.example.com", Priority: 20},
+			{Type: "a", Name: "api", Target: "4.4.4.4", TTLSec: 3000},
+		},
+	}
+}
+
+func TestPlanZone(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockZoneExisting(testZoneRecords)...)
+
+	params := map[string]string{"DomainID": "30", "SOA_Email": "hostmaster@example.com", "TTL_sec": "3600"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":30},"ACTION":"domain.update"}`))
+	params = map[string]string{"ResourceID": "4", "DomainID": "30", "Priority": "20"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":4},"ACTION":"domain.resource.update"}`))
+	params = map[string]string{"DomainID": "30", "Type": "a", "Name": "api", "Target": "4.4.4.4", "TTL_sec": "3600"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":6},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"ResourceID": "3", "Target": "new.example.com"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":3},"ACTION":"domain.resource.update"}`))
+	params = map[string]string{"DomainID": "30", "ResourceID": "5"}
+	responses = append(responses, newMockAPIResponse("domain.resource.delete", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.delete"}`))
+	params = map[string]string{"ResourceID": "2", "Target": "3.3.3.3"}
+	responses = append(responses, newMockAPIResponse("domain.resource.update", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":2},"ACTION":"domain.resource.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	plan, err := c.PlanZone(testZoneSpec(), ZonePlanOpts{})
+	require.NoError(t, err)
+
+	expected := `~ domain example.com
+    soa_email: "old@example.com" -> "hostmaster@example.com"
+    ttl_sec: "300" -> "3600"
+~ record @ MX mail.example.com
+    priority: "10" -> "20"
++ record api A 4.4.4.4
+    ttl_sec: "" -> "3600"
+~ record blog CNAME old.example.com
+    target: "old.example.com" -> "new.example.com"
+- record foo TXT by hand
+~ record www A 2.2.2.2
+    target: "2.2.2.2" -> "3.3.3.3"
+`
+	assert.Equal(t, expected, plan.String())
+
+	err = c.ApplyZone(plan)
+	require.NoError(t, err)
+}
+
+func TestPlanZoneNoChanges(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockZoneExisting(testZoneRecords)))
+	defer ts.Close()
+
+	spec := DomainSpec{
+		Domain: "EXAMPLE.com",
+		Records: []RecordSpec{
+			{Type: "A", Name: "www", Target: "2.2.2.2", TTLSec: 300},
+			{Type: "A", Name: "www", Target: "1.1.1.1", TTLSec: 300},
+			{Type: "CNAME", Name: "blog", Target: "old.example.com"},
+			{Type: "MX", Target: "mail.example.com.", Priority: 10},
+			{Type: "TXT", Name: "foo", Target: "by hand"},
+		},
+	}
+
+	plan, err := c.PlanZone(spec, ZonePlanOpts{})
+	require.NoError(t, err)
+	assert.True(t, plan.Empty())
+	assert.Equal(t, "No changes.\n", plan.String())
+}
+
+const testZoneOwnedRecords = `{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"1.1.1.1"},` +
+	`{"RESOURCEID":2,"DOMAINID":30,"TYPE":"txt","NAME":"_linode-owner.www","TARGET":"heritage=linode,owner=me"},` +
+	`{"RESOURCEID":3,"DOMAINID":30,"TYPE":"a","NAME":"hand","TARGET":"9.9.9.9"},` +
+	`{"RESOURCEID":4,"DOMAINID":30,"TYPE":"a","NAME":"old","TARGET":"5.5.5.5"},` +
+	`{"RESOURCEID":5,"DOMAINID":30,"TYPE":"txt","NAME":"_linode-owner.old","TARGET":"heritage=linode,owner=me"},` +
+	`{"RESOURCEID":6,"DOMAINID":30,"TYPE":"txt","NAME":"_linode-owner.theirs","TARGET":"heritage=linode,owner=you"}`
+
+func TestPlanZoneOwner(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, mockZoneExisting(testZoneOwnedRecords)))
+	defer ts.Close()
+
+	spec := DomainSpec{
+		Domain: "example.com",
+		Records: []RecordSpec{
+			{Type: "A", Name: "www", Target: "1.1.1.2"},
+			{Type: "A", Name: "api", Target: "4.4.4.4"},
+		},
+	}
+
+	plan, err := c.PlanZone(spec, ZonePlanOpts{Owner: "me"})
+	require.NoError(t, err)
+
+	expected := `+ record _linode-owner.api TXT heritage=linode,owner=me
++ record api A 4.4.4.4
+- record old A 5.5.5.5
+~ record www A 1.1.1.1
+    target: "1.1.1.1" -> "1.1.1.2"
+- record _linode-owner.old TXT heritage=linode,owner=me
+`
+	assert.Equal(t, expected, plan.String())
+}
+
+func TestPlanZoneOwnerConflict(t *testing.T) {
+	for _, name := range []string{"hand", "theirs", "_linode-owner.www"} {
+		c, ts := clientFor(newMockAPIServer(t, mockZoneExisting(testZoneOwnedRecords)))
+
+		spec := DomainSpec{
+			Domain:  "example.com",
+			Records: []RecordSpec{{Type: "TXT", Name: name, Target: "x"}},
+		}
+
+		_, err := c.PlanZone(spec, ZonePlanOpts{Owner: "me"})
+		assert.Error(t, err, name)
+
+		ts.Close()
+	}
+}
+
+func TestPlanZoneCreate(t *testing.T) {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[],"ACTION":"domain.list"}`))
+
+	params = map[string]string{"Domain": "example.com", "Type": "master", "SOA_Email": "hostmaster@example.com"}
+	responses = append(responses, newMockAPIResponse("domain.create", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "a", "Name": "", "Target": "1.1.1.1"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	spec := DomainSpec{
+		Domain:   "example.com",
+		SOAEmail: "hostmaster@example.com",
+		Records:  []RecordSpec{{Type: "A", Target: "1.1.1.1"}},
+	}
+
+	plan, err := c.PlanZone(spec, ZonePlanOpts{})
+	require.NoError(t, err)
+
+	expected := `+ domain example.com
+    type: "" -> "master"
+    soa_email: "" -> "hostmaster@example.com"
++ record @ A 1.1.1.1
+`
+	assert.Equal(t, expected, plan.String())
+
+	err = c.ApplyZone(plan)
+	require.NoError(t, err)
+}
+
+func TestPlanZoneInvalid(t *testing.T) {
+	c := NewClient("foo")
+	c.apiCall = apiCallerError
+
+	_, err := c.PlanZone(DomainSpec{}, ZonePlanOpts{})
+	require.Error(t, err)
+
+	_, err = c.PlanZone(DomainSpec{Domain: "example.com", Records: []RecordSpec{{Type: "A"}}}, ZonePlanOpts{})
+	require.Error(t, err)
+	assert.NotEqual(t, "bar", err.Error())
+
+	_, err = c.PlanZone(DomainSpec{Domain: "example.com"}, ZonePlanOpts{})
+	require.Error(t, err)
+	assert.Equal(t, "bar", err.Error())
+}
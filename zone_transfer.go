@@ -0,0 +1,284 @@
+package linode
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// MasterIPList returns the domain's master IPs, which a slave domain
+// transfers the zone from.
+func (d Domain) MasterIPList() ([]net.IP, error) {
+	return parseIPList(d.MasterIPs)
+}
+
+// AXFRIPList returns the IPs allowed to transfer the domain with AXFR.
+func (d Domain) AXFRIPList() ([]net.IP, error) {
+	return parseIPList(d.AXFRIPs)
+}
+
+func parseIPList(s string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, f := range splitIPList(s) {
+		ip := net.ParseIP(f)
+		if ip == nil {
+			return nil, fmt.Errorf("dns: invalid IP %q in %q", f, s)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// formatIPList joins ips the way the API expects, dropping duplicates.
+// "none" clears the list.
+func formatIPList(ips []net.IP) string {
+	var list []string
+	for _, ip := range ips {
+		list = append(list, ip.String())
+	}
+	return joinIPList(list)
+}
+
+// joinIPList is formatIPList() for entries that are kept as written.
+func joinIPList(list []string) string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	if len(out) == 0 {
+		return "none"
+	}
+	return strings.Join(out, ";")
+}
+
+// SlaveDomainCreate creates a slave domain that transfers the zone from
+// masters.  Other settings are taken from opts.
+func (c *Client) SlaveDomainCreate(domain string, masters []net.IP, opts DomainCreateOpts) (int, error) {
+	if len(masters) == 0 {
+		return 0, errors.New("dns: a slave domain needs at least one master")
+	}
+	for _, ip := range masters {
+		if ip == nil {
+			return 0, errors.New("dns: invalid master IP")
+		}
+	}
+
+	opts.MasterIPs = String(formatIPList(masters))
+	return c.DomainCreate(domain, "slave", opts)
+}
+
+// DomainSetMasterIPs replaces a slave domain's master IPs.
+func (c *Client) DomainSetMasterIPs(domainID int, masters []net.IP) error {
+	if len(masters) == 0 {
+		return errors.New("dns: a slave domain needs at least one master")
+	}
+	return c.DomainUpdate(domainID, DomainUpdateOpts{MasterIPs: String(formatIPList(masters))})
+}
+
+// DomainSetAXFRIPs replaces the IPs allowed to transfer a domain.  An empty
+// list disallows transfers.
+func (c *Client) DomainSetAXFRIPs(domainID int, ips []net.IP) error {
+	return c.DomainUpdate(domainID, DomainUpdateOpts{AXFRIPs: String(formatIPList(ips))})
+}
+
+// AddMasterIP adds ip to the master IPs of the given slave domains, or of
+// every slave domain if none are given.  Domains that already list it are
+// left alone.  It returns the IDs of the domains that were changed.
+func (c *Client) AddMasterIP(ip net.IP, domainIDs ...int) ([]int, error) {
+	return c.editIPLists(ip, true, true, domainIDs)
+}
+
+// RemoveMasterIP removes ip from the master IPs of the given slave domains,
+// or of every slave domain if none are given.  It is an error to remove a
+// slave domain's last master, in which case nothing is changed.  It returns
+// the IDs of the domains that were changed.
+func (c *Client) RemoveMasterIP(ip net.IP, domainIDs ...int) ([]int, error) {
+	return c.editIPLists(ip, true, false, domainIDs)
+}
+
+// AddAXFRIP allows ip to transfer the given master domains, or every master
+// domain if none are given.  It returns the IDs of the domains that were
+// changed.
+func (c *Client) AddAXFRIP(ip net.IP, domainIDs ...int) ([]int, error) {
+	return c.editIPLists(ip, false, true, domainIDs)
+}
+
+// RemoveAXFRIP stops ip from transferring the given domains, or every domain
+// if none are given.  It returns the IDs of the domains that were changed.
+func (c *Client) RemoveAXFRIP(ip net.IP, domainIDs ...int) ([]int, error) {
+	return c.editIPLists(ip, false, false, domainIDs)
+}
+
+type ipListEdit struct {
+	domain Domain
+	list   []string
+}
+
+// editIPLists adds or removes ip on the domains' lists.  Entries that are
+// not valid IPs are left as they are, so one bad list does not stop the
+// others from being edited.
+func (c *Client) editIPLists(ip net.IP, master bool, add bool, domainIDs []int) ([]int, error) {
+	if ip == nil {
+		return nil, errors.New("dns: invalid IP")
+	}
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[int]bool)
+	for _, id := range domainIDs {
+		wanted[id] = true
+	}
+
+	// Work out every change before making any, so that an invalid change
+	// leaves everything as it was.
+	var edits []ipListEdit
+	for _, d := range domains {
+		if len(wanted) > 0 {
+			if !wanted[d.ID] {
+				continue
+			}
+			delete(wanted, d.ID)
+		} else if add {
+			isSlave := strings.EqualFold(d.Type, "slave")
+			if master != isSlave {
+				continue
+			}
+		}
+
+		list := splitIPList(d.AXFRIPs)
+		if master {
+			list = splitIPList(d.MasterIPs)
+		}
+
+		list, changed := editIPList(list, ip, add)
+		if !changed {
+			continue
+		}
+		if master && !hasValidIP(list) && strings.EqualFold(d.Type, "slave") {
+			return nil, fmt.Errorf("dns: %s: cannot remove its only master %s", d.Domain, ip)
+		}
+
+		edits = append(edits, ipListEdit{d, list})
+	}
+
+	if len(wanted) > 0 {
+		var missing []int
+		for id := range wanted {
+			missing = append(missing, id)
+		}
+		sort.Ints(missing)
+		return nil, fmt.Errorf("dns: no domains with IDs %v", missing)
+	}
+
+	var changed []int
+	for _, e := range edits {
+		var opts DomainUpdateOpts
+		if master {
+			opts.MasterIPs = String(joinIPList(e.list))
+		} else {
+			opts.AXFRIPs = String(joinIPList(e.list))
+		}
+		err = c.DomainUpdate(e.domain.ID, opts)
+		if err != nil {
+			return changed, fmt.Errorf("dns: %s: %s", e.domain.Domain, err)
+		}
+		changed = append(changed, e.domain.ID)
+	}
+
+	return changed, nil
+}
+
+func editIPList(list []string, ip net.IP, add bool) ([]string, bool) {
+	var out []string
+	found := false
+	for _, s := range list {
+		if strings.EqualFold(s, "none") {
+			continue
+		}
+		if net.ParseIP(s).Equal(ip) {
+			found = true
+			if !add {
+				continue
+			}
+		}
+		out = append(out, s)
+	}
+
+	if add {
+		if found {
+			return list, false
+		}
+		return append(out, ip.String()), true
+	}
+	return out, found
+}
+
+func hasValidIP(list []string) bool {
+	for _, s := range list {
+		if net.ParseIP(s) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// IPReference is a domain that refers to an IP, as returned by
+// DomainIPReferences().
+type IPReference struct {
+	DomainID int
+	Domain   string
+
+	// Master is true if the IP is one of the domain's masters, and AXFR if
+	// it may transfer the domain.
+	Master bool
+	AXFR   bool
+}
+
+// DomainIPReferences returns every domain that lists ip as a master or as
+// allowed to transfer the zone, sorted by domain.  Entries that are not
+// valid IPs are skipped.
+func (c *Client) DomainIPReferences(ip net.IP) ([]IPReference, error) {
+	if ip == nil {
+		return nil, errors.New("dns: invalid IP")
+	}
+
+	domains, err := c.DomainList(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []IPReference
+	for _, d := range domains {
+		ref := IPReference{
+			DomainID: d.ID,
+			Domain:   d.Domain,
+			Master:   containsIP(splitIPList(d.MasterIPs), ip),
+			AXFR:     containsIP(splitIPList(d.AXFRIPs), ip),
+		}
+		if ref.Master || ref.AXFR {
+			refs = append(refs, ref)
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Domain < refs[j].Domain })
+
+	return refs, nil
+}
+
+func containsIP(list []string, ip net.IP) bool {
+	for _, s := range list {
+		if net.ParseIP(s).Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+// +build !integration
+
+package linode
+
+import (
+	"net"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+func TestDomainIPLists(t *testing.T) {
+	d := Domain{MasterIPs: "192.0.2.1; 2001:db8::1,192.0.2.2", AXFRIPs: ""}
+
+	masters, err := d.MasterIPList()
+	require.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("2001:db8::1"), net.ParseIP("192.0.2.2")}, masters)
+
+	axfr, err := d.AXFRIPList()
+	require.NoError(t, err)
+	assert.Len(t, axfr, 0)
+
+	_, err = Domain{MasterIPs: "1;2;3;"}.MasterIPList()
+	assert.Error(t, err)
+
+	assert.Equal(t, "192.0.2.1;192.0.2.2", formatIPList([]net.IP{
+		net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.1")}))
+	assert.Equal(t, "none", formatIPList(nil))
+}
+
+func TestSlaveDomainCreate(t *testing.T) {
+	params := map[string]string{
+		"Domain":     "example.com",
+		"Type":       "slave",
+		"master_ips": "192.0.2.1;192.0.2.2",
+	}
+	responses := []mockAPIResponse{newMockAPIResponse("domain.create", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":30},"ACTION":"domain.create"}`)}
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	id, err := c.SlaveDomainCreate("example.com",
+		[]net.IP{net.ParseIP("192.0.2.1"), net.ParseIP("192.0.2.2")}, DomainCreateOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 30, id)
+
+	_, err = c.SlaveDomainCreate("example.com", nil, DomainCreateOpts{})
+	assert.Error(t, err)
+}
+
+func TestDomainSetIPs(t *testing.T) {
+	var responses []mockAPIResponse
+	params := map[string]string{"DomainID": "30", "master_ips": "192.0.2.1"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":30},"ACTION":"domain.update"}`))
+	params = map[string]string{"DomainID": "31", "axfr_ips": "none"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	require.NoError(t, c.DomainSetMasterIPs(30, []net.IP{net.ParseIP("192.0.2.1")}))
+	require.NoError(t, c.DomainSetAXFRIPs(31, nil))
+	assert.Error(t, c.DomainSetMasterIPs(30, nil))
+}
+
+const testTransferDomains = `{"ERRORARRAY":[],"DATA":[` +
+	`{"DOMAINID":30,"DOMAIN":"a.com","TYPE":"slave","MASTER_IPS":"192.0.2.1;192.0.2.2","AXFR_IPS":"none"},` +
+	`{"DOMAINID":31,"DOMAIN":"b.com","TYPE":"slave","MASTER_IPS":"192.0.2.2","AXFR_IPS":"none"},` +
+	`{"DOMAINID":32,"DOMAIN":"c.com","TYPE":"master","MASTER_IPS":"","AXFR_IPS":"192.0.2.2"},` +
+	`{"DOMAINID":33,"DOMAIN":"d.com","TYPE":"master","MASTER_IPS":"","AXFR_IPS":"none"}` +
+	`],"ACTION":"domain.list"}`
+
+func mockTransferDomains() mockAPIResponse {
+	return newMockAPIResponse("domain.list", map[string]string{}, testTransferDomains)
+}
+
+func TestAddMasterIP(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockTransferDomains())
+	params := map[string]string{"DomainID": "31", "master_ips": "192.0.2.2;192.0.2.1"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// a.com already has it, and c.com and d.com are masters.
+	changed, err := c.AddMasterIP(net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{31}, changed)
+}
+
+func TestRemoveMasterIP(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockTransferDomains())
+	responses = append(responses, mockTransferDomains())
+	params := map[string]string{"DomainID": "30", "master_ips": "192.0.2.2"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":30},"ACTION":"domain.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// b.com would be left without a master, so nothing changes.
+	_, err := c.RemoveMasterIP(net.ParseIP("192.0.2.2"))
+	require.Error(t, err)
+
+	changed, err := c.RemoveMasterIP(net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{30}, changed)
+}
+
+func TestAXFRIP(t *testing.T) {
+	var responses []mockAPIResponse
+	responses = append(responses, mockTransferDomains())
+	params := map[string]string{"DomainID": "33", "axfr_ips": "192.0.2.2"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":33},"ACTION":"domain.update"}`))
+	responses = append(responses, mockTransferDomains())
+	params = map[string]string{"DomainID": "32", "axfr_ips": "none"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":32},"ACTION":"domain.update"}`))
+	responses = append(responses, mockTransferDomains())
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	changed, err := c.AddAXFRIP(net.ParseIP("192.0.2.2"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{33}, changed)
+
+	changed, err = c.RemoveAXFRIP(net.ParseIP("192.0.2.2"), 32, 33)
+	require.NoError(t, err)
+	assert.Equal(t, []int{32}, changed)
+
+	_, err = c.AddAXFRIP(net.ParseIP("192.0.2.2"), 99)
+	assert.Error(t, err)
+
+	_, err = c.AddAXFRIP(nil)
+	assert.Error(t, err)
+}
+
+func TestDomainIPReferences(t *testing.T) {
+	c, ts := clientFor(newMockAPIServer(t, []mockAPIResponse{mockTransferDomains()}))
+	defer ts.Close()
+
+	refs, err := c.DomainIPReferences(net.ParseIP("192.0.2.2"))
+	require.NoError(t, err)
+	assert.Equal(t, []IPReference{
+		{DomainID: 30, Domain: "a.com", Master: true},
+		{DomainID: 31, Domain: "b.com", Master: true},
+		{DomainID: 32, Domain: "c.com", AXFR: true},
+	}, refs)
+}
+
+func TestIPListsWithInvalidEntries(t *testing.T) {
+	domains := `{"ERRORARRAY":[],"DATA":[` +
+		`{"DOMAINID":30,"DOMAIN":"a.com","TYPE":"slave","MASTER_IPS":"1;2;3;","AXFR_IPS":"none"},` +
+		`{"DOMAINID":31,"DOMAIN":"b.com","TYPE":"slave","MASTER_IPS":"192.0.2.1;bogus;192.0.2.2","AXFR_IPS":"none"}` +
+		`],"ACTION":"domain.list"}`
+
+	var responses []mockAPIResponse
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{}, domains))
+	responses = append(responses, newMockAPIResponse("domain.list", map[string]string{}, domains))
+	params := map[string]string{"DomainID": "31", "master_ips": "bogus;192.0.2.2"}
+	responses = append(responses, newMockAPIResponse("domain.update", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.update"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	// a.com's list cannot be parsed, but does not stop b.com from being
+	// found and edited.  The bad entry is kept.
+	refs, err := c.DomainIPReferences(net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, []IPReference{{DomainID: 31, Domain: "b.com", Master: true}}, refs)
+
+	changed, err := c.RemoveMasterIP(net.ParseIP("192.0.2.1"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{31}, changed)
+
+	_, err = c.DomainIPReferences(nil)
+	assert.Error(t, err)
+}
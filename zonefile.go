@@ -0,0 +1,569 @@
+package linode
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Linode's name servers, which are authoritative for every master domain.
+var linodeNameServers = []string{"ns1.linode.com", "ns2.linode.com",
+	"ns3.linode.com", "ns4.linode.com", "ns5.linode.com"}
+
+// The values the DNS manager uses for SOA fields left at zero.
+const (
+	defaultRefreshSec = 14400
+	defaultRetrySec   = 14400
+	defaultExpireSec  = 1209600
+	defaultTTLSec     = 86400
+)
+
+// ExportZone returns the domain as an RFC 1035 zone file.  The SOA record is
+// built from the domain's settings, with today's date as the serial, and is
+// followed by NS records for Linode's name servers and every resource in the
+// domain.
+func (c *Client) ExportZone(domainID int) (string, error) {
+	domains, err := c.DomainList(Int(domainID))
+	if err != nil {
+		return "", err
+	}
+	if len(domains) != 1 {
+		return "", fmt.Errorf("zone: no domain with ID %d", domainID)
+	}
+
+	resources, err := c.DomainResourceList(domainID, nil)
+	if err != nil {
+		return "", err
+	}
+
+	serial, _ := strconv.Atoi(time.Now().UTC().Format("20060102") + "01")
+
+	return formatZone(domains[0], resources, serial), nil
+}
+
+func formatZone(d Domain, resources []DomainResource, serial int) string {
+	var buf bytes.Buffer
+
+	origin := strings.TrimSuffix(d.Domain, ".")
+	fmt.Fprintf(&buf, "$ORIGIN %s.\n", origin)
+	fmt.Fprintf(&buf, "$TTL %d\n", orDefault(d.TTLSec, defaultTTLSec))
+
+	fmt.Fprintf(&buf, "@\tIN\tSOA\t%s. %s (\n", linodeNameServers[0], soaMailbox(d.SOAEmail))
+	fmt.Fprintf(&buf, "\t\t%d\t; serial\n", serial)
+	fmt.Fprintf(&buf, "\t\t%d\t; refresh\n", orDefault(d.RefreshSec, defaultRefreshSec))
+	fmt.Fprintf(&buf, "\t\t%d\t; retry\n", orDefault(d.RetrySec, defaultRetrySec))
+	fmt.Fprintf(&buf, "\t\t%d\t; expire\n", orDefault(d.ExpireSec, defaultExpireSec))
+	fmt.Fprintf(&buf, "\t\t%d )\t; minimum\n", orDefault(d.TTLSec, defaultTTLSec))
+
+	for _, ns := range linodeNameServers {
+		fmt.Fprintf(&buf, "@\tIN\tNS\t%s.\n", ns)
+	}
+
+	var records []RecordSpec
+	for _, r := range resources {
+		records = append(records, recordSpecFor(r))
+	}
+	sortRecordSpecs(records)
+
+	for _, rs := range records {
+		name := rs.Name
+		if rs.Type == "SRV" && rs.Protocol != "" && !strings.Contains(name, "._"+rs.Protocol) {
+			name += "._" + rs.Protocol
+		}
+		if name == "" {
+			name = "@"
+		}
+
+		ttl := ""
+		if rs.TTLSec != 0 {
+			ttl = strconv.Itoa(rs.TTLSec)
+		}
+
+		fmt.Fprintf(&buf, "%s\t%s\tIN\t%s\t%s\n", name, ttl, rs.Type, zoneRData(rs))
+	}
+
+	return buf.String()
+}
+
+func orDefault(v int, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// soaMailbox converts an email address to the form used in SOA records,
+// escaping dots in the local part.
+func soaMailbox(email string) string {
+	if email == "" {
+		return "."
+	}
+
+	i := strings.LastIndex(email, "@")
+	if i < 0 {
+		return strings.TrimSuffix(email, ".") + "."
+	}
+
+	local := strings.Replace(email[:i], ".", `\.`, -1)
+	return local + "." + strings.TrimSuffix(email[i+1:], ".") + "."
+}
+
+func zoneRData(rs RecordSpec) string {
+	switch rs.Type {
+	case "CNAME", "NS":
+		return zoneHost(rs.Target)
+	case "MX":
+		return fmt.Sprintf("%d %s", rs.Priority, zoneHost(rs.Target))
+	case "SRV":
+		return fmt.Sprintf("%d %d %d %s", rs.Priority, rs.Weight, rs.Port, zoneHost(rs.Target))
+	case "TXT":
+		return quoteTXT(rs.Target)
+	}
+	return rs.Target
+}
+
+// zoneHost makes a hostname returned by the API absolute.  Names without a
+// dot are left relative to the origin.
+func zoneHost(host string) string {
+	if strings.Contains(host, ".") && !strings.HasSuffix(host, ".") {
+		return host + "."
+	}
+	return host
+}
+
+// quoteTXT quotes a TXT value, splitting it into strings of at most 255
+// bytes.
+func quoteTXT(s string) string {
+	var parts []string
+	for {
+		chunk := s
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		s = s[len(chunk):]
+
+		chunk = strings.Replace(chunk, `\`, `\\`, -1)
+		chunk = strings.Replace(chunk, `"`, `\"`, -1)
+		parts = append(parts, `"`+chunk+`"`)
+
+		if s == "" {
+			break
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseZone parses an RFC 1035 zone file for domain into a DomainSpec.
+//
+// The SOA record sets SOAEmail, RefreshSec, RetrySec, and ExpireSec, and
+// $TTL (or the SOA minimum) sets TTLSec.  NS records at the apex are skipped,
+// since Linode serves the domain from its own name servers.  A, AAAA, CNAME,
+// MX, TXT, SRV, and NS records are supported; any other type is an error, as
+// is $INCLUDE.  TTLs are left as written; PlanZone() and ImportZone() round
+// them up to values the API accepts.
+func ParseZone(domain string, r io.Reader) (DomainSpec, error) {
+	spec := DomainSpec{Domain: strings.TrimSuffix(domain, "."), Type: "master"}
+
+	lines, err := zoneLines(r)
+	if err != nil {
+		return DomainSpec{}, err
+	}
+
+	origin := spec.Domain + "."
+	last := ""
+	defaultTTL := 0
+
+	for _, l := range lines {
+		f := l.fields
+
+		switch strings.ToUpper(f[0].text) {
+		case "$ORIGIN":
+			if len(f) != 2 {
+				return DomainSpec{}, l.errorf("$ORIGIN takes one argument")
+			}
+			origin = absoluteName(f[1].text, origin)
+			continue
+		case "$TTL":
+			if len(f) != 2 {
+				return DomainSpec{}, l.errorf("$TTL takes one argument")
+			}
+			defaultTTL, err = parseZoneTTL(f[1].text)
+			if err != nil {
+				return DomainSpec{}, l.errorf("%s", err)
+			}
+			spec.TTLSec = defaultTTL
+			continue
+		case "$INCLUDE":
+			return DomainSpec{}, l.errorf("$INCLUDE is not supported")
+		}
+
+		owner := last
+		if !l.continued {
+			owner = absoluteName(f[0].text, origin)
+			f = f[1:]
+		}
+		if owner == "" {
+			return DomainSpec{}, l.errorf("record has no owner name")
+		}
+		last = owner
+
+		name, ok := zoneRelative(owner, spec.Domain)
+		if !ok {
+			return DomainSpec{}, l.errorf("%s is outside of %s", owner, spec.Domain)
+		}
+
+		ttl := 0
+		for len(f) > 0 {
+			if strings.EqualFold(f[0].text, "IN") {
+				f = f[1:]
+				continue
+			}
+			if t, err := parseZoneTTL(f[0].text); err == nil && !f[0].quoted {
+				ttl = t
+				f = f[1:]
+				continue
+			}
+			break
+		}
+		if len(f) == 0 {
+			return DomainSpec{}, l.errorf("record has no type")
+		}
+
+		rType := strings.ToUpper(f[0].text)
+		rdata := f[1:]
+
+		if rType == "SOA" {
+			err = parseSOA(&spec, rdata)
+			if err != nil {
+				return DomainSpec{}, l.errorf("%s", err)
+			}
+			if defaultTTL == 0 {
+				spec.TTLSec, _ = parseZoneTTL(rdata[6].text)
+			}
+			continue
+		}
+
+		if rType == "NS" && name == "" {
+			continue
+		}
+
+		rs, err := parseRecord(rType, name, rdata, origin)
+		if err != nil {
+			return DomainSpec{}, l.errorf("%s", err)
+		}
+		if ttl != defaultTTL {
+			rs.TTLSec = ttl
+		}
+		spec.Records = append(spec.Records, rs)
+	}
+
+	sortRecordSpecs(spec.Records)
+
+	return spec, nil
+}
+
+func parseSOA(spec *DomainSpec, rdata []zoneToken) error {
+	if len(rdata) != 7 {
+		return errors.New("SOA record must have 7 fields")
+	}
+
+	spec.SOAEmail = soaEmail(rdata[1].text)
+
+	// rdata[2] is the serial, which the API manages itself.
+	var err error
+	for i, p := range []*int{&spec.RefreshSec, &spec.RetrySec, &spec.ExpireSec} {
+		*p, err = parseZoneTTL(rdata[3+i].text)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = parseZoneTTL(rdata[6].text)
+	return err
+}
+
+// soaEmail is the inverse of soaMailbox.
+func soaEmail(mailbox string) string {
+	mailbox = strings.TrimSuffix(mailbox, ".")
+	for i := 0; i < len(mailbox); i++ {
+		switch mailbox[i] {
+		case '\\':
+			i++
+		case '.':
+			local := strings.Replace(mailbox[:i], `\.`, ".", -1)
+			return local + "@" + mailbox[i+1:]
+		}
+	}
+	return mailbox
+}
+
+func parseRecord(rType string, name string, rdata []zoneToken, origin string) (RecordSpec, error) {
+	rs := RecordSpec{Type: rType, Name: name}
+
+	want := map[string]int{"A": 1, "AAAA": 1, "CNAME": 1, "NS": 1, "MX": 2, "SRV": 4}
+	n, ok := want[rType]
+	if !ok && rType != "TXT" {
+		return RecordSpec{}, fmt.Errorf("unsupported record type %s", rType)
+	}
+	if rType == "TXT" {
+		if len(rdata) == 0 {
+			return RecordSpec{}, errors.New("TXT record has no data")
+		}
+	} else if len(rdata) != n {
+		return RecordSpec{}, fmt.Errorf("%s record must have %d fields", rType, n)
+	}
+
+	var err error
+	host := func(s string) string {
+		return strings.TrimSuffix(absoluteName(s, origin), ".")
+	}
+
+	switch rType {
+	case "A", "AAAA":
+		rs.Target = rdata[0].text
+	case "CNAME", "NS":
+		rs.Target = host(rdata[0].text)
+	case "MX":
+		rs.Priority, err = strconv.Atoi(rdata[0].text)
+		rs.Target = host(rdata[1].text)
+	case "SRV":
+		var nums [3]int
+		for i := range nums {
+			nums[i], err = strconv.Atoi(rdata[i].text)
+			if err != nil {
+				break
+			}
+		}
+		rs.Priority, rs.Weight, rs.Port = nums[0], nums[1], nums[2]
+		rs.Target = host(rdata[3].text)
+
+		labels := strings.Split(name, ".")
+		if len(labels) > 1 && strings.HasPrefix(labels[1], "_") {
+			rs.Protocol = strings.TrimPrefix(labels[1], "_")
+		}
+	case "TXT":
+		var parts []string
+		for _, t := range rdata {
+			parts = append(parts, t.text)
+		}
+		rs.Target = strings.Join(parts, "")
+	}
+	if err != nil {
+		return RecordSpec{}, err
+	}
+
+	return rs, nil
+}
+
+// absoluteName makes name absolute relative to origin.
+func absoluteName(name string, origin string) string {
+	if name == "@" {
+		return origin
+	}
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	if origin == "" || origin == "." {
+		return name + "."
+	}
+	return name + "." + origin
+}
+
+// zoneRelative returns the absolute name relative to domain.
+func zoneRelative(name string, domain string) (string, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	domain = strings.ToLower(domain)
+
+	if name == domain {
+		return "", true
+	}
+	if strings.HasSuffix(name, "."+domain) {
+		return strings.TrimSuffix(name, "."+domain), true
+	}
+	return "", false
+}
+
+// parseZoneTTL parses a TTL in seconds, with optional BIND unit suffixes
+// such as 1h30m.
+func parseZoneTTL(s string) (int, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return n, nil
+	}
+
+	units := map[byte]int{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}
+	total, n, digits := 0, 0, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch >= '0' && ch <= '9' {
+			n = n*10 + int(ch-'0')
+			digits = true
+			continue
+		}
+		u, ok := units[ch|0x20]
+		if !ok || !digits {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		total += n * u
+		n, digits = 0, false
+	}
+	if digits || s == "" {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+
+	return total, nil
+}
+
+type zoneToken struct {
+	text   string
+	quoted bool
+}
+
+type zoneLine struct {
+	num       int
+	continued bool
+	fields    []zoneToken
+}
+
+func (l zoneLine) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("zone: line %d: %s", l.num, fmt.Sprintf(format, args...))
+}
+
+// zoneLines splits a zone file into logical lines of tokens, removing
+// comments, joining parenthesized lines, and unquoting strings.  A line that
+// starts with whitespace is marked continued, meaning it has no owner name.
+func zoneLines(r io.Reader) ([]zoneLine, error) {
+	var lines []zoneLine
+	var cur *zoneLine
+	depth := 0
+
+	s := bufio.NewScanner(r)
+	num := 0
+	for s.Scan() {
+		num++
+		text := s.Text()
+
+		if depth == 0 {
+			if cur != nil && len(cur.fields) > 0 {
+				lines = append(lines, *cur)
+			}
+			cur = &zoneLine{
+				num:       num,
+				continued: len(text) > 0 && (text[0] == ' ' || text[0] == '\t'),
+			}
+		}
+
+		for i := 0; i < len(text); {
+			ch := text[i]
+			switch {
+			case ch == ';':
+				i = len(text)
+			case ch == ' ' || ch == '\t':
+				i++
+			case ch == '(':
+				depth++
+				i++
+			case ch == ')':
+				if depth == 0 {
+					return nil, fmt.Errorf("zone: line %d: unbalanced parenthesis", num)
+				}
+				depth--
+				i++
+			case ch == '"':
+				str, n, err := unquoteZone(text[i:])
+				if err != nil {
+					return nil, fmt.Errorf("zone: line %d: %s", num, err)
+				}
+				cur.fields = append(cur.fields, zoneToken{str, true})
+				i += n
+			default:
+				j := i
+				for j < len(text) && !strings.ContainsRune(" \t;()\"", rune(text[j])) {
+					if text[j] == '\\' {
+						j++
+					}
+					j++
+				}
+				if j > len(text) {
+					j = len(text)
+				}
+				cur.fields = append(cur.fields, zoneToken{text[i:j], false})
+				i = j
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if depth != 0 {
+		return nil, errors.New("zone: unbalanced parenthesis at end of file")
+	}
+	if cur != nil && len(cur.fields) > 0 {
+		lines = append(lines, *cur)
+	}
+
+	return lines, nil
+}
+
+// unquoteZone reads a quoted string from the start of s, returning its value
+// and the number of bytes consumed.  It understands \X and \DDD escapes.
+func unquoteZone(s string) (string, int, error) {
+	var buf bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return buf.String(), i + 1, nil
+		case '\\':
+			if i+3 < len(s) && isDigits(s[i+1:i+4]) {
+				n, _ := strconv.Atoi(s[i+1 : i+4])
+				buf.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+			i++
+			if i < len(s) {
+				buf.WriteByte(s[i])
+			}
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportZone parses a zone file for domain with ParseZone(), then creates the
+// domain and its records, rounding TTLs up to values the API accepts.  It
+// returns the new domain's ID.
+//
+// If creating a record fails, the domain is left in place with the records
+// created so far.
+func (c *Client) ImportZone(domain string, r io.Reader) (int, error) {
+	spec, err := ParseZone(domain, r)
+	if err != nil {
+		return 0, err
+	}
+
+	plan := &ZonePlan{Domain: spec.Domain}
+	plan.Changes = append(plan.Changes, planDomainCreate(spec))
+
+	var records []RecordSpec
+	for _, rs := range spec.Records {
+		records = append(records, normalizeRecord(rs, spec.Domain))
+	}
+	plan.Changes = append(plan.Changes, planRecords(nil, records, "")...)
+
+	return c.applyZone(plan)
+}
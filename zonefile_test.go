@@ -0,0 +1,218 @@
+// +build !integration
+
+package linode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alexsacr/linode/_third_party/testify/assert"
+	"github.com/alexsacr/linode/_third_party/testify/require"
+)
+
+var testZoneDomain = Domain{
+	ID:       30,
+	Domain:   "example.com",
+	Type:     "master",
+	SOAEmail: "host.master@example.com",
+	TTLSec:   3600,
+}
+
+var testZoneResources = []DomainResource{
+	{ID: 1, Type: "a", Name: "www", Target: "1.1.1.1", TTLSec: 300},
+	{ID: 2, Type: "aaaa", Name: "www", Target: "2001:db8::1"},
+	{ID: 3, Type: "mx", Name: "", Target: "mail.example.com", Priority: 10},
+	{ID: 4, Type: "cname", Name: "blog", Target: "www.example.com"},
+	{ID: 5, Type: "txt", Name: "", Target: `v=spf1 "quoted" mx -all`},
+	{ID: 6, Type: "srv", Name: "_sip._tcp", Target: "sip.example.com", Priority: 5, Weight: 10, Port: 5060, Protocol: "tcp"},
+	{ID: 7, Type: "ns", Name: "sub", Target: "ns1.other.net"},
+}
+
+const expectedZoneFile = `$ORIGIN example.com.
+$TTL 3600
+@	IN	SOA	ns1.linode.com. host\.master.example.com. (
+		2015070801	; serial
+		14400	; refresh
+		14400	; retry
+		1209600	; expire
+		3600 )	; minimum
+@	IN	NS	ns1.linode.com.
+@	IN	NS	ns2.linode.com.
+@	IN	NS	ns3.linode.com.
+@	IN	NS	ns4.linode.com.
+@	IN	NS	ns5.linode.com.
+@		IN	MX	10 mail.example.com.
+@		IN	TXT	"v=spf1 \"quoted\" mx -all"
+_sip._tcp		IN	SRV	5 10 5060 sip.example.com.
+blog		IN	CNAME	www.example.com.
+sub		IN	NS	ns1.other.net.
+www	300	IN	A	1.1.1.1
+www		IN	AAAA	2001:db8::1
+`
+
+func TestFormatZone(t *testing.T) {
+	assert.Equal(t, expectedZoneFile, formatZone(testZoneDomain, testZoneResources, 2015070801))
+}
+
+func TestZoneRoundTrip(t *testing.T) {
+	spec, err := ParseZone("example.com", strings.NewReader(expectedZoneFile))
+	require.NoError(t, err)
+
+	assert.Equal(t, "host.master@example.com", spec.SOAEmail)
+	assert.Equal(t, 3600, spec.TTLSec)
+	assert.Equal(t, 14400, spec.RefreshSec)
+	assert.Equal(t, 14400, spec.RetrySec)
+	assert.Equal(t, 1209600, spec.ExpireSec)
+
+	var expected []RecordSpec
+	for _, r := range testZoneResources {
+		expected = append(expected, recordSpecFor(r))
+	}
+	sortRecordSpecs(expected)
+
+	assert.Equal(t, expected, spec.Records)
+}
+
+const testZoneFile = `; example.com, by hand
+$ORIGIN example.com.
+$TTL 1h
+@ IN SOA ns1.example.net. admin.example.com. (
+    2016010101 ; serial
+    2h         ; refresh
+    30m        ; retry
+    2w         ; expire
+    300 )      ; minimum
+  IN NS ns1.example.net.
+  IN NS ns2.example.net.
+  IN MX 10 mail
+  IN TXT "part one, " "part two"
+mail 300 IN A 192.0.2.1
+     IN AAAA 2001:db8::2 ; same owner
+www.example.com. CNAME mail
+$ORIGIN sub.example.com.
+host 1d A 192.0.2.2
+`
+
+func TestParseZone(t *testing.T) {
+	spec, err := ParseZone("example.com.", strings.NewReader(testZoneFile))
+	require.NoError(t, err)
+
+	assert.Equal(t, DomainSpec{
+		Domain:     "example.com",
+		Type:       "master",
+		SOAEmail:   "admin@example.com",
+		RefreshSec: 7200,
+		RetrySec:   1800,
+		ExpireSec:  1209600,
+		TTLSec:     3600,
+		Records: []RecordSpec{
+			{Type: "MX", Target: "mail.example.com", Priority: 10},
+			{Type: "TXT", Target: "part one, part two"},
+			{Type: "A", Name: "host.sub", Target: "192.0.2.2", TTLSec: 86400},
+			{Type: "A", Name: "mail", Target: "192.0.2.1", TTLSec: 300},
+			{Type: "AAAA", Name: "mail", Target: "2001:db8::2"},
+			{Type: "CNAME", Name: "www", Target: "mail.example.com"},
+		},
+	}, spec)
+}
+
+func TestParseZoneErrors(t *testing.T) {
+	bad := []string{
+		"@ IN HINFO cpu os\n",
+		"$INCLUDE other.zone\n",
+		"www.example.org. IN A 192.0.2.1\n",
+		"@ IN SOA ns1 admin ( 1 2 3\n",
+		"@ IN MX mail\n",
+		"@ IN TXT \"unterminated\n",
+		"$TTL forever\n",
+		"  IN A 192.0.2.1\n",
+	}
+
+	for _, zone := range bad {
+		_, err := ParseZone("example.com", strings.NewReader(zone))
+		assert.Error(t, err, zone)
+	}
+}
+
+func TestParseZoneTTL(t *testing.T) {
+	tests := map[string]int{
+		"0":     0,
+		"300":   300,
+		"5m":    300,
+		"1H30M": 5400,
+		"1w1d":  691200,
+	}
+	for in, out := range tests {
+		ttl, err := parseZoneTTL(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, out, ttl, in)
+	}
+
+	for _, in := range []string{"", "h", "1x", "1h30", "-1"} {
+		_, err := parseZoneTTL(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestExportZone(t *testing.T) {
+	var responses []mockAPIResponse
+
+	params := map[string]string{"DomainID": "30"}
+	responses = append(responses, newMockAPIResponse("domain.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"DOMAINID":30,"DOMAIN":"example.com","TYPE":"master","SOA_EMAIL":"admin@example.com","TTL_SEC":0,"AXFR_IPS":"none"}],"ACTION":"domain.list"}`))
+	responses = append(responses, newMockAPIResponse("domain.resource.list", params,
+		`{"ERRORARRAY":[],"DATA":[{"RESOURCEID":1,"DOMAINID":30,"TYPE":"a","NAME":"www","TARGET":"1.1.1.1","TTL_SEC":0}],"ACTION":"domain.resource.list"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	zone, err := c.ExportZone(30)
+	require.NoError(t, err)
+	assert.Contains(t, zone, "$TTL 86400\n")
+	assert.Contains(t, zone, "@\tIN\tSOA\tns1.linode.com. admin.example.com. (\n")
+	assert.Contains(t, zone, "www\t\tIN\tA\t1.1.1.1\n")
+}
+
+func TestImportZone(t *testing.T) {
+	var params map[string]string
+	var responses []mockAPIResponse
+
+	params = map[string]string{
+		"Domain":      "example.com",
+		"Type":        "master",
+		"SOA_Email":   "admin@example.com",
+		"Refresh_sec": "7200",
+		"Retry_sec":   "3600",
+		"Expire_sec":  "1209600",
+		"TTL_sec":     "3600",
+	}
+	responses = append(responses, newMockAPIResponse("domain.create", params,
+		`{"ERRORARRAY":[],"DATA":{"DomainID":31},"ACTION":"domain.create"}`))
+
+	params = map[string]string{"DomainID": "31", "Type": "mx", "Name": "", "Target": "mail.example.com", "Priority": "10"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":1},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "txt", "Name": "", "Target": "part one, part two"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":2},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "a", "Name": "host.sub", "Target": "192.0.2.2", "TTL_sec": "86400"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":3},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "a", "Name": "mail", "Target": "192.0.2.1", "TTL_sec": "300"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":4},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "aaaa", "Name": "mail", "Target": "2001:db8::2"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":5},"ACTION":"domain.resource.create"}`))
+	params = map[string]string{"DomainID": "31", "Type": "cname", "Name": "www", "Target": "mail.example.com"}
+	responses = append(responses, newMockAPIResponse("domain.resource.create", params,
+		`{"ERRORARRAY":[],"DATA":{"ResourceID":6},"ACTION":"domain.resource.create"}`))
+
+	c, ts := clientFor(newMockAPIServer(t, responses))
+	defer ts.Close()
+
+	id, err := c.ImportZone("example.com", bytes.NewBufferString(testZoneFile))
+	require.NoError(t, err)
+	assert.Equal(t, 31, id)
+}